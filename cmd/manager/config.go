@@ -0,0 +1,196 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/webhook"
+)
+
+// Settings for the manager. Every field has a flag, and they can also be set in a YAML file given with
+// --config. Flags passed on the command line win over the file.
+type managerConfig struct {
+	// Only reconcile objects in these namespaces. Empty means all of them.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Name of the ConfigMap used for the leader election lock.
+	LeaderElectionID string `json:"leaderElectionID,omitempty"`
+	// Namespace for the leader election lock, defaults to the one we are running in.
+	LeaderElectionNamespace string `json:"leaderElectionNamespace,omitempty"`
+	// Where to serve Prometheus metrics, "0" to disable.
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+	// Where to serve the health and readiness endpoints, "0" to disable.
+	HealthBindAddress string `json:"healthBindAddress,omitempty"`
+	// Also fail the readiness probe when AWS can't be reached.
+	HealthCheckAWS bool `json:"healthCheckAWS,omitempty"`
+	// Also fail the readiness probe when Slack can't be reached.
+	HealthCheckSlack bool `json:"healthCheckSlack,omitempty"`
+	// How often every watched object is reconciled even if nothing changed.
+	SyncPeriod metav1.Duration `json:"syncPeriod,omitempty"`
+	// How many objects each controller reconciles at once.
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+	// Overrides MaxConcurrentReconciles for individual controllers.
+	ControllerMaxConcurrentReconciles map[string]int `json:"controllerMaxConcurrentReconciles,omitempty"`
+	// Controllers to leave out, by the name passed to components.NewReconciler.
+	DisabledControllers []string `json:"disabledControllers,omitempty"`
+	// Log and report changes without writing them.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Deadline for a whole reconcile of one object.
+	ReconcileTimeout metav1.Duration `json:"reconcileTimeout,omitempty"`
+	// Default deadline for each component within a reconcile.
+	ComponentTimeout metav1.Duration `json:"componentTimeout,omitempty"`
+	// Don't serve webhooks, for running outside the cluster.
+	DisableWebhooks bool `json:"disableWebhooks,omitempty"`
+	// Port to serve webhooks on.
+	WebhookPort int `json:"webhookPort,omitempty"`
+}
+
+func newManagerConfig() *managerConfig {
+	return &managerConfig{
+		MetricsBindAddress:                ":8080",
+		HealthBindAddress:                 ":8081",
+		SyncPeriod:                        metav1.Duration{Duration: 10 * time.Hour},
+		MaxConcurrentReconciles:           components.MaxConcurrentReconciles,
+		ControllerMaxConcurrentReconciles: map[string]int{},
+		ReconcileTimeout:                  metav1.Duration{Duration: components.ReconcileTimeout},
+		ComponentTimeout:                  metav1.Duration{Duration: components.ComponentTimeout},
+		WebhookPort:                       int(webhook.Port),
+	}
+}
+
+func (c *managerConfig) addFlags(fs *flag.FlagSet) {
+	fs.Var((*stringList)(&c.Namespaces), "namespaces", "Comma-separated namespaces to reconcile objects in, all of them if not set")
+	fs.StringVar(&c.LeaderElectionID, "leader-election-id", c.LeaderElectionID, "Name of the ConfigMap used for the leader election lock")
+	fs.StringVar(&c.LeaderElectionNamespace, "leader-election-namespace", c.LeaderElectionNamespace, "Namespace for the leader election lock, defaults to the one the manager runs in")
+	fs.StringVar(&c.MetricsBindAddress, "metrics-addr", c.MetricsBindAddress, "Address to serve metrics on, 0 to disable")
+	fs.StringVar(&c.HealthBindAddress, "health-addr", c.HealthBindAddress, "Address to serve /healthz and /readyz on, 0 to disable")
+	fs.BoolVar(&c.HealthCheckAWS, "health-check-aws", c.HealthCheckAWS, "Include AWS reachability in the readiness probe")
+	fs.BoolVar(&c.HealthCheckSlack, "health-check-slack", c.HealthCheckSlack, "Include Slack reachability in the readiness probe")
+	fs.DurationVar(&c.SyncPeriod.Duration, "sync-period", c.SyncPeriod.Duration, "How often every object is reconciled even without changes")
+	fs.IntVar(&c.MaxConcurrentReconciles, "max-concurrent-reconciles", c.MaxConcurrentReconciles, "How many objects each controller reconciles at once")
+	fs.Var((*intMap)(&c.ControllerMaxConcurrentReconciles), "controller-max-concurrent-reconciles", "Per-controller overrides for --max-concurrent-reconciles, like summon-platform-controller=4,iamuser-controller=2")
+	fs.Var((*stringList)(&c.DisabledControllers), "disable-controllers", "Comma-separated controllers not to run")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "Log and report the changes controllers would make without writing them")
+	fs.DurationVar(&c.ReconcileTimeout.Duration, "reconcile-timeout", c.ReconcileTimeout.Duration, "Deadline for a whole reconcile of one object")
+	fs.DurationVar(&c.ComponentTimeout.Duration, "component-timeout", c.ComponentTimeout.Duration, "Default deadline for each component within a reconcile")
+	fs.BoolVar(&c.DisableWebhooks, "disable-webhooks", c.DisableWebhooks, "Don't serve admission webhooks, for running outside the cluster")
+	fs.IntVar(&c.WebhookPort, "webhook-port", c.WebhookPort, "Port to serve admission webhooks on")
+}
+
+// Load a YAML config file over the current settings.
+func (c *managerConfig) loadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read config file %s", path)
+	}
+	err = yaml.Unmarshal(data, c)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse config file %s", path)
+	}
+	return nil
+}
+
+// Parse the command line, load the config file if one was given, and then parse the command line again so
+// explicit flags override the file.
+func parseConfig(fs *flag.FlagSet, args []string) (*managerConfig, error) {
+	c := newManagerConfig()
+	var configFile string
+	fs.StringVar(&configFile, "config", "", "Path to a YAML config file, flags override its values")
+	c.addFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if configFile != "" {
+		err = c.loadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		// List flags append, so start them over before the second pass.
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "namespaces":
+				c.Namespaces = nil
+			case "disable-controllers":
+				c.DisabledControllers = nil
+			}
+		})
+		err = fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// A comma-separated list flag, which can also be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// A comma-separated list of name=number pairs.
+type intMap map[string]int
+
+func (m *intMap) String() string {
+	pairs := []string{}
+	for name, n := range *m {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, n))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m *intMap) Set(value string) error {
+	if *m == nil {
+		*m = intMap{}
+	}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("%#v is not in the form name=number", pair)
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return errors.Wrapf(err, "invalid number for %s", parts[0])
+		}
+		(*m)[parts[0]] = n
+	}
+	return nil
+}
@@ -18,31 +18,85 @@ package main
 
 import (
 	"flag"
+	"io/ioutil"
 	"log"
+	"os"
+	"time"
 
 	"github.com/Ridecell/ridecell-operator/pkg/apis"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/controller"
+	"github.com/Ridecell/ridecell-operator/pkg/health"
+	"github.com/Ridecell/ridecell-operator/pkg/webhook"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
 )
 
 func main() {
-	flag.Parse()
+	cfg, err := parseConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	components.DryRun = cfg.DryRun
+	components.ReconcileTimeout = cfg.ReconcileTimeout.Duration
+	components.ComponentTimeout = cfg.ComponentTimeout.Duration
+	components.MaxConcurrentReconciles = cfg.MaxConcurrentReconciles
+	components.ControllerMaxConcurrentReconciles = cfg.ControllerMaxConcurrentReconciles
+	components.Namespaces = cfg.Namespaces
+	for _, name := range cfg.DisabledControllers {
+		controller.Disabled[name] = true
+	}
+	webhook.Port = int32(cfg.WebhookPort)
 
 	// Get a config to talk to the apiserver
-	cfg, err := config.GetConfig()
+	restCfg, err := config.GetConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// Create a new Cmd to provide shared dependencies and start components
-	mgr, err := manager.New(cfg, manager.Options{LeaderElection: true})
+	options := manager.Options{
+		LeaderElection:          true,
+		LeaderElectionID:        cfg.LeaderElectionID,
+		LeaderElectionNamespace: cfg.LeaderElectionNamespace,
+		MetricsBindAddress:      cfg.MetricsBindAddress,
+		SyncPeriod:              &cfg.SyncPeriod.Duration,
+	}
+	// The cache can only be narrowed to one namespace, components.Namespaces filters the rest.
+	if len(cfg.Namespaces) == 1 {
+		options.Namespace = cfg.Namespaces[0]
+	}
+	mgr, err := manager.New(restCfg, options)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Serve probes straight away, not only once we are the leader.
+	if cfg.HealthBindAddress != "0" {
+		lock, err := leaderElectionLock(restCfg, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		healthServer := health.NewServer(cfg.HealthBindAddress)
+		healthServer.AddLivenessCheck("leader-lease", health.LeaseRenewed(lock))
+		healthServer.AddReadinessCheck("cache-sync", health.CacheSynced(mgr.GetCache()))
+		if cfg.HealthCheckAWS {
+			healthServer.AddReadinessCheck("aws", health.Cached(health.AWSReachable(), time.Minute))
+		}
+		if cfg.HealthCheckSlack {
+			healthServer.AddReadinessCheck("slack", health.Cached(health.SlackReachable(os.Getenv("SLACK_API_KEY")), time.Minute))
+		}
+		go func() {
+			log.Fatal(healthServer.ListenAndServe())
+		}()
+	}
+
 	log.Printf("Registering Components.")
 
 	// Setup Scheme for all resources
@@ -55,8 +109,42 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Setup all Webhooks
+	if !cfg.DisableWebhooks {
+		if err := webhook.AddToManager(mgr); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	log.Printf("Starting the Cmd.")
 
 	// Start the Cmd
 	log.Fatal(mgr.Start(signals.SetupSignalHandler()))
 }
+
+// Open the ConfigMap the manager holds its leader election lease in, for the liveness check. The defaults
+// match the ones the manager fills in for itself.
+func leaderElectionLock(restCfg *rest.Config, cfg *managerConfig) (resourcelock.Interface, error) {
+	id := cfg.LeaderElectionID
+	if id == "" {
+		id = "controller-leader-election-helper"
+	}
+	namespace := cfg.LeaderElectionNamespace
+	if namespace == "" {
+		raw, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to find leader election namespace")
+		}
+		namespace = string(raw)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get hostname")
+	}
+	kubeClient, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create Kubernetes client")
+	}
+	// The manager adds a random suffix after the underscore, LeaseRenewed matches on the prefix.
+	return resourcelock.New(resourcelock.ConfigMapsResourceLock, namespace, id, kubeClient.CoreV1(), resourcelock.ResourceLockConfig{Identity: hostname + "_"})
+}
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 // IAMUserSpec defines the desired state of IAMUser
@@ -25,12 +27,19 @@ type IAMUserSpec struct {
 	UserName               string            `json:"username,omitempty"`
 	InlinePolicies         map[string]string `json:"inlinePolicies,omitempty"`
 	PermissionsBoundaryArn string            `json:"permissionsBoundaryArn"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // IAMUserStatus defines the observed state of IAMUser
 type IAMUserStatus struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 // S3BucketSpec defines the desired state of S3Bucket
@@ -25,12 +27,19 @@ type S3BucketSpec struct {
 	BucketName   string `json:"bucketName,omitempty"`
 	BucketPolicy string `json:"bucketPolicy,omitempty"`
 	Region       string `json:"region,omitempty"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // S3BucketStatus defines the observed state of S3Bucket
 type S3BucketStatus struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
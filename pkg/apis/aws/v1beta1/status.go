@@ -17,6 +17,9 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -31,6 +34,11 @@ func (sb *S3Bucket) SetStatus(status components.Status) {
 func (sb *S3Bucket) SetErrorStatus(errorMsg string) {
 	sb.Status.Status = StatusError
 	sb.Status.Message = errorMsg
+	sb.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (sb *S3Bucket) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	sb.Status.Conditions = helpers.SetCondition(sb.Status.Conditions, sb.Generation, conditionType, status, reason, message)
 }
 
 func (iu *IAMUser) GetStatus() components.Status {
@@ -44,4 +52,9 @@ func (iu *IAMUser) SetStatus(status components.Status) {
 func (iu *IAMUser) SetErrorStatus(errorMsg string) {
 	iu.Status.Status = StatusError
 	iu.Status.Message = errorMsg
+	iu.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (iu *IAMUser) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	iu.Status.Conditions = helpers.SetCondition(iu.Status.Conditions, iu.Generation, conditionType, status, reason, message)
 }
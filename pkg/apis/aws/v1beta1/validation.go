@@ -0,0 +1,55 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Decode an IAM or bucket policy, which also checks it is at least valid JSON before sending it to AWS.
+func DecodePolicy(policy string) (interface{}, error) {
+	var policyObj interface{}
+	err := json.Unmarshal([]byte(policy), &policyObj)
+	return policyObj, err
+}
+
+// Check an IAMUser for mistakes which would otherwise only show up during a reconcile.
+func (u *IAMUser) Validate() error {
+	allErrs := field.ErrorList{}
+	policiesPath := field.NewPath("spec", "inlinePolicies")
+	for name, policy := range u.Spec.InlinePolicies {
+		_, err := DecodePolicy(policy)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(policiesPath.Key(name), policy, "invalid JSON: "+err.Error()))
+		}
+	}
+	return allErrs.ToAggregate()
+}
+
+// Check an S3Bucket for mistakes which would otherwise only show up during a reconcile.
+func (b *S3Bucket) Validate() error {
+	allErrs := field.ErrorList{}
+	if b.Spec.BucketPolicy != "" {
+		_, err := DecodePolicy(b.Spec.BucketPolicy)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "bucketPolicy"), b.Spec.BucketPolicy, "invalid JSON: "+err.Error()))
+		}
+	}
+	return allErrs.ToAggregate()
+}
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 // PostgresExtensionSpec defines the desired state of PostgresExtension
@@ -25,12 +27,19 @@ type PostgresExtensionSpec struct {
 	ExtensionName string             `json:"extensionName,omitempty"`
 	Version       string             `json:"version,omitempty"`
 	Database      PostgresConnection `json:"database"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // PostgresExtensionStatus defines the observed state of PostgresExtension
 type PostgresExtensionStatus struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
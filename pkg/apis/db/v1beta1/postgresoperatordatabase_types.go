@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 type PostgresDBRef struct {
@@ -28,12 +30,19 @@ type PostgresDBRef struct {
 type PostgresOperatorDatabaseSpec struct {
 	Database    string        `json:"database"`
 	DatabaseRef PostgresDBRef `json:"databaseRef"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // PostgresOperatorDatabaseStatus defines the observed state of PostgresOperatorDatabase
 type PostgresOperatorDatabaseStatus struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
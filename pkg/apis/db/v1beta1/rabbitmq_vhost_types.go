@@ -18,18 +18,27 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 // RabbitmqVhostSpec defines the desired state of RabbitmqVhost
 type RabbitmqVhostSpec struct {
 	VhostName  string             `json:"vhostName,omitempty"`
 	Connection RabbitmqConnection `json:"connection"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // RabbitmqVhostStatus defines the observed state of RabbitmqVhost
 type RabbitmqVhostStatus struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -17,6 +17,9 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -31,6 +34,11 @@ func (pe *PostgresExtension) SetStatus(status components.Status) {
 func (pe *PostgresExtension) SetErrorStatus(errorMsg string) {
 	pe.Status.Status = StatusError
 	pe.Status.Message = errorMsg
+	pe.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (pe *PostgresExtension) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	pe.Status.Conditions = helpers.SetCondition(pe.Status.Conditions, pe.Generation, conditionType, status, reason, message)
 }
 
 func (po *PostgresOperatorDatabase) GetStatus() components.Status {
@@ -44,4 +52,27 @@ func (po *PostgresOperatorDatabase) SetStatus(status components.Status) {
 func (po *PostgresOperatorDatabase) SetErrorStatus(errorMsg string) {
 	po.Status.Status = StatusError
 	po.Status.Message = errorMsg
+	po.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (po *PostgresOperatorDatabase) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	po.Status.Conditions = helpers.SetCondition(po.Status.Conditions, po.Generation, conditionType, status, reason, message)
+}
+
+func (rv *RabbitmqVhost) GetStatus() components.Status {
+	return rv.Status
+}
+
+func (rv *RabbitmqVhost) SetStatus(status components.Status) {
+	rv.Status = status.(RabbitmqVhostStatus)
+}
+
+func (rv *RabbitmqVhost) SetErrorStatus(errorMsg string) {
+	rv.Status.Status = StatusError
+	rv.Status.Message = errorMsg
+	rv.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (rv *RabbitmqVhost) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	rv.Status.Conditions = helpers.SetCondition(rv.Status.Conditions, rv.Generation, conditionType, status, reason, message)
 }
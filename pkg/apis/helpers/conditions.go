@@ -0,0 +1,91 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The condition type used by every object for its overall state. Objects with several subsystems also
+// have a condition per subsystem.
+const ConditionReady = "Ready"
+
+// Reason used for conditions set from SetErrorStatus.
+const ReasonError = "Error"
+
+// A Condition is the observed state of one aspect of an object. Each component sets its own conditions
+// so a failure in one part doesn't hide the state of the others.
+// +k8s:deepcopy-gen=true
+type Condition struct {
+	// Type of the condition, like DatabaseReady.
+	Type string `json:"type"`
+	// One of True, False, or Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// Short CamelCase reason for the last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Human-readable details about the last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// When the condition last changed from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The metadata.generation the condition was set for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// Find the condition of the given type, or nil if it isn't set.
+func GetCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// Add or update a condition. The transition time is only changed if the status actually changes, so this
+// is safe to call on every reconcile.
+func SetCondition(conditions []Condition, generation int64, conditionType string, status corev1.ConditionStatus, reason, message string) []Condition {
+	existing := GetCondition(conditions, conditionType)
+	if existing == nil {
+		return append(conditions, Condition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: generation,
+		})
+	}
+	if existing.Status != status {
+		existing.Status = status
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Reason = reason
+	existing.Message = message
+	existing.ObservedGeneration = generation
+	return conditions
+}
+
+// Check if a condition is set to True.
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	condition := GetCondition(conditions, conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
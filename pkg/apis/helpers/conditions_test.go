@@ -0,0 +1,63 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+)
+
+var _ = Describe("Conditions", func() {
+	It("adds a new condition", func() {
+		conditions := helpers.SetCondition(nil, 3, "DatabaseReady", corev1.ConditionTrue, "Running", "")
+		Expect(conditions).To(HaveLen(1))
+		Expect(conditions[0].Type).To(Equal("DatabaseReady"))
+		Expect(conditions[0].Status).To(Equal(corev1.ConditionTrue))
+		Expect(conditions[0].ObservedGeneration).To(Equal(int64(3)))
+		Expect(conditions[0].LastTransitionTime.IsZero()).To(BeFalse())
+	})
+
+	It("leaves other conditions alone", func() {
+		conditions := helpers.SetCondition(nil, 1, "DatabaseReady", corev1.ConditionTrue, "Running", "")
+		conditions = helpers.SetCondition(conditions, 1, "SecretsReady", corev1.ConditionFalse, "Error", "oops")
+		Expect(conditions).To(HaveLen(2))
+		Expect(helpers.IsConditionTrue(conditions, "DatabaseReady")).To(BeTrue())
+		Expect(helpers.IsConditionTrue(conditions, "SecretsReady")).To(BeFalse())
+		Expect(helpers.GetCondition(conditions, "SecretsReady").Message).To(Equal("oops"))
+		Expect(helpers.GetCondition(conditions, "Deployed")).To(BeNil())
+	})
+
+	It("only changes the transition time when the status changes", func() {
+		past := metav1.NewTime(time.Now().Add(-time.Hour))
+		conditions := []helpers.Condition{{Type: "Deployed", Status: corev1.ConditionFalse, Reason: "Deploying", LastTransitionTime: past}}
+
+		conditions = helpers.SetCondition(conditions, 2, "Deployed", corev1.ConditionFalse, "Deploying", "still going")
+		Expect(conditions[0].LastTransitionTime).To(Equal(past))
+		Expect(conditions[0].Message).To(Equal("still going"))
+		Expect(conditions[0].ObservedGeneration).To(Equal(int64(2)))
+
+		conditions = helpers.SetCondition(conditions, 2, "Deployed", corev1.ConditionTrue, "DeploymentsAvailable", "")
+		Expect(conditions).To(HaveLen(1))
+		Expect(conditions[0].LastTransitionTime.After(past.Time)).To(BeTrue())
+	})
+})
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helpers
+
+import (
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+// A change which a dry run would have made, see components.DryRunChange.
+// +k8s:deepcopy-gen=true
+type DryRunChange struct {
+	// One of create, update, or delete.
+	Operation string `json:"operation"`
+	Kind      string `json:"kind"`
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Strategic merge patch for updates, or the whole object for creates.
+	// +optional
+	Diff string `json:"diff,omitempty"`
+}
+
+// Convert the changes from a dry run to store in a status. Returns nil when there are no changes so an
+// empty report is omitted.
+func NewDryRunChanges(changes []components.DryRunChange) []DryRunChange {
+	if len(changes) == 0 {
+		return nil
+	}
+	out := make([]DryRunChange, len(changes))
+	for i, change := range changes {
+		out[i] = DryRunChange{
+			Operation: change.Operation,
+			Kind:      change.Kind,
+			Namespace: change.Namespace,
+			Name:      change.Name,
+			Diff:      change.Diff,
+		}
+	}
+	return out
+}
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
@@ -28,6 +30,10 @@ type PullSecretSpec struct {
 	// Name of the secret to use for image pulls. Defaults to `"pull-secret"`.
 	// +optional
 	PullSecretName string `json:"pullSecretName,omitempty"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // PullSecretStatus defines the observed state of PullSecret
@@ -37,6 +43,10 @@ type PullSecretStatus struct {
 
 	// Message related to the current status.
 	Message string `json:"message,omitempty"`
+
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -17,6 +17,9 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -31,6 +34,11 @@ func (s *PullSecret) SetStatus(status components.Status) {
 func (s *PullSecret) SetErrorStatus(errorMsg string) {
 	s.Status.Status = StatusError
 	s.Status.Message = errorMsg
+	s.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (s *PullSecret) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	s.Status.Conditions = helpers.SetCondition(s.Status.Conditions, s.Generation, conditionType, status, reason, message)
 }
 
 func (es *EncryptedSecret) GetStatus() components.Status {
@@ -44,4 +52,9 @@ func (es *EncryptedSecret) SetStatus(status components.Status) {
 func (es *EncryptedSecret) SetErrorStatus(errorMsg string) {
 	es.Status.Status = StatusError
 	es.Status.Message = errorMsg
+	es.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (es *EncryptedSecret) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	es.Status.Conditions = helpers.SetCondition(es.Status.Conditions, es.Generation, conditionType, status, reason, message)
 }
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Check a single EncryptedSecret value is base64-encoded KMS ciphertext. Empty values aren't allowed, KMS
+// can't encrypt an empty string so use EncryptedSecretEmptyKey instead.
+func ValidateEncryptedValue(value string) error {
+	if value == "" {
+		return errors.New("does not have a value")
+	}
+	_, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return errors.Wrap(err, "is not base64 encoded")
+	}
+	return nil
+}
+
+// Check an EncryptedSecret for mistakes which would otherwise only show up during a reconcile.
+func (s *EncryptedSecret) Validate() error {
+	allErrs := field.ErrorList{}
+	dataPath := field.NewPath("data")
+	for key, value := range s.Data {
+		err := ValidateEncryptedValue(value)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(dataPath.Key(key), value, err.Error()))
+		}
+	}
+	return allErrs.ToAggregate()
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+// Convert this SummonPlatform to the v1beta1 version the controller works with.
+func (src *SummonPlatform) ConvertTo(dstRaw runtime.Object) error {
+	dst := dstRaw.(*summonv1beta1.SummonPlatform)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Hostname = src.Spec.Hostname
+	dst.Spec.Version = src.Spec.Version
+	dst.Spec.Secrets = src.Spec.Secrets
+	dst.Spec.PullSecret = src.Spec.PullSecret
+	dst.Spec.Config = nil
+	if src.Spec.Config != nil {
+		dst.Spec.Config = map[string]summonv1beta1.ConfigValue{}
+		for key, value := range src.Spec.Config {
+			configValue, err := convertConfigValueFromJSON(value)
+			if err != nil {
+				return errors.Wrapf(err, "spec.config[%s]", key)
+			}
+			dst.Spec.Config[key] = configValue
+		}
+	}
+	dst.Spec.WebReplicas = src.Spec.Replicas.Web
+	dst.Spec.DaphneReplicas = src.Spec.Replicas.Daphne
+	dst.Spec.WorkerReplicas = src.Spec.Replicas.Celeryd
+	dst.Spec.ChannelWorkerReplicas = src.Spec.Replicas.ChannelWorker
+	dst.Spec.StaticReplicas = src.Spec.Replicas.Static
+	dst.Spec.Autoscaling.Web = convertAutoscalerTo(src.Spec.Autoscaling.Web)
+	dst.Spec.Autoscaling.Daphne = convertAutoscalerTo(src.Spec.Autoscaling.Daphne)
+	dst.Spec.Autoscaling.Celeryd = convertAutoscalerTo(src.Spec.Autoscaling.Celeryd)
+	dst.Spec.Autoscaling.ChannelWorker = convertAutoscalerTo(src.Spec.Autoscaling.ChannelWorker)
+	dst.Spec.Autoscaling.Static = convertAutoscalerTo(src.Spec.Autoscaling.Static)
+	dst.Spec.Overrides.Web = summonv1beta1.PodOverrides(src.Spec.Overrides.Web)
+	dst.Spec.Overrides.Daphne = summonv1beta1.PodOverrides(src.Spec.Overrides.Daphne)
+	dst.Spec.Overrides.Celeryd = summonv1beta1.PodOverrides(src.Spec.Overrides.Celeryd)
+	dst.Spec.Overrides.ChannelWorker = summonv1beta1.PodOverrides(src.Spec.Overrides.ChannelWorker)
+	dst.Spec.Overrides.Static = summonv1beta1.PodOverrides(src.Spec.Overrides.Static)
+	dst.Spec.Overrides.Celerybeat = summonv1beta1.PodOverrides(src.Spec.Overrides.Celerybeat)
+	dst.Spec.Overrides.Migrations = summonv1beta1.PodOverrides(src.Spec.Overrides.Migrations)
+	dst.Spec.Overrides.Hooks = summonv1beta1.PodOverrides(src.Spec.Overrides.Hooks)
+	dst.Spec.Overrides.Redis = summonv1beta1.PodOverrides(src.Spec.Overrides.Redis)
+	dst.Spec.Notifications.SlackChannel = src.Spec.Notifications.SlackChannel
+	dst.Spec.FernetKeyLifetime = 0
+	if src.Spec.FernetKeyLifetime != nil {
+		dst.Spec.FernetKeyLifetime = src.Spec.FernetKeyLifetime.Duration
+	}
+	dst.Spec.NoCreateSuperuser = src.Spec.NoCreateSuperuser
+	dst.Spec.AwsRegion = src.Spec.AWS.Region
+	dst.Spec.SQSQueue = src.Spec.AWS.SQSQueue
+	switch src.Spec.Database.Type {
+	case "", DatabaseShared:
+		dst.Spec.Database.ExclusiveDatabase = false
+	case DatabaseExclusive:
+		dst.Spec.Database.ExclusiveDatabase = true
+	default:
+		return errors.Errorf("spec.database.type: unknown database type %#v", src.Spec.Database.Type)
+	}
+	dst.Spec.Database.SharedDatabaseName = src.Spec.Database.SharedName
+	dst.Spec.Rollback.MigrationFailures = src.Spec.Rollback.MigrationFailures
+	dst.Spec.Rollout.Strategy = summonv1beta1.RolloutStrategy(src.Spec.Rollout.Strategy)
+	dst.Spec.Rollout.CanaryWeight = src.Spec.Rollout.CanaryWeight
+	dst.Spec.Rollout.CanaryDuration = src.Spec.Rollout.CanaryDuration
+	dst.Spec.Rollout.Check.Path = src.Spec.Rollout.Check.Path
+	dst.Spec.Rollout.Check.ExpectedStatus = src.Spec.Rollout.Check.ExpectedStatus
+	dst.Spec.Hooks = nil
+	for _, hook := range src.Spec.Hooks {
+		dst.Spec.Hooks = append(dst.Spec.Hooks, summonv1beta1.HookSpec{
+			Name:          hook.Name,
+			Phase:         summonv1beta1.HookPhase(hook.Phase),
+			Command:       hook.Command,
+			FailurePolicy: summonv1beta1.HookFailurePolicy(hook.FailurePolicy),
+		})
+	}
+	dst.Spec.Paused = src.Spec.Paused
+
+	dst.Status.Status = src.Status.Status
+	dst.Status.Message = src.Status.Message
+	dst.Status.PullSecretStatus = src.Status.PullSecretStatus
+	dst.Status.PostgresStatus = src.Status.PostgresStatus
+	dst.Status.PostgresExtensionStatus = src.Status.PostgresExtensionStatus
+	dst.Status.MigrateVersion = src.Status.MigrateVersion
+	dst.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus(src.Status.MigrationFailure)
+	dst.Status.Rollout = summonv1beta1.RolloutStatus(src.Status.Rollout)
+	dst.Status.Hooks = nil
+	for _, hook := range src.Status.Hooks {
+		dst.Status.Hooks = append(dst.Status.Hooks, summonv1beta1.HookStatus(hook))
+	}
+	dst.Status.Notification.NotifyVersion = src.Status.Notification.NotifyVersion
+	dst.Status.Notification.RollbackVersion = src.Status.Notification.RollbackVersion
+	dst.Status.Conditions = src.Status.Conditions
+	dst.Status.DryRunChanges = src.Status.DryRunChanges
+	return nil
+}
+
+// Fill in this SummonPlatform from the v1beta1 version.
+func (dst *SummonPlatform) ConvertFrom(srcRaw runtime.Object) error {
+	src := srcRaw.(*summonv1beta1.SummonPlatform)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Hostname = src.Spec.Hostname
+	dst.Spec.Version = src.Spec.Version
+	dst.Spec.Secrets = src.Spec.Secrets
+	dst.Spec.PullSecret = src.Spec.PullSecret
+	dst.Spec.Config = nil
+	if src.Spec.Config != nil {
+		dst.Spec.Config = map[string]apiextv1beta1.JSON{}
+		for key, value := range src.Spec.Config {
+			jsonValue, err := convertConfigValueToJSON(value)
+			if err != nil {
+				return errors.Wrapf(err, "spec.config[%s]", key)
+			}
+			dst.Spec.Config[key] = jsonValue
+		}
+	}
+	dst.Spec.Replicas.Web = src.Spec.WebReplicas
+	dst.Spec.Replicas.Daphne = src.Spec.DaphneReplicas
+	dst.Spec.Replicas.Celeryd = src.Spec.WorkerReplicas
+	dst.Spec.Replicas.ChannelWorker = src.Spec.ChannelWorkerReplicas
+	dst.Spec.Replicas.Static = src.Spec.StaticReplicas
+	dst.Spec.Autoscaling.Web = convertAutoscalerFrom(src.Spec.Autoscaling.Web)
+	dst.Spec.Autoscaling.Daphne = convertAutoscalerFrom(src.Spec.Autoscaling.Daphne)
+	dst.Spec.Autoscaling.Celeryd = convertAutoscalerFrom(src.Spec.Autoscaling.Celeryd)
+	dst.Spec.Autoscaling.ChannelWorker = convertAutoscalerFrom(src.Spec.Autoscaling.ChannelWorker)
+	dst.Spec.Autoscaling.Static = convertAutoscalerFrom(src.Spec.Autoscaling.Static)
+	dst.Spec.Overrides.Web = PodOverrides(src.Spec.Overrides.Web)
+	dst.Spec.Overrides.Daphne = PodOverrides(src.Spec.Overrides.Daphne)
+	dst.Spec.Overrides.Celeryd = PodOverrides(src.Spec.Overrides.Celeryd)
+	dst.Spec.Overrides.ChannelWorker = PodOverrides(src.Spec.Overrides.ChannelWorker)
+	dst.Spec.Overrides.Static = PodOverrides(src.Spec.Overrides.Static)
+	dst.Spec.Overrides.Celerybeat = PodOverrides(src.Spec.Overrides.Celerybeat)
+	dst.Spec.Overrides.Migrations = PodOverrides(src.Spec.Overrides.Migrations)
+	dst.Spec.Overrides.Hooks = PodOverrides(src.Spec.Overrides.Hooks)
+	dst.Spec.Overrides.Redis = PodOverrides(src.Spec.Overrides.Redis)
+	dst.Spec.Notifications.SlackChannel = src.Spec.Notifications.SlackChannel
+	dst.Spec.FernetKeyLifetime = nil
+	if src.Spec.FernetKeyLifetime != 0 {
+		dst.Spec.FernetKeyLifetime = &metav1.Duration{Duration: src.Spec.FernetKeyLifetime}
+	}
+	dst.Spec.NoCreateSuperuser = src.Spec.NoCreateSuperuser
+	dst.Spec.AWS.Region = src.Spec.AwsRegion
+	dst.Spec.AWS.SQSQueue = src.Spec.SQSQueue
+	dst.Spec.Database.Type = DatabaseShared
+	if src.Spec.Database.ExclusiveDatabase {
+		dst.Spec.Database.Type = DatabaseExclusive
+	}
+	dst.Spec.Database.SharedName = src.Spec.Database.SharedDatabaseName
+	dst.Spec.Rollback.MigrationFailures = src.Spec.Rollback.MigrationFailures
+	dst.Spec.Rollout.Strategy = RolloutStrategy(src.Spec.Rollout.Strategy)
+	dst.Spec.Rollout.CanaryWeight = src.Spec.Rollout.CanaryWeight
+	dst.Spec.Rollout.CanaryDuration = src.Spec.Rollout.CanaryDuration
+	dst.Spec.Rollout.Check.Path = src.Spec.Rollout.Check.Path
+	dst.Spec.Rollout.Check.ExpectedStatus = src.Spec.Rollout.Check.ExpectedStatus
+	dst.Spec.Hooks = nil
+	for _, hook := range src.Spec.Hooks {
+		dst.Spec.Hooks = append(dst.Spec.Hooks, HookSpec{
+			Name:          hook.Name,
+			Phase:         HookPhase(hook.Phase),
+			Command:       hook.Command,
+			FailurePolicy: HookFailurePolicy(hook.FailurePolicy),
+		})
+	}
+	dst.Spec.Paused = src.Spec.Paused
+
+	dst.Status.Status = src.Status.Status
+	dst.Status.Message = src.Status.Message
+	dst.Status.PullSecretStatus = src.Status.PullSecretStatus
+	dst.Status.PostgresStatus = src.Status.PostgresStatus
+	dst.Status.PostgresExtensionStatus = src.Status.PostgresExtensionStatus
+	dst.Status.MigrateVersion = src.Status.MigrateVersion
+	dst.Status.MigrationFailure = MigrationFailureStatus(src.Status.MigrationFailure)
+	dst.Status.Rollout = RolloutStatus(src.Status.Rollout)
+	dst.Status.Hooks = nil
+	for _, hook := range src.Status.Hooks {
+		dst.Status.Hooks = append(dst.Status.Hooks, HookStatus(hook))
+	}
+	dst.Status.Notification.NotifyVersion = src.Status.Notification.NotifyVersion
+	dst.Status.Notification.RollbackVersion = src.Status.Notification.RollbackVersion
+	dst.Status.Conditions = src.Status.Conditions
+	dst.Status.DryRunChanges = src.Status.DryRunChanges
+	return nil
+}
+
+func convertAutoscalerTo(src *AutoscalerSpec) *summonv1beta1.AutoscalerSpec {
+	if src == nil {
+		return nil
+	}
+	dst := summonv1beta1.AutoscalerSpec(*src)
+	return &dst
+}
+
+func convertAutoscalerFrom(src *summonv1beta1.AutoscalerSpec) *AutoscalerSpec {
+	if src == nil {
+		return nil
+	}
+	dst := AutoscalerSpec(*src)
+	return &dst
+}
+
+// A v1beta1 config value is stored as plain JSON, with an empty one as null.
+func convertConfigValueToJSON(value summonv1beta1.ConfigValue) (apiextv1beta1.JSON, error) {
+	var v interface{}
+	if value.Bool != nil {
+		v = *value.Bool
+	} else if value.Float != nil {
+		v = *value.Float
+	} else if value.String != nil {
+		v = *value.String
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return apiextv1beta1.JSON{}, err
+	}
+	return apiextv1beta1.JSON{Raw: raw}, nil
+}
+
+func convertConfigValueFromJSON(value apiextv1beta1.JSON) (summonv1beta1.ConfigValue, error) {
+	if len(value.Raw) == 0 {
+		return summonv1beta1.ConfigValue{}, nil
+	}
+	var v interface{}
+	err := json.Unmarshal(value.Raw, &v)
+	if err != nil {
+		return summonv1beta1.ConfigValue{}, err
+	}
+	switch typedV := v.(type) {
+	case nil:
+		return summonv1beta1.ConfigValue{}, nil
+	case bool:
+		return summonv1beta1.ConfigValue{Bool: &typedV}, nil
+	case float64:
+		return summonv1beta1.ConfigValue{Float: &typedV}, nil
+	case string:
+		return summonv1beta1.ConfigValue{String: &typedV}, nil
+	default:
+		return summonv1beta1.ConfigValue{}, errors.Errorf("config values must be a string, number, or boolean, not %s", string(value.Raw))
+	}
+}
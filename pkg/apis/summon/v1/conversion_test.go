@@ -0,0 +1,175 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	summonv1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+var _ = Describe("SummonPlatform conversion", func() {
+	boolVal := true
+	floatVal := float64(24)
+	stringVal := "bar"
+	replicas := int32(2)
+	cpuTarget := int32(75)
+
+	It("round-trips a v1beta1 object through v1", func() {
+		original := &summonv1beta1.SummonPlatform{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Labels: map[string]string{"hello": "world"}},
+			Spec: summonv1beta1.SummonPlatformSpec{
+				Hostname:   "foo.example.com",
+				Version:    "1234-eb6b515-master",
+				Secrets:    []string{"foo", "bar"},
+				PullSecret: "other-pull-secret",
+				Config: map[string]summonv1beta1.ConfigValue{
+					"BOOL":   {Bool: &boolVal},
+					"FLOAT":  {Float: &floatVal},
+					"STRING": {String: &stringVal},
+					"EMPTY":  {},
+				},
+				WebReplicas:           &replicas,
+				DaphneReplicas:        &replicas,
+				WorkerReplicas:        &replicas,
+				ChannelWorkerReplicas: &replicas,
+				StaticReplicas:        &replicas,
+				Notifications:         summonv1beta1.NotificationsSpec{SlackChannel: "#foo"},
+				FernetKeyLifetime:     time.Hour,
+				NoCreateSuperuser:     true,
+				AwsRegion:             "eu-central-1",
+				SQSQueue:              "foo-queue",
+				Database:              summonv1beta1.DatabaseSpec{ExclusiveDatabase: true, SharedDatabaseName: "other"},
+				Rollback:              summonv1beta1.RollbackSpec{MigrationFailures: 3},
+				Autoscaling: summonv1beta1.AutoscalingSpec{
+					Web: &summonv1beta1.AutoscalerSpec{MinReplicas: &replicas, MaxReplicas: 10, TargetCPUUtilization: &cpuTarget},
+				},
+				Overrides: summonv1beta1.OverridesSpec{
+					Celerybeat: summonv1beta1.PodOverrides{
+						NodeSelector:      map[string]string{"pool": "workers"},
+						PriorityClassName: "low",
+						Env:               []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+					},
+				},
+				Rollout: summonv1beta1.RolloutSpec{
+					Strategy:       summonv1beta1.RolloutCanary,
+					CanaryWeight:   20,
+					CanaryDuration: &metav1.Duration{Duration: 10 * time.Minute},
+					Check:          summonv1beta1.RolloutCheckSpec{Path: "/healthz", ExpectedStatus: 204},
+				},
+				Hooks: []summonv1beta1.HookSpec{
+					{Name: "warmup", Phase: summonv1beta1.HookPostDeploy, Command: "python manage.py warm_cache", FailurePolicy: summonv1beta1.HookFailureIgnore},
+				},
+				Paused: true,
+			},
+			Status: summonv1beta1.SummonPlatformStatus{
+				Status:         summonv1beta1.StatusReady,
+				Message:        "Ready",
+				MigrateVersion: "1234-eb6b515-master",
+				Notification:   summonv1beta1.NotificationStatus{NotifyVersion: "1234-eb6b515-master", RollbackVersion: "1235-eb6b515-master"},
+				MigrationFailure: summonv1beta1.MigrationFailureStatus{
+					Version:    "1235-eb6b515-master",
+					Count:      3,
+					JobUID:     "abcd",
+					JobLogs:    "kubectl logs --namespace default job/foo-migrations",
+					RolledBack: true,
+				},
+				Rollout: summonv1beta1.RolloutStatus{
+					ActiveSlot:       "green",
+					ActiveVersion:    "1234-eb6b515-master",
+					CandidateVersion: "1236-eb6b515-master",
+				},
+				Hooks: []summonv1beta1.HookStatus{
+					{Name: "warmup", Version: "1234-eb6b515-master", FailedVersion: "1233-eb6b515-master", JobLogs: "kubectl logs --namespace default job/foo-hook-warmup"},
+				},
+				Conditions: []helpers.Condition{{Type: helpers.ConditionReady, Status: corev1.ConditionTrue}},
+			},
+		}
+
+		v1 := &summonv1.SummonPlatform{}
+		err := v1.ConvertFrom(original.DeepCopy())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(v1.Spec.Replicas.Celeryd).To(Equal(&replicas))
+		Expect(v1.Spec.Autoscaling.Web.MaxReplicas).To(Equal(int32(10)))
+		Expect(v1.Spec.Database.Type).To(Equal(summonv1.DatabaseExclusive))
+		Expect(v1.Spec.FernetKeyLifetime.Duration).To(Equal(time.Hour))
+		Expect(string(v1.Spec.Config["FLOAT"].Raw)).To(Equal("24"))
+
+		converted := &summonv1beta1.SummonPlatform{}
+		err = v1.ConvertTo(converted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(converted).To(Equal(original))
+	})
+
+	It("round-trips a v1 object through v1beta1", func() {
+		original := &summonv1.SummonPlatform{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: summonv1.SummonPlatformSpec{
+				Version: "1234-eb6b515-master",
+				Config: map[string]apiextv1beta1.JSON{
+					"BOOL":   {Raw: []byte(`true`)},
+					"FLOAT":  {Raw: []byte(`1.5`)},
+					"STRING": {Raw: []byte(`"bar"`)},
+				},
+				Replicas:          summonv1.ReplicasSpec{Web: &replicas},
+				FernetKeyLifetime: &metav1.Duration{Duration: 2 * time.Hour},
+				AWS:               summonv1.AWSSpec{Region: "eu-central-1"},
+				Database:          summonv1.DatabaseSpec{Type: summonv1.DatabaseShared, SharedName: "other"},
+			},
+		}
+
+		hub := &summonv1beta1.SummonPlatform{}
+		err := original.DeepCopy().ConvertTo(hub)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*hub.Spec.Config["FLOAT"].Float).To(Equal(1.5))
+		Expect(hub.Spec.WebReplicas).To(Equal(&replicas))
+		Expect(hub.Spec.AwsRegion).To(Equal("eu-central-1"))
+
+		converted := &summonv1.SummonPlatform{}
+		err = converted.ConvertFrom(hub)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(converted).To(Equal(original))
+	})
+
+	It("rejects a config value which isn't a scalar", func() {
+		instance := &summonv1.SummonPlatform{
+			Spec: summonv1.SummonPlatformSpec{
+				Config: map[string]apiextv1beta1.JSON{"FOO": {Raw: []byte(`{"a": 1}`)}},
+			},
+		}
+		err := instance.ConvertTo(&summonv1beta1.SummonPlatform{})
+		Expect(err).To(MatchError(ContainSubstring("spec.config[FOO]")))
+	})
+
+	It("rejects an unknown database type", func() {
+		instance := &summonv1.SummonPlatform{
+			Spec: summonv1.SummonPlatformSpec{
+				Database: summonv1.DatabaseSpec{Type: "Other"},
+			},
+		}
+		err := instance.ConvertTo(&summonv1beta1.SummonPlatform{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,431 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+)
+
+// DatabaseType is how the instance's database is provisioned.
+type DatabaseType string
+
+const (
+	// A database inside a Postgres cluster shared by the whole namespace.
+	DatabaseShared DatabaseType = "Shared"
+	// A Postgres cluster of its own.
+	DatabaseExclusive DatabaseType = "Exclusive"
+)
+
+// ReplicasSpec defines how many pods to run for each part of the instance.
+type ReplicasSpec struct {
+	// Number of gunicorn pods to run. Defaults to 1.
+	// +optional
+	Web *int32 `json:"web,omitempty"`
+	// Number of daphne pods to run. Defaults to 1.
+	// +optional
+	Daphne *int32 `json:"daphne,omitempty"`
+	// Number of celeryd pods to run. Defaults to 1.
+	// +optional
+	Celeryd *int32 `json:"celeryd,omitempty"`
+	// Number of channelworker pods to run. Defaults to 1.
+	// +optional
+	ChannelWorker *int32 `json:"channelWorker,omitempty"`
+	// Number of caddy pods to run. Defaults to 1.
+	// +optional
+	Static *int32 `json:"static,omitempty"`
+}
+
+// NotificationsSpec defines notificiations settings for this instance.
+type NotificationsSpec struct {
+	// Name of the slack channel for notifications. If not set, no notifications will be sent.
+	// +optional
+	SlackChannel string `json:"slackChannel,omitempty"`
+}
+
+// AWSSpec defines the AWS settings for this instance.
+type AWSSpec struct {
+	// AWS region for the instance's bucket and queue. Defaults to us-west-2.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Name of the data pipeline SQS queue.
+	// +optional
+	SQSQueue string `json:"sqsQueue,omitempty"`
+}
+
+// DatabaseSpec defines where the instance's database lives.
+type DatabaseSpec struct {
+	// Shared or Exclusive. Defaults to Shared.
+	// +optional
+	Type DatabaseType `json:"type,omitempty"`
+	// Name of the shared Postgres cluster, only used with the Shared type. Defaults to the namespace.
+	// +optional
+	SharedName string `json:"sharedName,omitempty"`
+}
+
+// RollbackSpec defines what to do when a new version can't be migrated.
+type RollbackSpec struct {
+	// Go back to the last good version after this many failed migration attempts. Until then each failure
+	// is retried with a new job. Defaults to 0, never roll back and don't retry.
+	// +optional
+	MigrationFailures int32 `json:"migrationFailures,omitempty"`
+}
+
+// RolloutStrategy is how a new version reaches the web and daphne pods.
+type RolloutStrategy string
+
+const (
+	// Update the Deployments in place with their normal rolling update.
+	RolloutRollingUpdate RolloutStrategy = "RollingUpdate"
+	// Bring the new version up next to the old one and switch the Services over once it is ready.
+	RolloutBlueGreen RolloutStrategy = "BlueGreen"
+	// Send a share of the requests to the new version for a while before switching everything over.
+	RolloutCanary RolloutStrategy = "Canary"
+)
+
+// RolloutCheckSpec is an HTTP check the new version has to pass before it is promoted.
+type RolloutCheckSpec struct {
+	// Path to request from the new web pods, like /healthz. If not set, only pod availability is checked.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Status code which counts as passing. Defaults to 200.
+	// +optional
+	ExpectedStatus int32 `json:"expectedStatus,omitempty"`
+}
+
+// RolloutSpec defines how new versions of the web and daphne pods are rolled out.
+type RolloutSpec struct {
+	// RollingUpdate, BlueGreen, or Canary. Defaults to RollingUpdate. Moving between RollingUpdate and the
+	// others replaces the web and daphne pods all at once.
+	// +optional
+	Strategy RolloutStrategy `json:"strategy,omitempty"`
+	// Percentage of requests sent to the new version during a canary. Defaults to 10.
+	// +optional
+	CanaryWeight int32 `json:"canaryWeight,omitempty"`
+	// How long the canary takes requests before it is promoted. Defaults to 5m.
+	// +optional
+	CanaryDuration *metav1.Duration `json:"canaryDuration,omitempty"`
+	// Check run against the new version before it is promoted.
+	// +optional
+	Check RolloutCheckSpec `json:"check,omitempty"`
+}
+
+// AutoscalerSpec defines a HorizontalPodAutoscaler for one subsystem. At least one target should be set.
+type AutoscalerSpec struct {
+	// Lowest number of pods to run. Defaults to 1.
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// Highest number of pods to run.
+	MaxReplicas int32 `json:"maxReplicas"`
+	// Average CPU use to aim for, as a percentage of the CPU request.
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+	// Average memory use to aim for, as a percentage of the memory request.
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+}
+
+// AutoscalingSpec defines which subsystems are scaled by a HorizontalPodAutoscaler. The replica count
+// for an autoscaled subsystem is ignored.
+type AutoscalingSpec struct {
+	// +optional
+	Web *AutoscalerSpec `json:"web,omitempty"`
+	// +optional
+	Daphne *AutoscalerSpec `json:"daphne,omitempty"`
+	// +optional
+	Celeryd *AutoscalerSpec `json:"celeryd,omitempty"`
+	// +optional
+	ChannelWorker *AutoscalerSpec `json:"channelWorker,omitempty"`
+	// +optional
+	Static *AutoscalerSpec `json:"static,omitempty"`
+}
+
+// PodOverrides customises the pods of one subsystem. Anything not set keeps the template's value.
+type PodOverrides struct {
+	// Requests and limits for the main container, replacing the template's values.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// Extra environment variables for the main container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// OverridesSpec defines pod overrides for each subsystem.
+type OverridesSpec struct {
+	// +optional
+	Web PodOverrides `json:"web,omitempty"`
+	// +optional
+	Daphne PodOverrides `json:"daphne,omitempty"`
+	// +optional
+	Celeryd PodOverrides `json:"celeryd,omitempty"`
+	// +optional
+	ChannelWorker PodOverrides `json:"channelWorker,omitempty"`
+	// +optional
+	Static PodOverrides `json:"static,omitempty"`
+	// +optional
+	Celerybeat PodOverrides `json:"celerybeat,omitempty"`
+	// +optional
+	Migrations PodOverrides `json:"migrations,omitempty"`
+	// Used by every hook Job, separately from the migrations.
+	// +optional
+	Hooks PodOverrides `json:"hooks,omitempty"`
+	// +optional
+	Redis PodOverrides `json:"redis,omitempty"`
+}
+
+// HookPhase is when a hook runs during a deploy.
+type HookPhase string
+
+const (
+	// Before the migrations for a new version.
+	HookPreMigrate HookPhase = "PreMigrate"
+	// After the migrations, before the new version's pods are started.
+	HookPostMigrate HookPhase = "PostMigrate"
+	// Once the new version is deployed and ready.
+	HookPostDeploy HookPhase = "PostDeploy"
+)
+
+// HookFailurePolicy is what to do when a hook fails.
+type HookFailurePolicy string
+
+const (
+	// Stop the deploy with an error. Delete the failed Job to try again.
+	HookFailureFail HookFailurePolicy = "Fail"
+	// Send a notification and carry on with the deploy.
+	HookFailureIgnore HookFailurePolicy = "Ignore"
+)
+
+// HookSpec defines a command to run as a Job once for each version, like a cache warmup or a data backfill.
+// Hook Jobs use the same image, config, and secrets as the rest of the instance.
+type HookSpec struct {
+	// Name of the hook, used in the Job name and status. Must be unique.
+	Name string `json:"name"`
+	// PreMigrate, PostMigrate, or PostDeploy.
+	Phase HookPhase `json:"phase"`
+	// Shell command to run, like "python manage.py loaddata initial.json".
+	Command string `json:"command"`
+	// Fail or Ignore. Defaults to Fail.
+	// +optional
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// SummonPlatformSpec defines the desired state of SummonPlatform
+type SummonPlatformSpec struct {
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Hostname to use for the instance. Defaults to $NAME.ridecell.us.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+	// Summon image version to deploy.
+	Version string `json:"version"`
+	// Name of the secret to use for secret values.
+	Secrets []string `json:"secrets,omitempty"`
+	// Name of the secret to use for image pulls. Defaults to `"pull-secret"`.
+	// +optional
+	PullSecret string `json:"pullSecret,omitempty"`
+	// Summon-platform.yml configuration options. Values must be a string, number, or boolean.
+	// +optional
+	Config map[string]apiextv1beta1.JSON `json:"config,omitempty"`
+	// Number of pods to run for each part of the instance.
+	// +optional
+	Replicas ReplicasSpec `json:"replicas,omitempty"`
+	// Scale parts of the instance with a HorizontalPodAutoscaler instead of a fixed number of pods.
+	// +optional
+	Autoscaling AutoscalingSpec `json:"autoscaling,omitempty"`
+	// Resources, scheduling, and extra environment variables for each part of the instance's pods.
+	// +optional
+	Overrides OverridesSpec `json:"overrides,omitempty"`
+	// Settings for deploy and error notifications.
+	// +optional
+	Notifications NotificationsSpec `json:"notifications,omitempty"`
+	// How often to rotate the Fernet keys, like "8760h". Defaults to one year.
+	// +optional
+	FernetKeyLifetime *metav1.Duration `json:"fernetKeyLifetime,omitempty"`
+	// Disable the creation of the dispatcher@ridecell.com superuser.
+	// +optional
+	NoCreateSuperuser bool `json:"noCreateSuperuser,omitempty"`
+	// AWS settings.
+	// +optional
+	AWS AWSSpec `json:"aws,omitempty"`
+	// Database-related settings.
+	// +optional
+	Database DatabaseSpec `json:"database,omitempty"`
+	// Automatic rollback policy.
+	// +optional
+	Rollback RollbackSpec `json:"rollback,omitempty"`
+	// How new versions of the web and daphne pods are rolled out.
+	// +optional
+	Rollout RolloutSpec `json:"rollout,omitempty"`
+	// Jobs to run during each deploy, in order within each phase.
+	// +optional
+	Hooks []HookSpec `json:"hooks,omitempty"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// NotificationStatus defines the observed state of Notifications
+type NotificationStatus struct {
+	// The last version we posted a deploy success notification for.
+	// +optional
+	NotifyVersion string `json:"notifyVersion,omitempty"`
+	// The last failed version we posted a rollback notification for.
+	// +optional
+	RollbackVersion string `json:"rollbackVersion,omitempty"`
+}
+
+// MigrationFailureStatus records failed migrations for the rollback policy.
+type MigrationFailureStatus struct {
+	// Version whose migrations failed.
+	Version string `json:"version,omitempty"`
+	// How many migration jobs have failed for Version.
+	Count int32 `json:"count,omitempty"`
+	// UID of the last failed job, so each one is only counted once.
+	JobUID string `json:"jobUID,omitempty"`
+	// Where to find the logs of the last failed job.
+	JobLogs string `json:"jobLogs,omitempty"`
+	// Set once Version was given up on and the last good version is being served instead. A new version
+	// is needed before migrations are tried again.
+	RolledBack bool `json:"rolledBack,omitempty"`
+}
+
+// RolloutStatus tracks which slot of the web and daphne Deployments is serving. It is empty when using
+// the RollingUpdate strategy, as there is only one Deployment of each.
+type RolloutStatus struct {
+	// Slot receiving the traffic, blue or green.
+	// +optional
+	ActiveSlot string `json:"activeSlot,omitempty"`
+	// Version running in the active slot.
+	// +optional
+	ActiveVersion string `json:"activeVersion,omitempty"`
+	// Version being rolled out in the other slot, empty when there is no rollout in progress.
+	// +optional
+	CandidateVersion string `json:"candidateVersion,omitempty"`
+	// Percentage of requests currently sent to the candidate during a canary.
+	// +optional
+	CanaryWeight int32 `json:"canaryWeight,omitempty"`
+	// When the candidate started taking requests during a canary.
+	// +optional
+	CanaryStartTime *metav1.Time `json:"canaryStartTime,omitempty"`
+}
+
+// The slot a candidate version is rolled out in, whichever one isn't active.
+func (r RolloutStatus) CandidateSlot() string {
+	if r.ActiveSlot == "blue" {
+		return "green"
+	}
+	return "blue"
+}
+
+// HookStatus tracks a single hook across versions.
+type HookStatus struct {
+	// Name of the hook from the spec.
+	Name string `json:"name"`
+	// Last version the hook succeeded for.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Last version the hook failed for.
+	// +optional
+	FailedVersion string `json:"failedVersion,omitempty"`
+	// Where to find the logs of the last failed job.
+	// +optional
+	JobLogs string `json:"jobLogs,omitempty"`
+	// The last failed version a notification was sent for.
+	// +optional
+	NotifiedVersion string `json:"notifiedVersion,omitempty"`
+}
+
+// SummonPlatformStatus defines the observed state of SummonPlatform
+type SummonPlatformStatus struct {
+	// Overall object status
+	Status string `json:"status,omitempty"`
+
+	// Message related to the current status.
+	Message string `json:"message,omitempty"`
+
+	// Status of the pull secret.
+	PullSecretStatus string `json:"pullSecretStatus,omitempty"`
+
+	// Current Postgresql status if one exists.
+	PostgresStatus postgresv1.PostgresStatus `json:"postgresStatus,omitempty"`
+
+	// Status of the required Postgres extensions (collectively).
+	PostgresExtensionStatus string `json:"postgresExtensionStatus,omitempty"`
+
+	// Previous version for which migrations ran successfully.
+	// +optional
+	MigrateVersion string `json:"migrateVersion,omitempty"`
+	// Spec for Notification
+	// +optional
+	Notification NotificationStatus `json:"notification,omitempty"`
+	// Failed migrations for the current or last rolled back version.
+	// +optional
+	MigrationFailure MigrationFailureStatus `json:"migrationFailure,omitempty"`
+	// Progress of the current rollout, see RolloutSpec.
+	// +optional
+	Rollout RolloutStatus `json:"rollout,omitempty"`
+	// Progress of each hook in the spec.
+	// +optional
+	Hooks []HookStatus `json:"hooks,omitempty"`
+
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
+
+	// Changes the last dry run would have made, set when the ridecell.io/dry-run annotation is used.
+	// +optional
+	DryRunChanges []helpers.DryRunChange `json:"dryRunChanges,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SummonPlatform is the Schema for the summonplatforms API. There is deliberately no +genclient here, the
+// CRD is still generated from v1beta1 and the webhook server adds this version to it along with the
+// conversion settings.
+type SummonPlatform struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SummonPlatformSpec   `json:"spec,omitempty"`
+	Status SummonPlatformStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SummonPlatformList contains a list of SummonPlatform
+type SummonPlatformList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SummonPlatform `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SummonPlatform{}, &SummonPlatformList{})
+}
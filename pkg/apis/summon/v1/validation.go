@@ -0,0 +1,48 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+// Check a SummonPlatform by converting it to v1beta1, so both versions always accept the same objects.
+func (s *SummonPlatform) Validate() error {
+	hub := &summonv1beta1.SummonPlatform{}
+	err := s.ConvertTo(hub)
+	if err != nil {
+		return err
+	}
+	return hub.Validate()
+}
+
+// Check a change to an existing SummonPlatform, the same way as Validate.
+func (s *SummonPlatform) ValidateUpdate(oldObj runtime.Object) error {
+	hub := &summonv1beta1.SummonPlatform{}
+	err := s.ConvertTo(hub)
+	if err != nil {
+		return err
+	}
+	oldHub := &summonv1beta1.SummonPlatform{}
+	err = oldObj.(*SummonPlatform).ConvertTo(oldHub)
+	if err != nil {
+		return err
+	}
+	return hub.ValidateUpdate(oldHub)
+}
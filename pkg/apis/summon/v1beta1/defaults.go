@@ -0,0 +1,236 @@
+/*
+Copyright 2018-2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultFernetKeysLifespan = "8760h"
+
+const (
+	defaultCanaryWeight   = 10
+	defaultCanaryDuration = 5 * time.Minute
+)
+
+// Used for autoscaled subsystems with no target set.
+const defaultTargetCPUUtilization = 80
+
+// Treat this as a const, no touchy.
+var zeroSeconds time.Duration
+
+var configDefaults map[string]ConfigValue
+
+// Fill in default values for everything not set in the spec. This is run by the mutating webhook so the
+// defaults are saved, and again at the start of every reconcile for objects created before the webhook.
+func (instance *SummonPlatform) Default() {
+	// Objects created with generateName have no name yet when the webhook sees them, so anything built
+	// from the name is left for the reconcile to fill in.
+	named := instance.Name != ""
+
+	// Fill in defaults.
+	if instance.Spec.Hostname == "" && named {
+		instance.Spec.Hostname = instance.Name + ".ridecell.us"
+	}
+	defaultReplicas := int32(1)
+	if instance.Spec.WebReplicas == nil {
+		instance.Spec.WebReplicas = &defaultReplicas
+	}
+	if instance.Spec.DaphneReplicas == nil {
+		instance.Spec.DaphneReplicas = &defaultReplicas
+	}
+	if instance.Spec.WorkerReplicas == nil {
+		instance.Spec.WorkerReplicas = &defaultReplicas
+	}
+	if instance.Spec.ChannelWorkerReplicas == nil {
+		instance.Spec.ChannelWorkerReplicas = &defaultReplicas
+	}
+	if instance.Spec.StaticReplicas == nil {
+		instance.Spec.StaticReplicas = &defaultReplicas
+	}
+	for _, autoscaler := range instance.Spec.Autoscaling.autoscalers() {
+		if autoscaler.spec == nil {
+			continue
+		}
+		if autoscaler.spec.MinReplicas == nil {
+			minReplicas := int32(1)
+			autoscaler.spec.MinReplicas = &minReplicas
+		}
+		if autoscaler.spec.TargetCPUUtilization == nil && autoscaler.spec.TargetMemoryUtilization == nil {
+			target := int32(defaultTargetCPUUtilization)
+			autoscaler.spec.TargetCPUUtilization = &target
+		}
+	}
+	if len(instance.Spec.Secrets) == 0 && named {
+		if instance.Namespace == "dev" || instance.Namespace == "qa" {
+			instance.Spec.Secrets = []string{instance.Namespace, instance.Name}
+		} else {
+			instance.Spec.Secrets = []string{instance.Name}
+		}
+	}
+	if instance.Spec.PullSecret == "" {
+		instance.Spec.PullSecret = "pull-secret"
+	}
+	if instance.Spec.FernetKeyLifetime == zeroSeconds {
+		// This is set to rotate fernet keys every year.
+		parsedTimeDuration, _ := time.ParseDuration(defaultFernetKeysLifespan)
+		instance.Spec.FernetKeyLifetime = parsedTimeDuration
+	}
+	if instance.Spec.AwsRegion == "" {
+		instance.Spec.AwsRegion = "us-west-2"
+	}
+	if instance.Spec.SQSQueue == "" {
+		if instance.Namespace == "prod" || instance.Namespace == "uat" {
+			instance.Spec.SQSQueue = "prod-data-pipeline"
+		} else {
+			instance.Spec.SQSQueue = "master-data-pipeline"
+		}
+	}
+	if instance.Spec.Database.SharedDatabaseName == "" {
+		instance.Spec.Database.SharedDatabaseName = instance.Namespace
+	}
+	if instance.Spec.Rollout.Strategy == "" {
+		instance.Spec.Rollout.Strategy = RolloutRollingUpdate
+	}
+	if instance.Spec.Rollout.Strategy == RolloutCanary {
+		if instance.Spec.Rollout.CanaryWeight == 0 {
+			instance.Spec.Rollout.CanaryWeight = defaultCanaryWeight
+		}
+		if instance.Spec.Rollout.CanaryDuration == nil {
+			instance.Spec.Rollout.CanaryDuration = &metav1.Duration{Duration: defaultCanaryDuration}
+		}
+	}
+	if instance.Spec.Rollout.Check.Path != "" && instance.Spec.Rollout.Check.ExpectedStatus == 0 {
+		instance.Spec.Rollout.Check.ExpectedStatus = 200
+	}
+	for i := range instance.Spec.Hooks {
+		if instance.Spec.Hooks[i].FailurePolicy == "" {
+			instance.Spec.Hooks[i].FailurePolicy = HookFailureFail
+		}
+	}
+	// Fill in static default config values.
+	if instance.Spec.Config == nil {
+		instance.Spec.Config = map[string]ConfigValue{}
+	}
+	for key, value := range configDefaults {
+		_, ok := instance.Spec.Config[key]
+		if !ok {
+			instance.Spec.Config[key] = value
+		}
+	}
+
+	// Fill in the two config values that need the instance name in them.
+	defVal := func(key, valueTemplate string, args ...interface{}) {
+		_, ok := instance.Spec.Config[key]
+		if !ok {
+			value := fmt.Sprintf(valueTemplate, args...)
+			instance.Spec.Config[key] = ConfigValue{String: &value}
+		}
+	}
+	if named {
+		defVal("ASGI_URL", "redis://%s-redis/0", instance.Name)
+		defVal("CACHE_URL", "redis://%s-redis/1", instance.Name)
+		defVal("FIREBASE_ROOT_NODE", "%s", instance.Name)
+		defVal("TENANT_ID", "%s", instance.Name)
+		defVal("NEWRELIC_NAME", "%s-summon-platform", instance.Name)
+		defVal("AWS_STORAGE_BUCKET_NAME", "ridecell-%s-static", instance.Name)
+	}
+	if instance.Spec.Hostname != "" {
+		defVal("WEB_URL", "https://%s", instance.Spec.Hostname)
+	}
+	defVal("AWS_REGION", "%s", instance.Spec.AwsRegion)
+}
+
+func defConfig(key string, value interface{}) {
+	boolVal, ok := value.(bool)
+	if ok {
+		configDefaults[key] = ConfigValue{Bool: &boolVal}
+		return
+	}
+	floatVal, ok := value.(float64)
+	if ok {
+		configDefaults[key] = ConfigValue{Float: &floatVal}
+		return
+	}
+	stringVal, ok := value.(string)
+	if ok {
+		configDefaults[key] = ConfigValue{String: &stringVal}
+		return
+	}
+	panic("Unknown type")
+}
+
+func init() {
+	configDefaults = map[string]ConfigValue{}
+	// Default config, mostly based on local dev.
+	defConfig("AMAZON_S3_USED", true)
+	defConfig("AUTH_SDK_AUTH_SERVICE_PUBLIC_KEY", `-----BEGIN PUBLIC KEY-----
+MIICIjANBgkqhkiG9w0BAQEFAAOCAg8AMIICCgKCAgEAsPk83VrFTv1yp8yY3j38
+DlK93nZzu6QH3VoKe8VcbuEP7eixlKIt91ID67KCRQGYV/sWquTxP1bmBUrku7tx
+nUXKs7NEchyMyhnq9/MaGenqv79QjpEzx1QikHplSPtp1Jj85ApWuECLgVfYuU1o
+CkH5DFmyd7An5NCFjuU8On76KMbb05Mxmw0T10UVlftchP+aCAKuuqUFxcX6oVmw
+kzWaFA13CCaeL2Vq1//ydYQtrhWEpx0fBsYq4nQsSz9wy37wbTcWVuyjMYG0Zyhh
+Oer7gwhEQS+4Fbn5vluU0v4Fwy5Vo2sGJtYbsdMsQZIc11FJ6dRCOgS+oXcCouwS
+a+KiQKrss4HuCovEwKxm3KgzaTOfgmVyf/39DUuBJ7cJuNe2mSJeRJvWSXBktTyS
+gGXvsQ1JVRqbEAC0htjy4nKoCawvrIs1lO0CjfpxO5vEv4SPazGenHTGtN6RRIjk
+PSQQAdjCUVnumveczncRwDkLmRWud7ijF74cqLgDAnUIeLJE3dqQv0Ff08R5Uh9b
+WoyKbZrC1Ie5bd6OGix+GWOFtAZ6FQJ7fFVeOjCQkHOnYJfnorj0nlKTQXCWsDjq
+waGIhRA2Oq1iha0fw8udSyUU+F0tWtaTAPrKe8VBWQPBwaWSzUjIP8Nb7EZBHLyP
+ZSo/8E5P29isb34ZQedtc1kCAwEAAQ==
+-----END PUBLIC KEY-----`)
+	defConfig("CARSHARING_V1_API_DISABLED", false)
+	defConfig("CLOUDFRONT_DISTRIBUTION", "")
+	defConfig("COMPRESS_ENABLED", false)
+	defConfig("CSBE_CONNECTION_USED", false)
+	defConfig("DATA_PIPELINE_SQS_QUEUE_NAME", "master-data-pipeline")
+	defConfig("DEBUG", false)
+	defConfig("ENABLE_NEW_RELIC", false)
+	defConfig("ENABLE_SENTRY", false)
+	defConfig("FACEBOOK_AUTHENTICATION_EMPLOYEE_PERMISSION_REQUIRED", false)
+	defConfig("FIREBASE_APP", "instant-stage")
+	defConfig("GDPR_ENABLED", true)
+	defConfig("GOOGLE_ANALYTICS_ID", "UA-37653074-1")
+	defConfig("INTERNATIONAL_OUTGOING_SMS_NUMBER", "14152345773")
+	defConfig("OAUTH_HOSTED_DOMAIN", "")
+	defConfig("OUTGOING_SMS_NUMBER", "41254")
+	defConfig("PLATFORM_ENV", "DEV")
+	defConfig("SAML_EMAIL_ATTRIBUTE", "eduPersonPrincipalName")
+	defConfig("SAML_FIRST_NAME_ATTRIBUTE", "givenName")
+	defConfig("SAML_IDP_ENTITY_ID", "https://idp.testshib.org/idp/shibboleth")
+	defConfig("SAML_IDP_METADATA_FILENAME", "")
+	defConfig("SAML_IDP_METADATA_URL", "https://www.testshib.org/metadata/testshib-providers.xml")
+	defConfig("SAML_IDP_PUBLIC_KEY_FILENAME", "testshib.crt")
+	defConfig("SAML_IDP_SSO_URL", "https://idp.testshib.org/idp/profile/SAML2/Redirect/SSO")
+	defConfig("SAML_LAST_NAME_ATTRIBUTE", "sn")
+	defConfig("SAML_NAME_ID_FORMAT", "urn:oasis:names:tc:SAML:2.0:nameid-format:transient")
+	defConfig("SAML_PRIVATE_KEY_FILENAME", "sp.key")
+	defConfig("SAML_PRIVATE_KEY_FILENAME", "sp.key")
+	defConfig("SAML_PUBLIC_KEY_FILENAME", "sp.crt")
+	defConfig("SAML_PUBLIC_KEY_FILENAME", "sp.crt")
+	defConfig("SAML_SERVICE_NAME", "RideCell SAML Test")
+	defConfig("SAML_USE_LOCAL_METADATA", "")
+	defConfig("SAML_VALID_FOR_HOURS", float64(24))
+	defConfig("SESSION_COOKIE_AGE", float64(1209600))
+	defConfig("TIME_ZONE", "America/Los_Angeles")
+	defConfig("USE_FACEBOOK_AUTHENTICATION_FOR_RIDERS", false)
+	defConfig("USE_GOOGLE_AUTHENTICATION_FOR_RIDERS", false)
+	defConfig("USE_SAML_AUTHENTICATION_FOR_RIDERS", false)
+	defConfig("XMLSEC_BINARY_LOCATION", "/usr/bin/xmlsec1")
+}
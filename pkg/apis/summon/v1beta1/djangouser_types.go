@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 type SecretRef struct {
@@ -45,12 +47,19 @@ type DjangoUserSpec struct {
 	Dispatcher     bool               `json:"dispatcher"`
 	Staff          bool               `json:"staff"`
 	Superuser      bool               `json:"superuser"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // DjangoUserStatus defines the observed state of DjangoUser
 type DjangoUserStatus struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
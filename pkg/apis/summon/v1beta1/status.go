@@ -17,6 +17,9 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -31,6 +34,15 @@ func (s *SummonPlatform) SetStatus(status components.Status) {
 func (s *SummonPlatform) SetErrorStatus(errorMsg string) {
 	s.Status.Status = StatusError
 	s.Status.Message = errorMsg
+	s.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (s *SummonPlatform) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	s.Status.Conditions = helpers.SetCondition(s.Status.Conditions, s.Generation, conditionType, status, reason, message)
+}
+
+func (s *SummonPlatform) SetDryRunChanges(changes []components.DryRunChange) {
+	s.Status.DryRunChanges = helpers.NewDryRunChanges(changes)
 }
 
 func (s *DjangoUser) GetStatus() components.Status {
@@ -44,4 +56,9 @@ func (s *DjangoUser) SetStatus(status components.Status) {
 func (s *DjangoUser) SetErrorStatus(errorMsg string) {
 	s.Status.Status = StatusError
 	s.Status.Message = errorMsg
+	s.SetCondition(helpers.ConditionReady, corev1.ConditionFalse, helpers.ReasonError, errorMsg)
+}
+
+func (s *DjangoUser) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) {
+	s.Status.Conditions = helpers.SetCondition(s.Status.Conditions, s.Generation, conditionType, status, reason, message)
 }
@@ -20,7 +20,10 @@ import (
 	"time"
 
 	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 // Gross workaround for limitations the Kubernetes code generator and interface{}.
@@ -46,6 +49,160 @@ type DatabaseSpec struct {
 	SharedDatabaseName string `json:"sharedDatabaseName,omitempty"`
 }
 
+// RollbackSpec defines what to do when a new version can't be migrated.
+type RollbackSpec struct {
+	// Go back to the last good version after this many failed migration attempts. Until then each failure
+	// is retried with a new job. Defaults to 0, never roll back and don't retry.
+	// +optional
+	MigrationFailures int32 `json:"migrationFailures,omitempty"`
+}
+
+// RolloutStrategy is how a new version reaches the web and daphne pods.
+type RolloutStrategy string
+
+const (
+	// Update the Deployments in place with their normal rolling update.
+	RolloutRollingUpdate RolloutStrategy = "RollingUpdate"
+	// Bring the new version up next to the old one and switch the Services over once it is ready.
+	RolloutBlueGreen RolloutStrategy = "BlueGreen"
+	// Send a share of the requests to the new version for a while before switching everything over.
+	RolloutCanary RolloutStrategy = "Canary"
+)
+
+// RolloutCheckSpec is an HTTP check the new version has to pass before it is promoted.
+type RolloutCheckSpec struct {
+	// Path to request from the new web pods, like /healthz. If not set, only pod availability is checked.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Status code which counts as passing. Defaults to 200.
+	// +optional
+	ExpectedStatus int32 `json:"expectedStatus,omitempty"`
+}
+
+// RolloutSpec defines how new versions of the web and daphne pods are rolled out.
+type RolloutSpec struct {
+	// RollingUpdate, BlueGreen, or Canary. Defaults to RollingUpdate. Moving between RollingUpdate and the
+	// others replaces the web and daphne pods all at once.
+	// +optional
+	Strategy RolloutStrategy `json:"strategy,omitempty"`
+	// Percentage of requests sent to the new version during a canary. Defaults to 10.
+	// +optional
+	CanaryWeight int32 `json:"canaryWeight,omitempty"`
+	// How long the canary takes requests before it is promoted. Defaults to 5m.
+	// +optional
+	CanaryDuration *metav1.Duration `json:"canaryDuration,omitempty"`
+	// Check run against the new version before it is promoted.
+	// +optional
+	Check RolloutCheckSpec `json:"check,omitempty"`
+}
+
+// AutoscalerSpec defines a HorizontalPodAutoscaler for one subsystem. At least one target should be set.
+type AutoscalerSpec struct {
+	// Lowest number of pods to run. Defaults to 1.
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// Highest number of pods to run.
+	MaxReplicas int32 `json:"maxReplicas"`
+	// Average CPU use to aim for, as a percentage of the CPU request.
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+	// Average memory use to aim for, as a percentage of the memory request.
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+}
+
+// AutoscalingSpec defines which subsystems are scaled by a HorizontalPodAutoscaler. The replica count
+// for an autoscaled subsystem is ignored.
+type AutoscalingSpec struct {
+	// +optional
+	Web *AutoscalerSpec `json:"web,omitempty"`
+	// +optional
+	Daphne *AutoscalerSpec `json:"daphne,omitempty"`
+	// +optional
+	Celeryd *AutoscalerSpec `json:"celeryd,omitempty"`
+	// +optional
+	ChannelWorker *AutoscalerSpec `json:"channelWorker,omitempty"`
+	// +optional
+	Static *AutoscalerSpec `json:"static,omitempty"`
+}
+
+// PodOverrides customises the pods of one subsystem. Anything not set keeps the template's value.
+type PodOverrides struct {
+	// Requests and limits for the main container, replacing the template's values.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// Extra environment variables for the main container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// OverridesSpec defines pod overrides for each subsystem.
+type OverridesSpec struct {
+	// +optional
+	Web PodOverrides `json:"web,omitempty"`
+	// +optional
+	Daphne PodOverrides `json:"daphne,omitempty"`
+	// +optional
+	Celeryd PodOverrides `json:"celeryd,omitempty"`
+	// +optional
+	ChannelWorker PodOverrides `json:"channelWorker,omitempty"`
+	// +optional
+	Static PodOverrides `json:"static,omitempty"`
+	// +optional
+	Celerybeat PodOverrides `json:"celerybeat,omitempty"`
+	// +optional
+	Migrations PodOverrides `json:"migrations,omitempty"`
+	// Used by every hook Job, separately from the migrations.
+	// +optional
+	Hooks PodOverrides `json:"hooks,omitempty"`
+	// +optional
+	Redis PodOverrides `json:"redis,omitempty"`
+}
+
+// HookPhase is when a hook runs during a deploy.
+type HookPhase string
+
+const (
+	// Before the migrations for a new version.
+	HookPreMigrate HookPhase = "PreMigrate"
+	// After the migrations, before the new version's pods are started.
+	HookPostMigrate HookPhase = "PostMigrate"
+	// Once the new version is deployed and ready.
+	HookPostDeploy HookPhase = "PostDeploy"
+)
+
+// HookFailurePolicy is what to do when a hook fails.
+type HookFailurePolicy string
+
+const (
+	// Stop the deploy with an error. Delete the failed Job to try again.
+	HookFailureFail HookFailurePolicy = "Fail"
+	// Send a notification and carry on with the deploy.
+	HookFailureIgnore HookFailurePolicy = "Ignore"
+)
+
+// HookSpec defines a command to run as a Job once for each version, like a cache warmup or a data backfill.
+// Hook Jobs use the same image, config, and secrets as the rest of the instance.
+type HookSpec struct {
+	// Name of the hook, used in the Job name and status. Must be unique.
+	Name string `json:"name"`
+	// PreMigrate, PostMigrate, or PostDeploy.
+	Phase HookPhase `json:"phase"`
+	// Shell command to run, like "python manage.py loaddata initial.json".
+	Command string `json:"command"`
+	// Fail or Ignore. Defaults to Fail.
+	// +optional
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
 // SummonPlatformSpec defines the desired state of SummonPlatform
 type SummonPlatformSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
@@ -77,6 +234,12 @@ type SummonPlatformSpec struct {
 	// Number of caddy pods to run. Defaults to 1.
 	// +optional
 	StaticReplicas *int32 `json:"staticReplicas,omitempty"`
+	// Scale subsystems with a HorizontalPodAutoscaler instead of a fixed number of pods.
+	// +optional
+	Autoscaling AutoscalingSpec `json:"autoscaling,omitempty"`
+	// Resources, scheduling, and extra environment variables for each subsystem's pods.
+	// +optional
+	Overrides OverridesSpec `json:"overrides,omitempty"`
 	// Settings for deploy and error notifications.
 	// +optional
 	Notifications NotificationsSpec `json:"notifications,omitempty"`
@@ -94,6 +257,19 @@ type SummonPlatformSpec struct {
 	// Database-related settings.
 	// +optional
 	Database DatabaseSpec `json:"database,omitempty"`
+	// Automatic rollback policy.
+	// +optional
+	Rollback RollbackSpec `json:"rollback,omitempty"`
+	// How new versions of the web and daphne pods are rolled out.
+	// +optional
+	Rollout RolloutSpec `json:"rollout,omitempty"`
+	// Jobs to run during each deploy, in order within each phase.
+	// +optional
+	Hooks []HookSpec `json:"hooks,omitempty"`
+	// Stop reconciling this object, for hand-editing things during an incident. Same as the
+	// ridecell.io/paused annotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
 // NotificationStatus defines the observed state of Notifications
@@ -101,6 +277,70 @@ type NotificationStatus struct {
 	// The last version we posted a deploy success notification for.
 	// +optional
 	NotifyVersion string `json:"notifyVersion,omitempty"`
+	// The last failed version we posted a rollback notification for.
+	// +optional
+	RollbackVersion string `json:"rollbackVersion,omitempty"`
+}
+
+// MigrationFailureStatus records failed migrations for the rollback policy.
+type MigrationFailureStatus struct {
+	// Version whose migrations failed.
+	Version string `json:"version,omitempty"`
+	// How many migration jobs have failed for Version.
+	Count int32 `json:"count,omitempty"`
+	// UID of the last failed job, so each one is only counted once.
+	JobUID string `json:"jobUID,omitempty"`
+	// Where to find the logs of the last failed job.
+	JobLogs string `json:"jobLogs,omitempty"`
+	// Set once Version was given up on and the last good version is being served instead. A new version
+	// is needed before migrations are tried again.
+	RolledBack bool `json:"rolledBack,omitempty"`
+}
+
+// RolloutStatus tracks which slot of the web and daphne Deployments is serving. It is empty when using
+// the RollingUpdate strategy, as there is only one Deployment of each.
+type RolloutStatus struct {
+	// Slot receiving the traffic, blue or green.
+	// +optional
+	ActiveSlot string `json:"activeSlot,omitempty"`
+	// Version running in the active slot.
+	// +optional
+	ActiveVersion string `json:"activeVersion,omitempty"`
+	// Version being rolled out in the other slot, empty when there is no rollout in progress.
+	// +optional
+	CandidateVersion string `json:"candidateVersion,omitempty"`
+	// Percentage of requests currently sent to the candidate during a canary.
+	// +optional
+	CanaryWeight int32 `json:"canaryWeight,omitempty"`
+	// When the candidate started taking requests during a canary.
+	// +optional
+	CanaryStartTime *metav1.Time `json:"canaryStartTime,omitempty"`
+}
+
+// The slot a candidate version is rolled out in, whichever one isn't active.
+func (r RolloutStatus) CandidateSlot() string {
+	if r.ActiveSlot == "blue" {
+		return "green"
+	}
+	return "blue"
+}
+
+// HookStatus tracks a single hook across versions.
+type HookStatus struct {
+	// Name of the hook from the spec.
+	Name string `json:"name"`
+	// Last version the hook succeeded for.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Last version the hook failed for.
+	// +optional
+	FailedVersion string `json:"failedVersion,omitempty"`
+	// Where to find the logs of the last failed job.
+	// +optional
+	JobLogs string `json:"jobLogs,omitempty"`
+	// The last failed version a notification was sent for.
+	// +optional
+	NotifiedVersion string `json:"notifiedVersion,omitempty"`
 }
 
 // SummonPlatformStatus defines the observed state of SummonPlatform
@@ -126,6 +366,23 @@ type SummonPlatformStatus struct {
 	// Spec for Notification
 	// +optional
 	Notification NotificationStatus `json:"notification,omitempty"`
+	// Failed migrations for the current or last rolled back version.
+	// +optional
+	MigrationFailure MigrationFailureStatus `json:"migrationFailure,omitempty"`
+	// Progress of the current rollout, see RolloutSpec.
+	// +optional
+	Rollout RolloutStatus `json:"rollout,omitempty"`
+	// Progress of each hook in the spec.
+	// +optional
+	Hooks []HookStatus `json:"hooks,omitempty"`
+
+	// Conditions for the object and each of its parts.
+	// +optional
+	Conditions []helpers.Condition `json:"conditions,omitempty"`
+
+	// Changes the last dry run would have made, set when the ridecell.io/dry-run annotation is used.
+	// +optional
+	DryRunChanges []helpers.DryRunChange `json:"dryRunChanges,omitempty"`
 }
 
 // +genclient
@@ -23,3 +23,11 @@ const (
 	StatusReady        = "Ready"
 	StatusError        = "Error"
 )
+
+// Condition types for each part of a SummonPlatform.
+const (
+	ConditionDatabaseReady      = "DatabaseReady"
+	ConditionSecretsReady       = "SecretsReady"
+	ConditionMigrationsComplete = "MigrationsComplete"
+	ConditionDeployed           = "Deployed"
+)
@@ -0,0 +1,230 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Our usual image version format of build number, commit SHA, and branch, like 1234-eb6b515-master.
+var VersionRegex = regexp.MustCompile(`^(\d+)-([0-9a-fA-F]+)-(\S+)$`)
+
+// Check a Summon image version is in our usual format.
+func ValidateVersion(version string) error {
+	if !VersionRegex.MatchString(version) {
+		return errors.Errorf("version %#v does not match the format BUILD-SHA-BRANCH", version)
+	}
+	return nil
+}
+
+// Check a SummonPlatform for mistakes which would otherwise only show up during a reconcile.
+func (s *SummonPlatform) Validate() error {
+	return s.validate().ToAggregate()
+}
+
+// Check a change to an existing SummonPlatform. Moving between a shared and an exclusive database would
+// orphan the existing data, so it isn't allowed.
+func (s *SummonPlatform) ValidateUpdate(oldObj runtime.Object) error {
+	old := oldObj.(*SummonPlatform)
+	allErrs := s.validate()
+	if s.Spec.Database.ExclusiveDatabase != old.Spec.Database.ExclusiveDatabase {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "database", "exclusiveDatabase"), "cannot be changed on an existing instance"))
+	}
+	return allErrs.ToAggregate()
+}
+
+func (s *SummonPlatform) validate() field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	err := ValidateVersion(s.Spec.Version)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("version"), s.Spec.Version, err.Error()))
+	}
+
+	replicas := []struct {
+		name  string
+		value *int32
+	}{
+		{"webReplicas", s.Spec.WebReplicas},
+		{"daphneReplicas", s.Spec.DaphneReplicas},
+		{"workerReplicas", s.Spec.WorkerReplicas},
+		{"channelWorkerReplicas", s.Spec.ChannelWorkerReplicas},
+		{"staticReplicas", s.Spec.StaticReplicas},
+	}
+	for _, r := range replicas {
+		if r.value != nil && *r.value < 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child(r.name), *r.value, "must not be negative"))
+		}
+	}
+
+	for _, autoscaler := range s.Spec.Autoscaling.autoscalers() {
+		if autoscaler.spec != nil {
+			allErrs = append(allErrs, validateAutoscaler(autoscaler.spec, specPath.Child("autoscaling", autoscaler.name))...)
+		}
+	}
+
+	for _, overrides := range s.Spec.Overrides.subsystems() {
+		allErrs = append(allErrs, validatePodOverrides(overrides.spec, specPath.Child("overrides", overrides.name))...)
+	}
+
+	if s.Spec.Rollback.MigrationFailures < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("rollback", "migrationFailures"), s.Spec.Rollback.MigrationFailures, "must not be negative"))
+	}
+	allErrs = append(allErrs, s.validateRollout(specPath.Child("rollout"))...)
+	allErrs = append(allErrs, s.validateHooks(specPath.Child("hooks"))...)
+	if s.Spec.FernetKeyLifetime < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("fernetKeyLifetime"), s.Spec.FernetKeyLifetime, "must not be negative"))
+	}
+	return allErrs
+}
+
+func (s *SummonPlatform) validateRollout(rolloutPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	rollout := s.Spec.Rollout
+	switch rollout.Strategy {
+	case "", RolloutRollingUpdate, RolloutBlueGreen, RolloutCanary:
+	default:
+		allErrs = append(allErrs, field.NotSupported(rolloutPath.Child("strategy"), rollout.Strategy, []string{string(RolloutRollingUpdate), string(RolloutBlueGreen), string(RolloutCanary)}))
+	}
+	if rollout.CanaryWeight < 0 || rollout.CanaryWeight > 100 {
+		allErrs = append(allErrs, field.Invalid(rolloutPath.Child("canaryWeight"), rollout.CanaryWeight, "must be a percentage"))
+	}
+	if rollout.CanaryDuration != nil && rollout.CanaryDuration.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(rolloutPath.Child("canaryDuration"), rollout.CanaryDuration.Duration, "must not be negative"))
+	}
+	if rollout.Check.Path != "" && !strings.HasPrefix(rollout.Check.Path, "/") {
+		allErrs = append(allErrs, field.Invalid(rolloutPath.Child("check", "path"), rollout.Check.Path, "must start with /"))
+	}
+	if rollout.Check.ExpectedStatus != 0 && (rollout.Check.ExpectedStatus < 100 || rollout.Check.ExpectedStatus > 599) {
+		allErrs = append(allErrs, field.Invalid(rolloutPath.Child("check", "expectedStatus"), rollout.Check.ExpectedStatus, "must be an HTTP status code"))
+	}
+	return allErrs
+}
+
+type namedAutoscaler struct {
+	name string
+	spec *AutoscalerSpec
+}
+
+// The autoscaler for each subsystem along with its JSON name, for defaulting and validation.
+func (a *AutoscalingSpec) autoscalers() []namedAutoscaler {
+	return []namedAutoscaler{
+		{"web", a.Web},
+		{"daphne", a.Daphne},
+		{"celeryd", a.Celeryd},
+		{"channelWorker", a.ChannelWorker},
+		{"static", a.Static},
+	}
+}
+
+func validateAutoscaler(autoscaler *AutoscalerSpec, autoscalerPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if autoscaler.MaxReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(autoscalerPath.Child("maxReplicas"), autoscaler.MaxReplicas, "must be at least 1"))
+	}
+	if autoscaler.MinReplicas != nil {
+		if *autoscaler.MinReplicas < 1 {
+			allErrs = append(allErrs, field.Invalid(autoscalerPath.Child("minReplicas"), *autoscaler.MinReplicas, "must be at least 1"))
+		} else if *autoscaler.MinReplicas > autoscaler.MaxReplicas {
+			allErrs = append(allErrs, field.Invalid(autoscalerPath.Child("minReplicas"), *autoscaler.MinReplicas, "must not be more than maxReplicas"))
+		}
+	}
+	if autoscaler.TargetCPUUtilization != nil && *autoscaler.TargetCPUUtilization < 1 {
+		allErrs = append(allErrs, field.Invalid(autoscalerPath.Child("targetCPUUtilization"), *autoscaler.TargetCPUUtilization, "must be at least 1"))
+	}
+	if autoscaler.TargetMemoryUtilization != nil && *autoscaler.TargetMemoryUtilization < 1 {
+		allErrs = append(allErrs, field.Invalid(autoscalerPath.Child("targetMemoryUtilization"), *autoscaler.TargetMemoryUtilization, "must be at least 1"))
+	}
+	return allErrs
+}
+
+type namedPodOverrides struct {
+	name string
+	spec *PodOverrides
+}
+
+// The overrides for each subsystem along with its JSON name.
+func (o *OverridesSpec) subsystems() []namedPodOverrides {
+	return []namedPodOverrides{
+		{"web", &o.Web},
+		{"daphne", &o.Daphne},
+		{"celeryd", &o.Celeryd},
+		{"channelWorker", &o.ChannelWorker},
+		{"static", &o.Static},
+		{"celerybeat", &o.Celerybeat},
+		{"migrations", &o.Migrations},
+		{"hooks", &o.Hooks},
+		{"redis", &o.Redis},
+	}
+}
+
+func validatePodOverrides(overrides *PodOverrides, overridesPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if overrides.PriorityClassName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(overrides.PriorityClassName) {
+			allErrs = append(allErrs, field.Invalid(overridesPath.Child("priorityClassName"), overrides.PriorityClassName, msg))
+		}
+	}
+	for i, env := range overrides.Env {
+		for _, msg := range validation.IsEnvVarName(env.Name) {
+			allErrs = append(allErrs, field.Invalid(overridesPath.Child("env").Index(i).Child("name"), env.Name, msg))
+		}
+	}
+	return allErrs
+}
+
+// Hook names end up in Job names, which need to leave room for the pod name suffix.
+const maxHookNameLength = 40
+
+func (s *SummonPlatform) validateHooks(hooksPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := map[string]bool{}
+	for i, hook := range s.Spec.Hooks {
+		hookPath := hooksPath.Index(i)
+		for _, msg := range validation.IsDNS1123Label(hook.Name) {
+			allErrs = append(allErrs, field.Invalid(hookPath.Child("name"), hook.Name, msg))
+		}
+		if len(hook.Name) > maxHookNameLength {
+			allErrs = append(allErrs, field.TooLong(hookPath.Child("name"), hook.Name, maxHookNameLength))
+		}
+		if names[hook.Name] {
+			allErrs = append(allErrs, field.Duplicate(hookPath.Child("name"), hook.Name))
+		}
+		names[hook.Name] = true
+		switch hook.Phase {
+		case HookPreMigrate, HookPostMigrate, HookPostDeploy:
+		default:
+			allErrs = append(allErrs, field.NotSupported(hookPath.Child("phase"), hook.Phase, []string{string(HookPreMigrate), string(HookPostMigrate), string(HookPostDeploy)}))
+		}
+		if hook.Command == "" {
+			allErrs = append(allErrs, field.Required(hookPath.Child("command"), ""))
+		}
+		switch hook.FailurePolicy {
+		case "", HookFailureFail, HookFailureIgnore:
+		default:
+			allErrs = append(allErrs, field.NotSupported(hookPath.Child("failurePolicy"), hook.FailurePolicy, []string{string(HookFailureFail), string(HookFailureIgnore)}))
+		}
+	}
+	return allErrs
+}
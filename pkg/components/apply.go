@@ -0,0 +1,169 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Annotation holding the configuration Apply last wrote, used to work out which fields we own.
+const LastAppliedAnnotation = "ridecell.io/last-applied"
+
+// Render a template and apply it like `kubectl apply`. Unlike CreateOrUpdate, fields set by other
+// controllers, the API server, or humans are left alone, and fields which the template used to set but
+// doesn't any more are removed. This includes labels and annotations.
+func (ctx *ComponentContext) Apply(path string, extraData map[string]interface{}) (Result, controllerutil.OperationResult, error) {
+	target, err := ctx.GetTemplate(path, extraData)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, err
+	}
+	return ctx.ApplyObject(target)
+}
+
+// Apply an object built some other way than from a template. See Apply.
+func (ctx *ComponentContext) ApplyObject(target runtime.Object) (Result, controllerutil.OperationResult, error) {
+	target = target.DeepCopyObject()
+	targetMeta := target.(metav1.Object)
+	err := controllerutil.SetControllerReference(ctx.Top.(metav1.Object), targetMeta, ctx.Scheme)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrap(err, "apply: error setting owner reference")
+	}
+	modified, err := setLastApplied(target)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, err
+	}
+
+	// Get into a fresh object so nothing from the target leaks into what we think is live.
+	gvk, err := apiutil.GVKForObject(target, ctx.Scheme)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrap(err, "apply: error getting object kind")
+	}
+	existing, err := ctx.Scheme.New(gvk)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrapf(err, "apply: error making a new %s", gvk.Kind)
+	}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: targetMeta.GetName(), Namespace: targetMeta.GetNamespace()}, existing)
+	if err != nil && kerrors.IsNotFound(err) {
+		err = ctx.Create(ctx.Context, target)
+		if err != nil {
+			return Result{Requeue: true}, controllerutil.OperationResultNone, errors.Wrapf(err, "apply: error creating %s %s/%s", gvk.Kind, targetMeta.GetNamespace(), targetMeta.GetName())
+		}
+		return Result{}, controllerutil.OperationResultCreated, ctx.KeepObject(target)
+	} else if err != nil {
+		return Result{Requeue: true}, controllerutil.OperationResultNone, errors.Wrapf(err, "apply: error getting %s %s/%s", gvk.Kind, targetMeta.GetNamespace(), targetMeta.GetName())
+	}
+
+	// Work out what we need to change, based on what we applied last time.
+	original := existing.(metav1.Object).GetAnnotations()[LastAppliedAnnotation]
+	current, err := json.Marshal(existing)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrap(err, "apply: error marshalling live object")
+	}
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(existing)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrap(err, "apply: error getting patch metadata")
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch([]byte(original), modified, current, patchMeta, true)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrap(err, "apply: error computing patch")
+	}
+	if string(patch) == "{}" {
+		// Already up to date.
+		return Result{}, controllerutil.OperationResultNone, ctx.KeepObject(target)
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch(current, patch, existing)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrap(err, "apply: error applying patch")
+	}
+	updated, err := ctx.Scheme.New(gvk)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrapf(err, "apply: error making a new %s", gvk.Kind)
+	}
+	err = json.Unmarshal(patched, updated)
+	if err != nil {
+		return Result{}, controllerutil.OperationResultNone, errors.Wrap(err, "apply: error unmarshalling patched object")
+	}
+	err = ctx.Update(ctx.Context, updated)
+	if err != nil {
+		return Result{Requeue: true}, controllerutil.OperationResultNone, errors.Wrapf(err, "apply: error updating %s %s/%s", gvk.Kind, targetMeta.GetNamespace(), targetMeta.GetName())
+	}
+	return Result{}, controllerutil.OperationResultUpdated, ctx.KeepObject(target)
+}
+
+// Store the configuration being applied in an annotation on the object and return the JSON of the
+// object including that annotation, which is what we diff against.
+func setLastApplied(obj runtime.Object) ([]byte, error) {
+	objMeta := obj.(metav1.Object)
+	annotations := objMeta.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	delete(annotations, LastAppliedAnnotation)
+	objMeta.SetAnnotations(annotations)
+
+	lastApplied, err := marshalApplied(obj)
+	if err != nil {
+		return nil, err
+	}
+	annotations[LastAppliedAnnotation] = string(lastApplied)
+	objMeta.SetAnnotations(annotations)
+	return marshalApplied(obj)
+}
+
+// Marshal an object, dropping nulls. Typed objects always have things like a null creationTimestamp
+// which would otherwise look like we want to clear those fields.
+func marshalApplied(obj runtime.Object) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "apply: error marshalling object")
+	}
+	var parsed map[string]interface{}
+	err = json.Unmarshal(data, &parsed)
+	if err != nil {
+		return nil, errors.Wrap(err, "apply: error parsing object")
+	}
+	delete(parsed, "status")
+	return json.Marshal(dropNulls(parsed))
+}
+
+func dropNulls(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for k, v := range typed {
+			if v == nil {
+				delete(typed, k)
+				continue
+			}
+			typed[k] = dropNulls(v)
+		}
+	case []interface{}:
+		for i, v := range typed {
+			typed[i] = dropNulls(v)
+		}
+	}
+	return value
+}
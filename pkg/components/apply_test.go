@@ -0,0 +1,99 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var _ = Describe("ComponentContext.Apply", func() {
+	var ctx *ComponentContext
+
+	makeTarget := func() *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-web",
+				Namespace: "default",
+				Labels:    map[string]string{"app": "web", "old": "true"},
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Name: "http", Port: 8000}},
+			},
+		}
+	}
+
+	fetch := func() *corev1.Service {
+		service := &corev1.Service{}
+		err := ctx.Get(context.TODO(), types.NamespacedName{Name: "foo-web", Namespace: "default"}, service)
+		Expect(err).ToNot(HaveOccurred())
+		return service
+	}
+
+	BeforeEach(func() {
+		top := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "1234"}}
+		ctx = NewTestContext(top, nil)
+	})
+
+	It("creates the object with a last-applied annotation", func() {
+		_, op, err := ctx.ApplyObject(makeTarget())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(op).To(Equal(controllerutil.OperationResultCreated))
+		service := fetch()
+		Expect(service.Annotations).To(HaveKey(LastAppliedAnnotation))
+		Expect(service.OwnerReferences).To(HaveLen(1))
+	})
+
+	It("doesn't update an object which is already applied", func() {
+		_, _, err := ctx.ApplyObject(makeTarget())
+		Expect(err).ToNot(HaveOccurred())
+		_, op, err := ctx.ApplyObject(makeTarget())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(op).To(Equal(controllerutil.OperationResultNone))
+	})
+
+	It("keeps fields set by someone else and removes fields it stopped setting", func() {
+		_, _, err := ctx.ApplyObject(makeTarget())
+		Expect(err).ToNot(HaveOccurred())
+
+		// Simulate the API server and a human changing things.
+		service := fetch()
+		service.Spec.ClusterIP = "10.0.0.1"
+		service.Labels["human"] = "true"
+		Expect(ctx.Update(context.TODO(), service)).To(Succeed())
+
+		target := makeTarget()
+		delete(target.Labels, "old")
+		target.Spec.Ports[0].Port = 8080
+		_, op, err := ctx.ApplyObject(target)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(op).To(Equal(controllerutil.OperationResultUpdated))
+
+		service = fetch()
+		Expect(service.Spec.ClusterIP).To(Equal("10.0.0.1"))
+		Expect(service.Spec.Ports[0].Port).To(Equal(int32(8080)))
+		Expect(service.Labels).To(HaveKeyWithValue("human", "true"))
+		Expect(service.Labels).To(HaveKeyWithValue("app", "web"))
+		Expect(service.Labels).ToNot(HaveKey("old"))
+	})
+})
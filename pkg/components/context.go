@@ -17,18 +17,22 @@ limitations under the License.
 package components
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/pkg/errors"
 	meta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
 
 	"github.com/blaggacao/ridecell-operator/pkg/templates"
 )
@@ -39,10 +43,24 @@ func (ctx *ComponentContext) GetTemplate(path string, extraData map[string]inter
 		ctx.Logger.Error(err, "no templates loaded for this reconciler")
 		return nil, err
 	}
-	return templates.Get(ctx.templates, path, struct {
+	return templates.Get(ctx.templates, path, ctx.templateData(extraData))
+}
+
+// Like GetTemplate, but for templates which render any number of objects.
+func (ctx *ComponentContext) GetTemplateList(path string, extraData map[string]interface{}) ([]runtime.Object, error) {
+	if ctx.templates == nil {
+		err := fmt.Errorf("no templates loaded for this reconciler")
+		ctx.Logger.Error(err, "no templates loaded for this reconciler")
+		return nil, err
+	}
+	return templates.GetList(ctx.templates, path, ctx.templateData(extraData))
+}
+
+func (ctx *ComponentContext) templateData(extraData map[string]interface{}) interface{} {
+	return struct {
 		Instance runtime.Object
 		Extra    map[string]interface{}
-	}{Instance: ctx.Top, Extra: extraData})
+	}{Instance: ctx.Top, Extra: extraData}
 }
 
 func (ctx *ComponentContext) CreateOrUpdate(path string, extraData map[string]interface{}, mutateFn func(runtime.Object, runtime.Object) error) (Result, controllerutil.OperationResult, error) {
@@ -50,7 +68,26 @@ func (ctx *ComponentContext) CreateOrUpdate(path string, extraData map[string]in
 	if err != nil {
 		return Result{}, controllerutil.OperationResultNone, err
 	}
+	return ctx.createOrUpdateObject(target, mutateFn)
+}
+
+// Reconcile every object rendered by a multi-document template. mutateFn is called for each object.
+func (ctx *ComponentContext) CreateOrUpdateAll(path string, extraData map[string]interface{}, mutateFn func(runtime.Object, runtime.Object) error) (Result, error) {
+	targets, err := ctx.GetTemplateList(path, extraData)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, target := range targets {
+		res, _, err := ctx.createOrUpdateObject(target, mutateFn)
+		if err != nil {
+			accessor := target.(metav1.Object)
+			return res, errors.Wrapf(err, "error reconciling %s/%s", accessor.GetNamespace(), accessor.GetName())
+		}
+	}
+	return Result{}, nil
+}
 
+func (ctx *ComponentContext) createOrUpdateObject(target runtime.Object, mutateFn func(runtime.Object, runtime.Object) error) (Result, controllerutil.OperationResult, error) {
 	op, err := controllerutil.CreateOrUpdate(ctx.Context, ctx, target.DeepCopyObject(), func(existing runtime.Object) error {
 		// Set owner ref.
 		err := controllerutil.SetControllerReference(ctx.Top.(metav1.Object), existing.(metav1.Object), ctx.Scheme)
@@ -71,6 +108,10 @@ func (ctx *ComponentContext) CreateOrUpdate(path string, extraData map[string]in
 		return Result{Requeue: true}, op, err
 	}
 
+	err = ctx.KeepObject(target)
+	if err != nil {
+		return Result{}, op, err
+	}
 	return Result{}, op, nil
 }
 
@@ -127,15 +168,38 @@ func (ctx *ComponentContext) GetOne(obj runtime.Object, labels map[string]string
 
 }
 
-// Method for creating a test context, for use in component unit tests.
+// Record an Event on the top object. eventType should be corev1.EventTypeNormal or corev1.EventTypeWarning
+// and reason a short CamelCase description like "MigrationJobCreated".
+func (ctx *ComponentContext) Eventf(eventType, reason, messageFmt string, args ...interface{}) {
+	if ctx.Recorder == nil {
+		return
+	}
+	if ctx.IsDryRun() {
+		ctx.Logger.V(1).Info("dry run, not recording event", "reason", reason, "message", fmt.Sprintf(messageFmt, args...))
+		return
+	}
+	ctx.Recorder.Eventf(ctx.Top, eventType, reason, messageFmt, args...)
+}
+
+// Method for creating a test context, for use in component unit tests. New tests should use the fake
+// package instead, which can record and fail individual calls. A top object with the dry-run annotation
+// gets a dry-run context, the same as in the reconciler.
 func NewTestContext(top runtime.Object, templates http.FileSystem) *ComponentContext {
-	// This method is ugly and I don't like it. I should rebuild this whole subsytem around interfaces and have an explicit fake for it.
-	return &ComponentContext{
+	ctx := &ComponentContext{
 		Top:       top,
+		Context:   context.TODO(),
+		Logger:    log.Log.WithName("test"),
 		Client:    fake.NewFakeClient(top),
 		Scheme:    scheme.Scheme,
 		templates: templates,
+		Recorder:  record.NewFakeRecorder(100),
+		inventory: newInventory(),
+	}
+	if isDryRun(top) {
+		ctx.dryRun = &dryRunClient{Client: ctx.Client, scheme: ctx.Scheme}
+		ctx.Client = ctx.dryRun
 	}
+	return ctx
 }
 
 // ComponentContext implements inject.Client.
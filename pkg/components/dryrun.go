@@ -0,0 +1,189 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Set from the manager's --dry-run flag to put every controller in dry-run mode.
+var DryRun bool
+
+// Annotation to put a single top object in dry-run mode, set it to "true".
+const DryRunAnnotation = "ridecell.io/dry-run"
+
+// A write which would have happened if this wasn't a dry run.
+type DryRunChange struct {
+	// One of create, update, or delete.
+	Operation string
+	// The object kind, or for changes outside of Kubernetes the service and type like "aws:iam:User".
+	Kind      string
+	Namespace string
+	Name      string
+	// For updates this is a strategic merge patch against the live object, for creates it is the whole
+	// rendered object.
+	Diff string
+}
+
+// An optional interface for top objects which can show a dry run's changes in their status. Other status
+// changes are discarded during a dry run.
+type DryRunReporter interface {
+	SetDryRunChanges([]DryRunChange)
+}
+
+// A client wrapper which records writes instead of sending them to the API server. Reads pass through
+// so components still see the live state.
+type dryRunClient struct {
+	client.Client
+	scheme *runtime.Scheme
+
+	// Shared by every component in a reconcile, which may be running concurrently.
+	mu      sync.Mutex
+	changes []DryRunChange
+}
+
+func isDryRun(top runtime.Object) bool {
+	if DryRun {
+		return true
+	}
+	obj, ok := top.(metav1.Object)
+	return ok && obj.GetAnnotations()[DryRunAnnotation] == "true"
+}
+
+func (c *dryRunClient) Create(ctx context.Context, obj runtime.Object) error {
+	rendered, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, "dry run: error marshalling object")
+	}
+	return c.record("create", obj, string(rendered))
+}
+
+func (c *dryRunClient) Update(ctx context.Context, obj runtime.Object) error {
+	objMeta := obj.(metav1.Object)
+	live := obj.DeepCopyObject()
+	err := c.Get(ctx, types.NamespacedName{Name: objMeta.GetName(), Namespace: objMeta.GetNamespace()}, live)
+	if err != nil {
+		// Same as a real Update, you can't update something which isn't there.
+		return err
+	}
+	// Typed objects don't always have their TypeMeta filled in, don't count that as a change.
+	live.GetObjectKind().SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	liveJSON, err := json.Marshal(live)
+	if err != nil {
+		return errors.Wrap(err, "dry run: error marshalling live object")
+	}
+	objJSON, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, "dry run: error marshalling object")
+	}
+	patch, err := strategicpatch.CreateTwoWayMergePatch(liveJSON, objJSON, obj)
+	if err != nil {
+		return errors.Wrap(err, "dry run: error computing diff")
+	}
+	if string(patch) == "{}" {
+		return nil
+	}
+	return c.record("update", obj, string(patch))
+}
+
+func (c *dryRunClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOptionFunc) error {
+	return c.record("delete", obj, "")
+}
+
+func (c *dryRunClient) record(operation string, obj runtime.Object, diff string) error {
+	objMeta := obj.(metav1.Object)
+	change := DryRunChange{
+		Operation: operation,
+		Namespace: objMeta.GetNamespace(),
+		Name:      objMeta.GetName(),
+		Diff:      diff,
+	}
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err == nil {
+		change.Kind = gvk.Kind
+	}
+	c.add(change)
+	return nil
+}
+
+func (c *dryRunClient) add(change DryRunChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, change)
+}
+
+func (c *dryRunClient) getChanges() []DryRunChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]DryRunChange{}, c.changes...)
+}
+
+// Check if this reconcile is a dry run. Components which make changes outside of Kubernetes should skip
+// them when this is true.
+func (ctx *ComponentContext) IsDryRun() bool {
+	return ctx.dryRun != nil
+}
+
+// Record a change outside of Kubernetes which was skipped because this is a dry run, so it shows up in the
+// report alongside the object changes. Does nothing outside of a dry run.
+func (ctx *ComponentContext) RecordDryRun(operation, kind, name, diff string) {
+	if ctx.dryRun == nil {
+		return
+	}
+	ctx.dryRun.add(DryRunChange{Operation: operation, Kind: kind, Name: name, Diff: diff})
+}
+
+// Every change recorded so far in this dry run, for use in component unit tests.
+func (ctx *ComponentContext) DryRunChanges() []DryRunChange {
+	if ctx.dryRun == nil {
+		return nil
+	}
+	return ctx.dryRun.getChanges()
+}
+
+// Log the changes from a dry run and save them as the only status change.
+func (cr *componentReconciler) reportDryRun(ctx *ComponentContext, cleanTop runtime.Object, result *reconcilerResults) {
+	changes := ctx.dryRun.getChanges()
+	for _, change := range changes {
+		ctx.Logger.Info("dry run change", "operation", change.Operation, "kind", change.Kind, "namespace", change.Namespace, "name", change.Name, "diff", change.Diff)
+	}
+
+	// Put the real client back so the report can be saved.
+	ctx.Client = ctx.dryRun.Client
+	ctx.Top.(Statuser).SetStatus(cleanTop.(Statuser).GetStatus())
+	result.statusModifiers = nil
+	reporter, ok := ctx.Top.(DryRunReporter)
+	if !ok {
+		return
+	}
+	modifier := func(obj runtime.Object) error {
+		obj.(DryRunReporter).SetDryRunChanges(changes)
+		return nil
+	}
+	reporter.SetDryRunChanges(changes)
+	result.statusModifiers = []StatusModifier{modifier}
+}
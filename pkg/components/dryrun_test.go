@@ -0,0 +1,88 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("dry run client", func() {
+	var live *corev1.ConfigMap
+	var dryRun *dryRunClient
+
+	BeforeEach(func() {
+		live = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Data:       map[string]string{"a": "1"},
+		}
+		dryRun = &dryRunClient{Client: fake.NewFakeClient(live), scheme: scheme.Scheme}
+	})
+
+	It("records creates without writing them", func() {
+		obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"}}
+		Expect(dryRun.Create(context.TODO(), obj)).To(Succeed())
+
+		err := dryRun.Get(context.TODO(), types.NamespacedName{Name: "bar", Namespace: "default"}, &corev1.ConfigMap{})
+		Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		changes := dryRun.getChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("create"))
+		Expect(changes[0].Kind).To(Equal("ConfigMap"))
+		Expect(changes[0].Name).To(Equal("bar"))
+		Expect(changes[0].Diff).To(ContainSubstring(`"name":"bar"`))
+	})
+
+	It("records a diff for updates without writing them", func() {
+		obj := &corev1.ConfigMap{}
+		Expect(dryRun.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, obj)).To(Succeed())
+		obj.Data["a"] = "2"
+		Expect(dryRun.Update(context.TODO(), obj)).To(Succeed())
+
+		fetched := &corev1.ConfigMap{}
+		Expect(dryRun.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, fetched)).To(Succeed())
+		Expect(fetched.Data["a"]).To(Equal("1"))
+		changes := dryRun.getChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("update"))
+		Expect(changes[0].Diff).To(Equal(`{"data":{"a":"2"}}`))
+	})
+
+	It("ignores updates which don't change anything", func() {
+		obj := &corev1.ConfigMap{}
+		Expect(dryRun.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, obj)).To(Succeed())
+		Expect(dryRun.Update(context.TODO(), obj)).To(Succeed())
+		Expect(dryRun.getChanges()).To(BeEmpty())
+	})
+
+	It("records deletes without writing them", func() {
+		Expect(dryRun.Delete(context.TODO(), live)).To(Succeed())
+
+		Expect(dryRun.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, &corev1.ConfigMap{})).To(Succeed())
+		changes := dryRun.getChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("delete"))
+	})
+})
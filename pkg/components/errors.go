@@ -0,0 +1,142 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Bounds for the per-component exponential backoff used for transient and untyped errors.
+var (
+	BackoffBase = 1 * time.Second
+	BackoffMax  = 5 * time.Minute
+)
+
+// An error which is expected to go away by itself, like a timeout talking to an outside API or waiting
+// on something another controller makes. The top object isn't put into an error state and ErrorHandlers
+// aren't called, the component is just retried with backoff.
+type transientError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Cause() error {
+	return e.err
+}
+
+// An error which can't be fixed by trying again, like an invalid spec. The top object is put into an error
+// state and ErrorHandlers are called, but nothing is retried until the object changes.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Cause() error {
+	return e.err
+}
+
+// Mark an error as transient. If retryAfter is 0, the component's exponential backoff is used instead.
+func Transient(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err, retryAfter: retryAfter}
+}
+
+// Mark an error as permanent.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Look through any wrapping for a transient or permanent error. Doesn't use errors.Cause because that
+// would walk straight past our own types, which are causers too so errors.Cause still finds the original.
+func findTypedError(err error) error {
+	type causer interface {
+		Cause() error
+	}
+	for err != nil {
+		switch err.(type) {
+		case *transientError, *permanentError:
+			return err
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			return nil
+		}
+		err = cause.Cause()
+	}
+	return nil
+}
+
+// Check if an error, or anything it wraps, was marked as transient.
+func IsTransient(err error) bool {
+	_, ok := findTypedError(err).(*transientError)
+	return ok
+}
+
+// Check if an error, or anything it wraps, was marked as permanent.
+func IsPermanent(err error) bool {
+	_, ok := findTypedError(err).(*permanentError)
+	return ok
+}
+
+// Tracks failures for each component of each top object so retries back off independently.
+type componentBackoff struct {
+	limiter workqueue.RateLimiter
+}
+
+func newComponentBackoff() *componentBackoff {
+	return &componentBackoff{limiter: workqueue.NewItemExponentialFailureRateLimiter(BackoffBase, BackoffMax)}
+}
+
+func backoffKey(ctx *ComponentContext, component Component) string {
+	instance := ctx.Top.(metav1.Object)
+	return fmt.Sprintf("%s/%s/%s", instance.GetNamespace(), instance.GetName(), componentName(component))
+}
+
+// Work out how long to wait before retrying a failed component. Permanent errors aren't retried at all.
+func (b *componentBackoff) failed(ctx *ComponentContext, component Component, err error) time.Duration {
+	key := backoffKey(ctx, component)
+	if IsPermanent(err) {
+		b.limiter.Forget(key)
+		return 0
+	}
+	transient, ok := findTypedError(err).(*transientError)
+	if ok && transient.retryAfter != 0 {
+		return transient.retryAfter
+	}
+	return b.limiter.When(key)
+}
+
+// Reset the backoff for a component after it succeeds.
+func (b *componentBackoff) succeeded(ctx *ComponentContext, component Component) {
+	b.limiter.Forget(backoffKey(ctx, component))
+}
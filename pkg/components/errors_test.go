@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("typed errors", func() {
+	It("finds a transient error through wrapping", func() {
+		err := errors.Wrap(Transient(errors.New("timeout"), 0), "outer")
+		Expect(IsTransient(err)).To(BeTrue())
+		Expect(IsPermanent(err)).To(BeFalse())
+		Expect(err.Error()).To(Equal("outer: timeout"))
+	})
+
+	It("finds a permanent error through wrapping", func() {
+		err := errors.Wrap(Permanent(errors.New("bad spec")), "outer")
+		Expect(IsPermanent(err)).To(BeTrue())
+		Expect(IsTransient(err)).To(BeFalse())
+	})
+
+	It("doesn't hide the original cause", func() {
+		inner := errors.New("inner")
+		Expect(errors.Cause(Transient(errors.Wrap(inner, "middle"), 0))).To(Equal(inner))
+	})
+
+	It("treats untyped errors as neither", func() {
+		err := errors.New("something")
+		Expect(IsTransient(err)).To(BeFalse())
+		Expect(IsPermanent(err)).To(BeFalse())
+	})
+
+	It("keeps nil errors nil", func() {
+		Expect(Transient(nil, time.Second)).To(BeNil())
+		Expect(Permanent(nil)).To(BeNil())
+	})
+})
+
+var _ = Describe("component backoff", func() {
+	var backoff *componentBackoff
+	var ctx *ComponentContext
+	var comp Component
+
+	BeforeEach(func() {
+		backoff = newComponentBackoff()
+		ctx = &ComponentContext{Top: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}}
+		comp = &legacyTestComponent{"a"}
+	})
+
+	It("backs off exponentially until the component succeeds", func() {
+		err := errors.New("failed")
+		Expect(backoff.failed(ctx, comp, err)).To(Equal(BackoffBase))
+		Expect(backoff.failed(ctx, comp, err)).To(Equal(2 * BackoffBase))
+		Expect(backoff.failed(ctx, comp, err)).To(Equal(4 * BackoffBase))
+		backoff.succeeded(ctx, comp)
+		Expect(backoff.failed(ctx, comp, err)).To(Equal(BackoffBase))
+	})
+
+	It("tracks each component separately", func() {
+		err := errors.New("failed")
+		Expect(backoff.failed(ctx, comp, err)).To(Equal(BackoffBase))
+		Expect(backoff.failed(ctx, &legacyTestComponent{"b"}, err)).To(Equal(BackoffBase))
+	})
+
+	It("uses the delay from a transient error", func() {
+		Expect(backoff.failed(ctx, comp, Transient(errors.New("failed"), time.Minute))).To(Equal(time.Minute))
+	})
+
+	It("doesn't retry permanent errors", func() {
+		Expect(backoff.failed(ctx, comp, Permanent(errors.New("failed")))).To(BeZero())
+	})
+})
@@ -0,0 +1,259 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// The verbs recorded by the fake client.
+const (
+	VerbGet          = "get"
+	VerbList         = "list"
+	VerbCreate       = "create"
+	VerbUpdate       = "update"
+	VerbUpdateStatus = "update-status"
+	VerbDelete       = "delete"
+)
+
+// A single call made through the fake client.
+type Action struct {
+	Verb      string
+	Kind      string
+	Namespace string
+	Name      string
+	// A copy of the object as it was passed in, nil for gets and lists.
+	Object runtime.Object
+	// The error returned to the caller, if any.
+	Err error
+}
+
+// An error to return from matching calls instead of running them.
+type Injection struct {
+	verb string
+	kind string
+	name string
+	err  error
+	// How many more calls to fail, -1 for all of them.
+	remaining int
+}
+
+// Only fail the next matching call.
+func (i *Injection) Once() *Injection {
+	i.remaining = 1
+	return i
+}
+
+// Only fail the next n matching calls.
+func (i *Injection) Times(n int) *Injection {
+	i.remaining = n
+	return i
+}
+
+func (i *Injection) matches(verb, kind, name string) bool {
+	return i.remaining != 0 && i.verb == verb && (i.kind == "" || i.kind == kind) && (i.name == "" || i.name == name)
+}
+
+// An in-memory client which records every call made through it and can be told to fail specific calls.
+type Client struct {
+	client.Client
+	scheme *runtime.Scheme
+
+	mu         sync.Mutex
+	actions    []Action
+	injections []*Injection
+}
+
+func NewClient(scheme *runtime.Scheme, objs ...runtime.Object) *Client {
+	return &Client{Client: fake.NewFakeClientWithScheme(scheme, objs...), scheme: scheme}
+}
+
+// Make matching calls fail with err. An empty kind or name matches anything.
+func (c *Client) InjectError(verb, kind, name string, err error) *Injection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	injection := &Injection{verb: verb, kind: kind, name: name, err: err, remaining: -1}
+	c.injections = append(c.injections, injection)
+	return injection
+}
+
+// Make matching calls fail with a conflict, like when someone else wrote the object first.
+func (c *Client) InjectConflict(verb, kind, name string) *Injection {
+	return c.InjectError(verb, kind, name, kerrors.NewConflict(schema.GroupResource{Resource: strings.ToLower(kind)}, name, errors.New("injected conflict")))
+}
+
+// Make matching calls fail with NotFound.
+func (c *Client) InjectNotFound(verb, kind, name string) *Injection {
+	return c.InjectError(verb, kind, name, kerrors.NewNotFound(schema.GroupResource{Resource: strings.ToLower(kind)}, name))
+}
+
+// Remove all injected errors.
+func (c *Client) ClearErrors() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.injections = nil
+}
+
+// Every call made so far, in order.
+func (c *Client) Actions() []Action {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Action{}, c.actions...)
+}
+
+// Every successful call with the given verb, like VerbCreate.
+func (c *Client) ActionsFor(verb string) []Action {
+	actions := []Action{}
+	for _, action := range c.Actions() {
+		if action.Verb == verb && action.Err == nil {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// Find the last successful call with the given verb on a named object, or nil if there wasn't one.
+func (c *Client) Find(verb, kind, name string) *Action {
+	actions := c.ActionsFor(verb)
+	for i := len(actions) - 1; i >= 0; i-- {
+		if actions[i].Kind == kind && actions[i].Name == name {
+			return &actions[i]
+		}
+	}
+	return nil
+}
+
+// Check if a named object was created.
+func (c *Client) Created(kind, name string) bool {
+	return c.Find(VerbCreate, kind, name) != nil
+}
+
+// Check if a named object was updated.
+func (c *Client) Updated(kind, name string) bool {
+	return c.Find(VerbUpdate, kind, name) != nil
+}
+
+// Check if a named object was deleted.
+func (c *Client) Deleted(kind, name string) bool {
+	return c.Find(VerbDelete, kind, name) != nil
+}
+
+// Forget every recorded call, but keep the objects and injected errors.
+func (c *Client) ResetActions() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = nil
+}
+
+func (c *Client) Get(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+	return c.do(VerbGet, c.kindOf(obj), key.Namespace, key.Name, nil, func() error {
+		return c.Client.Get(ctx, key, obj)
+	})
+}
+
+func (c *Client) List(ctx context.Context, opts *client.ListOptions, list runtime.Object) error {
+	namespace := ""
+	if opts != nil {
+		namespace = opts.Namespace
+	}
+	return c.do(VerbList, strings.TrimSuffix(c.kindOf(list), "List"), namespace, "", nil, func() error {
+		return c.Client.List(ctx, opts, list)
+	})
+}
+
+func (c *Client) Create(ctx context.Context, obj runtime.Object) error {
+	return c.write(VerbCreate, obj, func() error {
+		return c.Client.Create(ctx, obj)
+	})
+}
+
+func (c *Client) Update(ctx context.Context, obj runtime.Object) error {
+	return c.write(VerbUpdate, obj, func() error {
+		return c.Client.Update(ctx, obj)
+	})
+}
+
+func (c *Client) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOptionFunc) error {
+	return c.write(VerbDelete, obj, func() error {
+		return c.Client.Delete(ctx, obj, opts...)
+	})
+}
+
+func (c *Client) Status() client.StatusWriter {
+	return &statusWriter{client: c}
+}
+
+type statusWriter struct {
+	client *Client
+}
+
+func (w *statusWriter) Update(ctx context.Context, obj runtime.Object) error {
+	return w.client.write(VerbUpdateStatus, obj, func() error {
+		return w.client.Client.Status().Update(ctx, obj)
+	})
+}
+
+func (c *Client) write(verb string, obj runtime.Object, fn func() error) error {
+	objMeta := obj.(metav1.Object)
+	return c.do(verb, c.kindOf(obj), objMeta.GetNamespace(), objMeta.GetName(), obj.DeepCopyObject(), fn)
+}
+
+func (c *Client) do(verb, kind, namespace, name string, obj runtime.Object, fn func() error) error {
+	err := c.injectedError(verb, kind, name)
+	if err == nil {
+		err = fn()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = append(c.actions, Action{Verb: verb, Kind: kind, Namespace: namespace, Name: name, Object: obj, Err: err})
+	return err
+}
+
+func (c *Client) injectedError(verb, kind, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, injection := range c.injections {
+		if injection.matches(verb, kind, name) {
+			if injection.remaining > 0 {
+				injection.remaining--
+			}
+			return injection.err
+		}
+	}
+	return nil
+}
+
+func (c *Client) kindOf(obj runtime.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		// Not in the scheme, the real call will fail anyway.
+		return ""
+	}
+	return gvk.Kind
+}
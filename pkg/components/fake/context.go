@@ -0,0 +1,112 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an explicit fake ComponentContext for component unit tests, with an in-memory
+// client which records every call and an event recorder.
+package fake
+
+import (
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+// A ComponentContext wired up to fakes, pass the embedded ComponentContext to components.
+type Context struct {
+	*components.ComponentContext
+	Client   *Client
+	Recorder *record.FakeRecorder
+}
+
+// Make a fake context for the given top object. Any other objects are loaded into the fake client. Uses
+// the global client-go scheme, so make sure the operator's APIs have been added to it.
+func NewContext(top runtime.Object, templates http.FileSystem, objs ...runtime.Object) *Context {
+	ctx := components.NewTestContext(top, templates)
+	fakeClient := NewClient(scheme.Scheme, append([]runtime.Object{top}, objs...)...)
+	recorder := record.NewFakeRecorder(100)
+	ctx.Client = fakeClient
+	ctx.Recorder = recorder
+	return &Context{ComponentContext: ctx, Client: fakeClient, Recorder: recorder}
+}
+
+// Every event recorded since the last call, formatted like "Normal Reason message".
+func (ctx *Context) Events() []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-ctx.Recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// Run components in order the way the reconciler does. Components which aren't reconcilable are skipped,
+// status modifiers are applied to Top, and the first error stops the run and is passed to every
+// reconcilable ErrorHandler, unless it was marked Transient. Dependencies aren't checked, so list the
+// components in a working order.
+func (ctx *Context) Run(comps ...components.Component) (components.Result, error) {
+	res := components.Result{}
+	for _, comp := range comps {
+		if !comp.IsReconcilable(ctx.ComponentContext) {
+			continue
+		}
+		compRes, err := comp.Reconcile(ctx.ComponentContext)
+		statusErr := ctx.merge(&res, compRes)
+		if err == nil {
+			err = statusErr
+		}
+		if err != nil {
+			if !components.IsTransient(err) {
+				ctx.dispatchError(&res, comps, err)
+			}
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (ctx *Context) merge(res *components.Result, compRes components.Result) error {
+	if compRes.Requeue {
+		res.Requeue = true
+	}
+	if compRes.RequeueAfter != 0 && (res.RequeueAfter == 0 || res.RequeueAfter > compRes.RequeueAfter) {
+		res.RequeueAfter = compRes.RequeueAfter
+	}
+	if compRes.StatusModifier != nil {
+		return compRes.StatusModifier(ctx.Top)
+	}
+	return nil
+}
+
+func (ctx *Context) dispatchError(res *components.Result, comps []components.Component, err error) {
+	for _, comp := range comps {
+		errorHandler, ok := comp.(components.ErrorHandler)
+		if !ok || !comp.IsReconcilable(ctx.ComponentContext) {
+			continue
+		}
+		compRes, errorErr := errorHandler.ReconcileError(ctx.ComponentContext, err)
+		ctx.merge(res, compRes)
+		if errorErr != nil {
+			ctx.Logger.Error(errorErr, "error running error handler")
+		}
+	}
+}
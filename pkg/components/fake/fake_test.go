@@ -0,0 +1,138 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/components/fake"
+)
+
+// Creates a Secret named after the top object and marks the top object as done.
+type secretComponent struct {
+	err error
+}
+
+func (_ *secretComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *secretComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	return true
+}
+
+func (comp *secretComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	if comp.err != nil {
+		return components.Result{}, comp.err
+	}
+	top := ctx.Top.(*corev1.ConfigMap)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: top.Name, Namespace: top.Namespace}}
+	err := ctx.Create(ctx.Context, secret)
+	if err != nil {
+		return components.Result{}, err
+	}
+	ctx.Eventf(corev1.EventTypeNormal, "SecretCreated", "Created secret %s", secret.Name)
+	return components.Result{RequeueAfter: time.Minute, StatusModifier: func(obj runtime.Object) error {
+		obj.(*corev1.ConfigMap).Data = map[string]string{"done": "true"}
+		return nil
+	}}, nil
+}
+
+// Remembers the last error it was given.
+type errorHandlerComponent struct {
+	secretComponent
+	seen error
+}
+
+func (comp *errorHandlerComponent) Reconcile(_ *components.ComponentContext) (components.Result, error) {
+	return components.Result{}, nil
+}
+
+func (comp *errorHandlerComponent) ReconcileError(_ *components.ComponentContext, err error) (components.Result, error) {
+	comp.seen = err
+	return components.Result{}, nil
+}
+
+var _ = Describe("fake context", func() {
+	var ctx *fake.Context
+
+	BeforeEach(func() {
+		top := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+		ctx = fake.NewContext(top, nil)
+	})
+
+	It("records writes", func() {
+		_, err := ctx.Run(&secretComponent{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ctx.Client.Created("Secret", "foo")).To(BeTrue())
+		Expect(ctx.Client.Updated("Secret", "foo")).To(BeFalse())
+		Expect(ctx.Client.ActionsFor(fake.VerbCreate)).To(HaveLen(1))
+		Expect(ctx.Events()).To(ConsistOf("Normal SecretCreated Created secret foo"))
+	})
+
+	It("applies status modifiers and merges results", func() {
+		res, err := ctx.Run(&secretComponent{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(time.Minute))
+		Expect(ctx.Top.(*corev1.ConfigMap).Data).To(HaveKeyWithValue("done", "true"))
+	})
+
+	It("injects errors on matching calls", func() {
+		ctx.Client.InjectConflict(fake.VerbCreate, "Secret", "foo").Once()
+		_, err := ctx.Run(&secretComponent{})
+		Expect(kerrors.IsConflict(err)).To(BeTrue())
+		Expect(ctx.Client.Created("Secret", "foo")).To(BeFalse())
+
+		// Only failed once.
+		_, err = ctx.Run(&secretComponent{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ctx.Client.Created("Secret", "foo")).To(BeTrue())
+	})
+
+	It("injects NotFound on gets", func() {
+		ctx.Client.InjectNotFound(fake.VerbGet, "ConfigMap", "")
+		err := ctx.Client.Get(ctx.Context, types.NamespacedName{Name: "foo", Namespace: "default"}, &corev1.ConfigMap{})
+		Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		ctx.Client.ClearErrors()
+		err = ctx.Client.Get(ctx.Context, types.NamespacedName{Name: "foo", Namespace: "default"}, &corev1.ConfigMap{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("passes errors to error handlers", func() {
+		handler := &errorHandlerComponent{}
+		_, err := ctx.Run(handler, &secretComponent{err: errors.New("oops")})
+		Expect(err).To(MatchError("oops"))
+		Expect(handler.seen).To(MatchError("oops"))
+	})
+
+	It("doesn't pass transient errors to error handlers", func() {
+		handler := &errorHandlerComponent{}
+		_, err := ctx.Run(handler, &secretComponent{err: components.Transient(errors.New("oops"), 0)})
+		Expect(err).To(HaveOccurred())
+		Expect(handler.seen).To(BeNil())
+	})
+})
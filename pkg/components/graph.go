@@ -0,0 +1,179 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A single component in the dependency graph.
+type graphNode struct {
+	component Component
+	// Indexes of the nodes which must finish before this one can start.
+	after []int
+	// Names of outputs which must be ready for this component to run.
+	dependencies []string
+}
+
+// Where an output comes from.
+type outputProvider struct {
+	node   int
+	output Output
+}
+
+// The validated dependency graph for a reconciler's components.
+type componentGraph struct {
+	nodes   []graphNode
+	outputs map[string]outputProvider
+}
+
+func buildGraph(components []Component) (*componentGraph, error) {
+	graph := &componentGraph{
+		nodes:   make([]graphNode, len(components)),
+		outputs: map[string]outputProvider{},
+	}
+
+	// Collect all the outputs first so dependencies can point in either direction.
+	for i, component := range components {
+		graph.nodes[i].component = component
+		declarer, ok := component.(DependencyDeclarer)
+		if !ok {
+			continue
+		}
+		for _, output := range declarer.Outputs() {
+			existing, ok := graph.outputs[output.Name]
+			if ok {
+				return nil, errors.Errorf("components: output %s is provided by both %s and %s", output.Name, componentName(components[existing.node]), componentName(component))
+			}
+			graph.outputs[output.Name] = outputProvider{node: i, output: output}
+		}
+	}
+
+	for i, component := range components {
+		node := &graph.nodes[i]
+		declarer, ok := component.(DependencyDeclarer)
+		if !ok {
+			// Components which don't declare anything keep the old behavior of running after everything before them.
+			for j := 0; j < i; j++ {
+				node.after = append(node.after, j)
+			}
+			continue
+		}
+		for _, dep := range declarer.Dependencies() {
+			provider, ok := graph.outputs[dep]
+			if !ok {
+				return nil, errors.Errorf("components: %s depends on %s but no component provides it", componentName(component), dep)
+			}
+			node.after = append(node.after, provider.node)
+			node.dependencies = append(node.dependencies, dep)
+		}
+	}
+
+	err := graph.checkCycles()
+	if err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// Depth-first search for any loops in the graph.
+func (g *componentGraph) checkCycles() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.nodes))
+	path := []int{}
+
+	var visit func(int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			// Found a loop, report it starting from where it closed.
+			start := 0
+			for path[start] != i {
+				start++
+			}
+			names := []string{}
+			for _, j := range append(path[start:], i) {
+				names = append(names, componentName(g.nodes[j].component))
+			}
+			return errors.Errorf("components: dependency cycle %s", strings.Join(names, " -> "))
+		}
+		state[i] = visiting
+		path = append(path, i)
+		for _, j := range g.nodes[i].after {
+			err := visit(j)
+			if err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+
+	for i := range g.nodes {
+		err := visit(i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find every unfinished node whose predecessors have all finished.
+func (g *componentGraph) nextWave(finished []bool) []int {
+	wave := []int{}
+	for i, node := range g.nodes {
+		if finished[i] {
+			continue
+		}
+		ready := true
+		for _, j := range node.after {
+			if !finished[j] {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			wave = append(wave, i)
+		}
+	}
+	return wave
+}
+
+// Check if all the outputs a node depends on are ready. Outputs without a Ready check are only ready if
+// their component reconciled successfully in this pass.
+func (g *componentGraph) dependenciesReady(ctx *ComponentContext, i int, succeeded []bool) (bool, string) {
+	for _, dep := range g.nodes[i].dependencies {
+		provider := g.outputs[dep]
+		if provider.output.Ready != nil {
+			if !provider.output.Ready(ctx) {
+				return false, dep
+			}
+		} else if !succeeded[provider.node] {
+			return false, dep
+		}
+	}
+	return true, ""
+}
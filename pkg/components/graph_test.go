@@ -0,0 +1,128 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// A component which doesn't implement DependencyDeclarer.
+type legacyTestComponent struct {
+	name string
+}
+
+func (c *legacyTestComponent) String() string {
+	return c.name
+}
+
+func (c *legacyTestComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (c *legacyTestComponent) IsReconcilable(_ *ComponentContext) bool {
+	return true
+}
+
+func (c *legacyTestComponent) Reconcile(_ *ComponentContext) (Result, error) {
+	return Result{}, nil
+}
+
+type graphTestComponent struct {
+	legacyTestComponent
+	dependencies []string
+	outputs      []string
+}
+
+func declared(name string, dependencies []string, outputs []string) *graphTestComponent {
+	return &graphTestComponent{legacyTestComponent{name}, dependencies, outputs}
+}
+
+func (c *graphTestComponent) Dependencies() []string {
+	return c.dependencies
+}
+
+func (c *graphTestComponent) Outputs() []Output {
+	outputs := []Output{}
+	for _, name := range c.outputs {
+		outputs = append(outputs, Output{Name: name})
+	}
+	return outputs
+}
+
+var _ = Describe("buildGraph", func() {
+	It("orders components by their dependencies", func() {
+		graph, err := buildGraph([]Component{
+			declared("c", []string{"a", "b"}, nil),
+			declared("a", nil, []string{"a"}),
+			declared("b", []string{"a"}, []string{"b"}),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		finished := make([]bool, 3)
+		Expect(graph.nextWave(finished)).To(Equal([]int{1}))
+		finished[1] = true
+		Expect(graph.nextWave(finished)).To(Equal([]int{2}))
+		finished[2] = true
+		Expect(graph.nextWave(finished)).To(Equal([]int{0}))
+	})
+
+	It("runs independent components in the same wave", func() {
+		graph, err := buildGraph([]Component{
+			declared("a", nil, []string{"a"}),
+			declared("b", []string{"a"}, nil),
+			declared("c", []string{"a"}, nil),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(graph.nextWave([]bool{true, false, false})).To(Equal([]int{1, 2}))
+	})
+
+	It("runs undeclared components after everything before them", func() {
+		graph, err := buildGraph([]Component{
+			declared("a", nil, nil),
+			declared("b", nil, nil),
+			&legacyTestComponent{name: "c"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(graph.nextWave([]bool{false, false, false})).To(Equal([]int{0, 1}))
+		Expect(graph.nextWave([]bool{true, false, false})).To(Equal([]int{1}))
+		Expect(graph.nextWave([]bool{true, true, false})).To(Equal([]int{2}))
+	})
+
+	It("rejects duplicate outputs", func() {
+		_, err := buildGraph([]Component{
+			declared("a", nil, []string{"x"}),
+			declared("b", nil, []string{"x"}),
+		})
+		Expect(err).To(MatchError("components: output x is provided by both a and b"))
+	})
+
+	It("rejects unknown dependencies", func() {
+		_, err := buildGraph([]Component{
+			declared("a", []string{"x"}, nil),
+		})
+		Expect(err).To(MatchError("components: a depends on x but no component provides it"))
+	})
+
+	It("rejects cycles", func() {
+		_, err := buildGraph([]Component{
+			declared("a", []string{"b"}, []string{"a"}),
+			declared("b", []string{"a"}, []string{"b"}),
+		})
+		Expect(err).To(MatchError("components: dependency cycle a -> b -> a"))
+	})
+})
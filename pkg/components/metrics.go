@@ -0,0 +1,78 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	componentReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ridecell_operator_component_reconcile_duration_seconds",
+		Help:    "Time taken by a single component Reconcile call.",
+		Buckets: []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"controller", "component"})
+
+	componentErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_component_errors_total",
+		Help: "Number of component Reconcile calls which returned an error.",
+	}, []string{"controller", "component"})
+
+	componentRequeues = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_component_requeues_total",
+		Help: "Number of component Reconcile calls which asked to be requeued.",
+	}, []string{"controller", "component"})
+
+	componentSkips = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_component_skips_total",
+		Help: "Number of times a component was skipped because it was not reconcilable.",
+	}, []string{"controller", "component"})
+
+	componentErrorHandlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_component_error_handler_errors_total",
+		Help: "Number of ReconcileError calls which themselves returned an error.",
+	}, []string{"controller", "component"})
+
+	statusUpdateRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_status_update_retries_total",
+		Help: "Number of times a status update had to be retried after the fast path failed.",
+	}, []string{"controller"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		componentReconcileDuration,
+		componentErrors,
+		componentRequeues,
+		componentSkips,
+		componentErrorHandlerErrors,
+		statusUpdateRetries,
+	)
+}
+
+// Get a name for a component to use in logs and metrics. Components which are used more than once in
+// a controller can implement fmt.Stringer to tell the instances apart.
+func componentName(component Component) string {
+	stringer, ok := component.(fmt.Stringer)
+	if ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", component)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+// Set from the manager's --max-concurrent-reconciles flag. How many top objects each controller reconciles
+// at once.
+var MaxConcurrentReconciles = 1
+
+// Set from the manager's --controller-max-concurrent-reconciles flag. Overrides MaxConcurrentReconciles
+// for the controllers named as keys.
+var ControllerMaxConcurrentReconciles = map[string]int{}
+
+// Set from the manager's --namespaces flag. If not empty, top objects in any other namespace are ignored.
+var Namespaces []string
+
+func maxConcurrentReconciles(name string) int {
+	n, ok := ControllerMaxConcurrentReconciles[name]
+	if ok && n > 0 {
+		return n
+	}
+	return MaxConcurrentReconciles
+}
+
+func watchingNamespace(namespace string) bool {
+	if len(Namespaces) == 0 || namespace == "" {
+		return true
+	}
+	for _, ns := range Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
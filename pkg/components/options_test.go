@@ -0,0 +1,47 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("manager options", func() {
+	AfterEach(func() {
+		MaxConcurrentReconciles = 1
+		ControllerMaxConcurrentReconciles = map[string]int{}
+		Namespaces = nil
+	})
+
+	It("uses the per-controller concurrency if set", func() {
+		MaxConcurrentReconciles = 2
+		ControllerMaxConcurrentReconciles["foo-controller"] = 5
+		Expect(maxConcurrentReconciles("foo-controller")).To(Equal(5))
+		Expect(maxConcurrentReconciles("bar-controller")).To(Equal(2))
+	})
+
+	It("watches every namespace by default", func() {
+		Expect(watchingNamespace("default")).To(BeTrue())
+	})
+
+	It("only watches the listed namespaces", func() {
+		Namespaces = []string{"dev", "qa"}
+		Expect(watchingNamespace("qa")).To(BeTrue())
+		Expect(watchingNamespace("prod")).To(BeFalse())
+	})
+})
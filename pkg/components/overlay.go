@@ -0,0 +1,152 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+
+	"github.com/Ridecell/ridecell-operator/pkg/templates"
+)
+
+// Annotation on a top object naming a ConfigMap in the same namespace with template overrides.
+const TemplateOverridesAnnotation = "ridecell.io/template-overrides"
+
+// ConfigMap used for template overrides by every top object in a namespace without the annotation.
+const DefaultTemplateOverridesName = "ridecell-operator-template-overrides"
+
+// ConfigMap keys can't contain slashes, so this is used in their place. "helpers__deployment.yml.tpl"
+// overrides "helpers/deployment.yml.tpl".
+const overrideKeySeparator = "__"
+
+// An overlay filesystem built from a specific version of a ConfigMap.
+type cachedOverlay struct {
+	resourceVersion string
+	fs              http.FileSystem
+}
+
+type overlayCache struct {
+	sync.Mutex
+	overlays map[types.NamespacedName]cachedOverlay
+}
+
+// Find the name of the overrides ConfigMap for a top object, and if it was explicitly requested.
+func templateOverridesName(top metav1.Object) (string, bool) {
+	name, ok := top.GetAnnotations()[TemplateOverridesAnnotation]
+	if ok && name != "" {
+		return name, true
+	}
+	return DefaultTemplateOverridesName, false
+}
+
+// Work out the templates to use for a top object, layering any overrides on top of the embedded ones.
+func (cr *componentReconciler) templatesFor(ctx context.Context, top metav1.Object) (http.FileSystem, error) {
+	if cr.templates == nil {
+		return nil, nil
+	}
+	name, explicit := templateOverridesName(top)
+	key := types.NamespacedName{Name: name, Namespace: top.GetNamespace()}
+	configMap := &corev1.ConfigMap{}
+	err := cr.client.Get(ctx, key, configMap)
+	if err != nil {
+		if kerrors.IsNotFound(err) && !explicit {
+			// No overrides, which is the normal case.
+			return cr.templates, nil
+		}
+		return nil, errors.Wrapf(err, "error getting template overrides ConfigMap %s", key)
+	}
+
+	cr.overlays.Lock()
+	defer cr.overlays.Unlock()
+	if cr.overlays.overlays == nil {
+		cr.overlays.overlays = map[types.NamespacedName]cachedOverlay{}
+	}
+	cached, ok := cr.overlays.overlays[key]
+	if ok && cached.resourceVersion == configMap.ResourceVersion {
+		return cached.fs, nil
+	}
+	if ok {
+		// Don't leave parsed templates for the old version lying around.
+		templates.Invalidate(cached.fs)
+	}
+	fs := templates.NewOverlayFileSystem(cr.templates, overrideFiles(configMap))
+	cr.overlays.overlays[key] = cachedOverlay{resourceVersion: configMap.ResourceVersion, fs: fs}
+	return fs, nil
+}
+
+// Convert ConfigMap keys to template paths.
+func overrideFiles(configMap *corev1.ConfigMap) map[string][]byte {
+	files := map[string][]byte{}
+	for key, value := range configMap.Data {
+		files[strings.Replace(key, overrideKeySeparator, "/", -1)] = []byte(value)
+	}
+	for key, value := range configMap.BinaryData {
+		files[strings.Replace(key, overrideKeySeparator, "/", -1)] = value
+	}
+	return files
+}
+
+// Find the top objects using a ConfigMap for template overrides, so they are reconciled when it changes.
+func (cr *componentReconciler) watchTemplateOverrides(obj handler.MapObject) []reconcile.Request {
+	logger := log.Log.WithName(cr.name).WithValues("configmap", obj.Meta.GetName())
+	scheme := cr.manager.GetScheme()
+	gvk, err := apiutil.GVKForObject(cr.top, scheme)
+	if err != nil {
+		logger.Error(err, "unable to get kind of top object")
+		return nil
+	}
+	gvk.Kind += "List"
+	list, err := scheme.New(gvk)
+	if err != nil {
+		logger.Error(err, "unable to make list of top objects")
+		return nil
+	}
+	err = cr.client.List(context.TODO(), client.InNamespace(obj.Meta.GetNamespace()), list)
+	if err != nil {
+		logger.Error(err, "unable to list top objects")
+		return nil
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		logger.Error(err, "unable to extract top objects")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range items {
+		top := item.(metav1.Object)
+		name, _ := templateOverridesName(top)
+		if name == obj.Meta.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: top.GetName(), Namespace: top.GetNamespace()}})
+		}
+	}
+	return requests
+}
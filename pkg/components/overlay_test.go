@@ -0,0 +1,55 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("template overrides", func() {
+	It("uses the default ConfigMap without an annotation", func() {
+		top := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+		name, explicit := templateOverridesName(top)
+		Expect(name).To(Equal(DefaultTemplateOverridesName))
+		Expect(explicit).To(BeFalse())
+	})
+
+	It("uses the ConfigMap from the annotation", func() {
+		top := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{TemplateOverridesAnnotation: "custom"},
+		}}
+		name, explicit := templateOverridesName(top)
+		Expect(name).To(Equal("custom"))
+		Expect(explicit).To(BeTrue())
+	})
+
+	It("converts ConfigMap keys to template paths", func() {
+		configMap := &corev1.ConfigMap{
+			Data:       map[string]string{"helpers__deployment.yml.tpl": "a"},
+			BinaryData: map[string][]byte{"service.yml.tpl": []byte("b")},
+		}
+		files := overrideFiles(configMap)
+		Expect(files).To(HaveLen(2))
+		Expect(files).To(HaveKeyWithValue("helpers/deployment.yml.tpl", []byte("a")))
+		Expect(files).To(HaveKeyWithValue("service.yml.tpl", []byte("b")))
+	})
+})
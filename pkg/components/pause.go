@@ -0,0 +1,91 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"reflect"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Annotation to stop reconciling a top object, for hand-editing things during an incident.
+// Equivalent to setting spec.paused.
+const PausedAnnotation = "ridecell.io/paused"
+
+// Condition reported on every top object, true while it is paused.
+const ConditionPaused = "Paused"
+
+// Check if a top object is paused, either via the annotation or spec.paused. Returns why, for the condition.
+func isPaused(top runtime.Object) (bool, string) {
+	value, ok := top.(metav1.Object).GetAnnotations()[PausedAnnotation]
+	if ok {
+		paused, err := strconv.ParseBool(value)
+		if err == nil && paused {
+			return true, "paused by " + PausedAnnotation + " annotation"
+		}
+	}
+
+	// Not every type has a spec, so look for the field generically.
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(top)
+	if err != nil {
+		return false, ""
+	}
+	paused, found, err := unstructured.NestedBool(content, "spec", "paused")
+	if err == nil && found && paused {
+		return true, "paused by spec.paused"
+	}
+	return false, ""
+}
+
+// Check if the top object still has a true Paused condition from an earlier reconcile.
+func wasPaused(top runtime.Object) bool {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(top)
+	if err != nil {
+		return false
+	}
+	conditions, found, err := unstructured.NestedSlice(content, "status", "conditions")
+	if err != nil || !found {
+		return false
+	}
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if ok && condition["type"] == ConditionPaused && condition["status"] == string(corev1.ConditionTrue) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report that a top object is paused without running any components.
+func (cr *componentReconciler) reconcilePaused(ctx *ComponentContext, message string) (reconcile.Result, error) {
+	ctx.Logger.Info("paused, skipping reconcile")
+	cleanTop := ctx.Top.DeepCopyObject()
+	setPaused := SetCondition(ConditionPaused, corev1.ConditionTrue, "Paused", message)
+	setPaused(ctx.Top)
+	if !reflect.DeepEqual(ctx.Top.(Statuser).GetStatus(), cleanTop.(Statuser).GetStatus()) {
+		err := cr.modifyStatus(ctx, []StatusModifier{setPaused})
+		if err != nil {
+			return reconcile.Result{Requeue: true}, err
+		}
+	}
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("isPaused", func() {
+	It("is not paused by default", func() {
+		paused, _ := isPaused(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}})
+		Expect(paused).To(BeFalse())
+	})
+
+	It("is paused by the annotation", func() {
+		obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{PausedAnnotation: "true"},
+		}}
+		paused, message := isPaused(obj)
+		Expect(paused).To(BeTrue())
+		Expect(message).To(ContainSubstring("annotation"))
+	})
+
+	It("ignores a false annotation", func() {
+		obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{PausedAnnotation: "false"},
+		}}
+		paused, _ := isPaused(obj)
+		Expect(paused).To(BeFalse())
+	})
+
+	It("is paused by spec.paused", func() {
+		obj := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Paused: true},
+		}
+		paused, message := isPaused(obj)
+		Expect(paused).To(BeTrue())
+		Expect(message).To(ContainSubstring("spec.paused"))
+	})
+})
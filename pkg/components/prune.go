@@ -0,0 +1,209 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// What to do with objects which no component produced.
+type PruneMode string
+
+const (
+	// Delete orphaned objects.
+	PruneDelete PruneMode = "delete"
+	// Only log and record an Event for orphaned objects, for checking what would be deleted.
+	PruneReport PruneMode = "report"
+)
+
+// Annotation on the top object to override the prune mode for a single instance.
+const PruneModeAnnotation = "ridecell.io/prune"
+
+// Labels used to find objects which might need pruning.
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	partOfLabel    = "app.kubernetes.io/part-of"
+)
+
+type inventoryKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+// Tracks every object produced during a single reconcile so the pruner can find the rest.
+type inventory struct {
+	mu      sync.Mutex
+	objects map[inventoryKey]bool
+	// Set if any component was skipped, in which case we don't know everything that should exist.
+	incomplete bool
+}
+
+func newInventory() *inventory {
+	return &inventory{objects: map[inventoryKey]bool{}}
+}
+
+func (inv *inventory) add(key inventoryKey) {
+	if inv == nil {
+		return
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.objects[key] = true
+}
+
+func (inv *inventory) has(key inventoryKey) bool {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	return inv.objects[key]
+}
+
+func (inv *inventory) markIncomplete() {
+	if inv == nil {
+		return
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.incomplete = true
+}
+
+func (inv *inventory) isComplete() bool {
+	if inv == nil {
+		return false
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	return !inv.incomplete
+}
+
+func (ctx *ComponentContext) inventoryKey(obj runtime.Object) (inventoryKey, error) {
+	gvk, err := apiutil.GVKForObject(obj, ctx.Scheme)
+	if err != nil {
+		return inventoryKey{}, err
+	}
+	objMeta := obj.(metav1.Object)
+	return inventoryKey{gvk: gvk, namespace: objMeta.GetNamespace(), name: objMeta.GetName()}, nil
+}
+
+// Record that an object is still wanted so it won't be pruned. CreateOrUpdate does this automatically,
+// components which manage objects some other way should call it themselves.
+func (ctx *ComponentContext) KeepObject(obj runtime.Object) error {
+	key, err := ctx.inventoryKey(obj)
+	if err != nil {
+		return err
+	}
+	ctx.inventory.add(key)
+	return nil
+}
+
+type prunerComponent struct {
+	managedBy string
+	listTypes []runtime.Object
+	mode      PruneMode
+}
+
+// Make a component which deletes objects labeled as managed-by and part-of the top object which no
+// component produced. It must be the last non-status component, and only runs after a pass where every
+// other component was reconciled. listTypes are the List types to check, like &appsv1.DeploymentList{}.
+func NewPruner(managedBy string, listTypes []runtime.Object, mode PruneMode) *prunerComponent {
+	return &prunerComponent{managedBy: managedBy, listTypes: listTypes, mode: mode}
+}
+
+func (_ *prunerComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *prunerComponent) IsReconcilable(ctx *ComponentContext) bool {
+	// Objects from skipped components would look orphaned, so only prune after a full pass.
+	return ctx.inventory.isComplete()
+}
+
+func (comp *prunerComponent) Reconcile(ctx *ComponentContext) (Result, error) {
+	top := ctx.Top.(metav1.Object)
+	mode := comp.mode
+	override, ok := top.GetAnnotations()[PruneModeAnnotation]
+	if ok {
+		mode = PruneMode(override)
+	}
+	if mode != PruneDelete && mode != PruneReport {
+		return Result{}, errors.Errorf("pruner: unknown prune mode %#v", mode)
+	}
+
+	for _, listType := range comp.listTypes {
+		list := listType.DeepCopyObject()
+		listOptions := client.InNamespace(top.GetNamespace()).MatchingLabels(map[string]string{
+			managedByLabel: comp.managedBy,
+			partOfLabel:    top.GetName(),
+		})
+		err := ctx.List(ctx.Context, listOptions, list)
+		if err != nil {
+			return Result{}, errors.Wrapf(err, "pruner: error listing %T", listType)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return Result{}, errors.Wrapf(err, "pruner: error extracting items from %T", listType)
+		}
+
+		for _, item := range items {
+			err := comp.pruneObject(ctx, item, mode)
+			if err != nil {
+				return Result{}, err
+			}
+		}
+	}
+	return Result{}, nil
+}
+
+func (comp *prunerComponent) pruneObject(ctx *ComponentContext, obj runtime.Object, mode PruneMode) error {
+	objMeta := obj.(metav1.Object)
+	// Labels are easy to copy around, only touch things we actually own.
+	owner := metav1.GetControllerOf(objMeta)
+	if owner == nil || owner.UID != ctx.Top.(metav1.Object).GetUID() {
+		return nil
+	}
+	key, err := ctx.inventoryKey(obj)
+	if err != nil {
+		return errors.Wrapf(err, "pruner: error getting kind of %s/%s", objMeta.GetNamespace(), objMeta.GetName())
+	}
+	if ctx.inventory.has(key) {
+		return nil
+	}
+
+	description := fmt.Sprintf("%s %s/%s", key.gvk.Kind, key.namespace, key.name)
+	if mode == PruneReport {
+		ctx.Logger.Info("found orphaned object", "object", description)
+		ctx.Eventf(corev1.EventTypeWarning, "OrphanedObject", "%s is no longer rendered by any component", description)
+		return nil
+	}
+	ctx.Logger.Info("pruning orphaned object", "object", description)
+	err = ctx.Delete(ctx.Context, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil {
+		return errors.Wrapf(err, "pruner: error deleting %s", description)
+	}
+	ctx.Eventf(corev1.EventTypeNormal, "ObjectPruned", "Deleted %s which is no longer rendered by any component", description)
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("pruner Component", func() {
+	var top *corev1.ConfigMap
+	var ctx *ComponentContext
+
+	// Make a Service which looks like it came from a template.
+	makeService := func(name string, owned bool) *corev1.Service {
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					managedByLabel: "test-operator",
+					partOfLabel:    "foo",
+				},
+			},
+		}
+		if owned {
+			isController := true
+			service.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "foo", UID: top.UID, Controller: &isController}}
+		}
+		return service
+	}
+
+	serviceExists := func(name string) bool {
+		err := ctx.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, &corev1.Service{})
+		if kerrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).ToNot(HaveOccurred())
+		return true
+	}
+
+	BeforeEach(func() {
+		top = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "1234"}}
+		ctx = NewTestContext(top, nil)
+		ctx.Client = fake.NewFakeClient(top, makeService("foo-web", true), makeService("foo-old", true), makeService("foo-manual", false))
+		Expect(ctx.KeepObject(makeService("foo-web", true))).To(Succeed())
+	})
+
+	It("deletes owned objects which weren't produced", func() {
+		comp := NewPruner("test-operator", []runtime.Object{&corev1.ServiceList{}}, PruneDelete)
+		Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+		_, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serviceExists("foo-web")).To(BeTrue())
+		Expect(serviceExists("foo-old")).To(BeFalse())
+		Expect(serviceExists("foo-manual")).To(BeTrue())
+	})
+
+	It("only reports in report mode", func() {
+		comp := NewPruner("test-operator", []runtime.Object{&corev1.ServiceList{}}, PruneReport)
+		_, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serviceExists("foo-old")).To(BeTrue())
+	})
+
+	It("lets the annotation override the mode", func() {
+		top.Annotations = map[string]string{PruneModeAnnotation: "report"}
+		comp := NewPruner("test-operator", []runtime.Object{&corev1.ServiceList{}}, PruneDelete)
+		_, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serviceExists("foo-old")).To(BeTrue())
+	})
+
+	It("doesn't run if a component was skipped", func() {
+		ctx.inventory.markIncomplete()
+		comp := NewPruner("test-operator", []runtime.Object{&corev1.ServiceList{}}, PruneDelete)
+		Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+	})
+
+	It("leaves the objects of a skipped component alone", func() {
+		comps := []Component{
+			&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"old"}, skip: true},
+			NewPruner("test-operator", []runtime.Object{&corev1.ServiceList{}}, PruneDelete),
+		}
+		graph, err := buildGraph(comps)
+		Expect(err).ToNot(HaveOccurred())
+		cr := &componentReconciler{name: "prune-test", components: comps, graph: graph, backoff: newComponentBackoff()}
+
+		_, err = cr.reconcileComponents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serviceExists("foo-old")).To(BeTrue())
+	})
+
+	It("leaves the objects of a failed component alone", func() {
+		comps := []Component{
+			&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"old"}, err: errors.New("failed")},
+			NewPruner("test-operator", []runtime.Object{&corev1.ServiceList{}}, PruneDelete),
+		}
+		graph, err := buildGraph(comps)
+		Expect(err).ToNot(HaveOccurred())
+		cr := &componentReconciler{name: "prune-test", components: comps, graph: graph, backoff: newComponentBackoff()}
+
+		_, err = cr.reconcileComponents(ctx)
+		Expect(err).To(MatchError("failed"))
+		Expect(serviceExists("foo-old")).To(BeTrue())
+	})
+})
@@ -21,8 +21,11 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -45,10 +48,29 @@ func NewReconciler(name string, mgr manager.Manager, top runtime.Object, templat
 		templates:  templates,
 		components: components,
 		manager:    mgr,
+		recorder:   mgr.GetRecorder(name),
+		backoff:    newComponentBackoff(),
+	}
+
+	// Work out what order things need to run in.
+	graph, err := buildGraph(components)
+	if err != nil {
+		logger.Error(err, "invalid component dependencies")
+		return nil, err
+	}
+	cr.graph = graph
+
+	// Only use a finalizer if some component has cleanup to do.
+	for _, comp := range components {
+		_, ok := comp.(Finalizer)
+		if ok {
+			cr.finalizer = fmt.Sprintf("%s.finalizers.ridecell.io", name)
+			break
+		}
 	}
 
 	// Create the controller.
-	c, err := controller.New(name, mgr, controller.Options{Reconciler: cr})
+	c, err := controller.New(name, mgr, controller.Options{Reconciler: cr, MaxConcurrentReconciles: maxConcurrentReconciles(name)})
 	if err != nil {
 		logger.Error(err, "unable to create controller")
 		return nil, err
@@ -61,6 +83,16 @@ func NewReconciler(name string, mgr manager.Manager, top runtime.Object, templat
 		return nil, err
 	}
 
+	// Watch for changes in template overrides.
+	if cr.templates != nil {
+		err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(cr.watchTemplateOverrides),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create template overrides watch")
+		}
+	}
+
 	// Watch for changes in other objects.
 	watchedTypes := map[reflect.Type]bool{}
 	for _, comp := range cr.components {
@@ -97,9 +129,7 @@ func NewReconciler(name string, mgr manager.Manager, top runtime.Object, templat
 	return cr, nil
 }
 
-func (cr *componentReconciler) newContext(request reconcile.Request) (*ComponentContext, error) {
-	reqCtx := context.TODO()
-
+func (cr *componentReconciler) newContext(reqCtx context.Context, request reconcile.Request) (*ComponentContext, error) {
 	// Fetch the current value of the top object for this reconcile.
 	top := cr.top.DeepCopyObject()
 	err := cr.client.Get(reqCtx, request.NamespacedName, top)
@@ -107,24 +137,45 @@ func (cr *componentReconciler) newContext(request reconcile.Request) (*Component
 		return nil, err
 	}
 
+	templates, err := cr.templatesFor(reqCtx, top.(metav1.Object))
+	if err != nil {
+		return nil, err
+	}
+
 	ctx := &ComponentContext{
-		templates: cr.templates,
+		templates: templates,
 		Logger:    log.Log.WithName(cr.name).WithValues("request", request.NamespacedName),
 		Context:   reqCtx,
 		Top:       top,
+		Recorder:  cr.recorder,
+		inventory: newInventory(),
 	}
 	err = cr.manager.SetFields(ctx)
 	if err != nil {
 		ctx.Logger.Error(err, "error calling manager.SetFields")
 		return nil, err
 	}
+	if isDryRun(top) {
+		ctx.Logger = ctx.Logger.WithValues("dryRun", true)
+		ctx.dryRun = &dryRunClient{Client: ctx.Client, scheme: ctx.Scheme}
+		ctx.Client = ctx.dryRun
+	}
 	return ctx, nil
 }
 
 func (cr *componentReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	// The cache only narrows to a single namespace, so with several the filtering happens here.
+	if !watchingNamespace(request.Namespace) {
+		return reconcile.Result{}, nil
+	}
+
+	// Everything this reconcile does, including talking to things outside Kubernetes, has to finish in time
+	// so one hung endpoint can't block a worker forever.
+	reqCtx, cancel := context.WithTimeout(context.Background(), ReconcileTimeout)
+	defer cancel()
 
 	// Build a reconciler context to pass around.
-	ctx, err := cr.newContext(request)
+	ctx, err := cr.newContext(reqCtx, request)
 	if err != nil {
 		if kerrors.IsNotFound(err) {
 			// Top object not found, likely already deleted.
@@ -133,6 +184,39 @@ func (cr *componentReconciler) Reconcile(request reconcile.Request) (reconcile.R
 		// Some other fetch error, try again on the next tick.
 		return reconcile.Result{Requeue: true}, err
 	}
+	return cr.reconcileContext(ctx)
+}
+
+// Everything Reconcile does once the context is built.
+func (cr *componentReconciler) reconcileContext(ctx *ComponentContext) (reconcile.Result, error) {
+	// Deletion goes first, even while paused, otherwise a paused object would be stuck terminating until
+	// someone unpaused it.
+	instance := ctx.Top.(metav1.Object)
+	if cr.finalizer != "" && instance.GetDeletionTimestamp() != nil {
+		if ctx.IsDryRun() {
+			// Finalizers mostly clean up things outside of Kubernetes, which a dry run can't intercept.
+			ctx.Logger.Info("dry run, not finalizing")
+			return reconcile.Result{}, nil
+		}
+		return cr.finalize(ctx)
+	}
+
+	// Don't touch anything else while paused, not even adding the finalizer. Components never run, so there
+	// are no writes and no notifications.
+	paused, pausedMessage := isPaused(ctx.Top)
+	if paused {
+		return cr.reconcilePaused(ctx, pausedMessage)
+	}
+
+	// Make sure our finalizer is in place, if we have one.
+	if cr.finalizer != "" && !hasFinalizer(instance, cr.finalizer) {
+		addFinalizer(instance, cr.finalizer)
+		err := ctx.Update(ctx.Context, ctx.Top)
+		if err != nil {
+			return reconcile.Result{Requeue: true}, errors.Wrapf(err, "error adding finalizer %s", cr.finalizer)
+		}
+	}
+
 	ctx.Logger.Info("reconciling")
 
 	// Make a clean copy of the top object to diff against later. This is used for
@@ -140,12 +224,36 @@ func (cr *componentReconciler) Reconcile(request reconcile.Request) (reconcile.R
 	cleanTop := ctx.Top.DeepCopyObject()
 
 	// Reconcile all the components.
-	// start := time.Now()
 	result, err := cr.reconcileComponents(ctx)
-	// fmt.Printf("$$$ Reconcile took %s\n", time.Since(start))
-	if err != nil {
-		// fmt.Printf("@@@@ Reconcile error %v\n", err)
-		ctx.Top.(Statuser).SetErrorStatus(err.Error())
+	if wasPaused(ctx.Top) {
+		resumed := SetCondition(ConditionPaused, corev1.ConditionFalse, "Resumed", "")
+		resumed(ctx.Top)
+		result.statusModifiers = append(result.statusModifiers, resumed)
+	}
+	if ctx.IsDryRun() {
+		if err != nil {
+			ctx.Logger.Error(err, "error during dry run")
+		}
+		cr.reportDryRun(ctx, cleanTop, result)
+	} else {
+		if err != nil {
+			if IsTransient(err) {
+				// Should clear up by itself, so don't flag the object as broken.
+				ctx.Logger.Info("transient error, retrying", "error", err.Error(), "requeueAfter", result.result.RequeueAfter)
+			} else {
+				ctx.Top.(Statuser).SetErrorStatus(err.Error())
+			}
+		}
+		// Clear out any report from a previous dry run.
+		_, ok := ctx.Top.(DryRunReporter)
+		if ok {
+			clearDryRun := func(obj runtime.Object) error {
+				obj.(DryRunReporter).SetDryRunChanges(nil)
+				return nil
+			}
+			clearDryRun(ctx.Top)
+			result.statusModifiers = append(result.statusModifiers, clearDryRun)
+		}
 	}
 
 	// Check if an update to the status subresource is required.
@@ -181,9 +289,7 @@ func (r *reconcilerResults) mergeResult(componentResult Result, component Compon
 	if componentResult.Requeue {
 		r.result.Requeue = true
 	}
-	if componentResult.RequeueAfter != 0 && (r.result.RequeueAfter == 0 || r.result.RequeueAfter > componentResult.RequeueAfter) {
-		r.result.RequeueAfter = componentResult.RequeueAfter
-	}
+	r.requeueAfter(componentResult.RequeueAfter)
 	if componentResult.StatusModifier != nil {
 		r.statusModifiers = append(r.statusModifiers, componentResult.StatusModifier)
 		statusErr := componentResult.StatusModifier(r.ctx.Top)
@@ -200,45 +306,188 @@ func (r *reconcilerResults) mergeResult(componentResult Result, component Compon
 	return err
 }
 
+// Requeue after the given delay, unless something already wants to requeue sooner.
+func (r *reconcilerResults) requeueAfter(delay time.Duration) {
+	if delay != 0 && (r.result.RequeueAfter == 0 || r.result.RequeueAfter > delay) {
+		r.result.RequeueAfter = delay
+	}
+}
+
+// The outcome of a single component Reconcile, collected so concurrent runs can be merged in order.
+type componentOutcome struct {
+	node   int
+	result Result
+	err    error
+}
+
 func (cr *componentReconciler) reconcileComponents(ctx *ComponentContext) (*reconcilerResults, error) {
+	res := &reconcilerResults{ctx: ctx}
+	nodes := cr.graph.nodes
+	finished := make([]bool, len(nodes))
+	succeeded := make([]bool, len(nodes))
+	for {
+		wave := cr.graph.nextWave(finished)
+		if len(wave) == 0 {
+			// All done.
+			return res, nil
+		}
+
+		// Work out which components in this wave can run. Readiness is checked against the current status,
+		// so it sees the modifiers from every earlier wave.
+		runnable := []int{}
+		for _, i := range wave {
+			component := nodes[i].component
+			name := componentName(component)
+			depsReady, dep := cr.graph.dependenciesReady(ctx, i, succeeded)
+			if depsReady && component.IsReconcilable(ctx) {
+				ctx.Logger.V(1).Info("available to reconcile!!", "component", name)
+				runnable = append(runnable, i)
+				continue
+			}
+			if !depsReady {
+				ctx.Logger.V(1).Info("dependency not ready", "component", name, "dependency", dep)
+			} else {
+				ctx.Logger.V(1).Info("not reconcilable", "component", name)
+			}
+			finished[i] = true
+			ctx.inventory.markIncomplete()
+			componentSkips.WithLabelValues(cr.name, name).Inc()
+		}
+
+		// Everything in a wave is independent, so run them all at once.
+		outcomes := make([]componentOutcome, len(runnable))
+		var wg sync.WaitGroup
+		for n, i := range runnable {
+			wg.Add(1)
+			go func(n, i int) {
+				defer wg.Done()
+				outcomes[n] = cr.reconcileComponent(ctx, i)
+			}(n, i)
+		}
+		wg.Wait()
+
+		// Merge in list order so status modifiers are applied deterministically. This should be done before
+		// checking errors because sometimes we want to requeue immediately on error.
+		var firstErr error
+		for _, outcome := range outcomes {
+			component := nodes[outcome.node].component
+			finished[outcome.node] = true
+			if IsPermanent(outcome.err) {
+				// Trying again won't help, so drop any requeue the failing component asked for and wait for the
+				// object to change. Requeues from other components still stand.
+				outcome.result.Requeue = false
+				outcome.result.RequeueAfter = 0
+			}
+			err := res.mergeResult(outcome.result, component, outcome.err)
+			if err != nil {
+				componentErrors.WithLabelValues(cr.name, componentName(component)).Inc()
+				res.requeueAfter(cr.backoff.failed(ctx, component, err))
+				// Report the worst error, a transient one shouldn't hide a real failure.
+				if firstErr == nil || (IsTransient(firstErr) && !IsTransient(err)) {
+					firstErr = err
+				}
+				continue
+			}
+			cr.backoff.succeeded(ctx, component)
+			succeeded[outcome.node] = true
+		}
+		if firstErr != nil {
+			// Transient errors should clear up by themselves, so there is no need to tell anyone.
+			if !IsTransient(firstErr) {
+				cr.dispatchError(ctx, res, firstErr)
+			}
+			return res, firstErr
+		}
+	}
+}
+
+func (cr *componentReconciler) reconcileComponent(ctx *ComponentContext, i int) componentOutcome {
+	component := cr.graph.nodes[i].component
+	name := componentName(component)
+	compCtx, cancel := ctx.forComponent(component)
+	defer cancel()
+	start := time.Now()
+	result, err := component.Reconcile(compCtx)
+	componentReconcileDuration.WithLabelValues(cr.name, name).Observe(time.Since(start).Seconds())
+	if result.Requeue || result.RequeueAfter != 0 {
+		componentRequeues.WithLabelValues(cr.name, name).Inc()
+	}
+	return componentOutcome{node: i, result: result, err: err}
+}
+
+// Let every reconcilable ErrorHandler know about a failure.
+func (cr *componentReconciler) dispatchError(ctx *ComponentContext, res *reconcilerResults, err error) {
 	instance := ctx.Top.(metav1.Object)
-	ready := []Component{}
-	for _, component := range cr.components {
-		ctx.Logger.V(1).Info("available to reconcile??", "component", fmt.Sprintf("%T", component))
-		if component.IsReconcilable(ctx) {
-			ctx.Logger.V(1).Info("available to reconcile!!", "component", fmt.Sprintf("%T", component))
-			ready = append(ready, component)
+	for _, node := range cr.graph.nodes {
+		errReconciler, ok := node.component.(ErrorHandler)
+		if !ok || !node.component.IsReconcilable(ctx) {
+			// Not an error handler, push on.
+			continue
+		}
+		compCtx, cancel := ctx.forComponent(node.component)
+		innerRes, errorErr := errReconciler.ReconcileError(compCtx, err)
+		cancel()
+		res.mergeResult(innerRes, node.component, nil)
+		if errorErr != nil {
+			// Can't really do much more than log it and count it, sigh.
+			componentErrorHandlerErrors.WithLabelValues(cr.name, componentName(node.component)).Inc()
+			ctx.Logger.Error(errorErr, "error running error handler", "instance", instance, "component", node.component)
 		}
 	}
+}
+
+// Run cleanup for a top object which is being deleted and then release our finalizer.
+func (cr *componentReconciler) finalize(ctx *ComponentContext) (reconcile.Result, error) {
+	instance := ctx.Top.(metav1.Object)
+	if !hasFinalizer(instance, cr.finalizer) {
+		// Already cleaned up, just waiting for the object to go away.
+		return reconcile.Result{}, nil
+	}
+	ctx.Logger.Info("finalizing")
+
+	cleanTop := ctx.Top.DeepCopyObject()
+	result, err := cr.finalizeComponents(ctx)
+	if err != nil {
+		ctx.Top.(Statuser).SetErrorStatus(err.Error())
+		if !reflect.DeepEqual(ctx.Top.(Statuser).GetStatus(), cleanTop.(Statuser).GetStatus()) {
+			statusErr := cr.modifyStatus(ctx, result.statusModifiers)
+			if statusErr != nil {
+				ctx.Logger.Error(statusErr, "error updating status during finalize")
+			}
+		}
+		return result.result, err
+	}
+	if result.result.Requeue || result.result.RequeueAfter != 0 {
+		// Some component isn't done cleaning up yet, keep the finalizer in place.
+		return result.result, nil
+	}
+
+	// Everything is cleaned up, let the object go.
+	removeFinalizer(instance, cr.finalizer)
+	err = ctx.Update(ctx.Context, ctx.Top)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return reconcile.Result{Requeue: true}, errors.Wrapf(err, "error removing finalizer %s", cr.finalizer)
+	}
+	return reconcile.Result{}, nil
+}
+
+func (cr *componentReconciler) finalizeComponents(ctx *ComponentContext) (*reconcilerResults, error) {
 	res := &reconcilerResults{ctx: ctx}
-	ctxLogger := ctx.Logger
-	for _, component := range ready {
-		// fmt.Printf("### Reconciling %#v\n", component)
-		// start := time.Now()
-		ctx.Logger = ctxLogger.WithValues("component", fmt.Sprintf("%T", component))
-		innerRes, err := component.Reconcile(ctx)
-		// fmt.Printf("### Done reconciling %#v, took %s\n", component, time.Since(start))
-		// Update result. This should be checked before the err!=nil because sometimes
-		// we want to requeue immediately on error.
+	// Run in reverse order so things are torn down in the opposite order they were built.
+	for i := len(cr.components) - 1; i >= 0; i-- {
+		component := cr.components[i]
+		finalizer, ok := component.(Finalizer)
+		if !ok {
+			continue
+		}
+		compCtx, cancel := ctx.forComponent(component)
+		innerRes, err := finalizer.Finalize(compCtx)
+		cancel()
 		err = res.mergeResult(innerRes, component, err)
 		if err != nil {
-			for _, errComponent := range ready {
-				errReconciler, ok := errComponent.(ErrorHandler)
-				if !ok {
-					// Not an error handler, push on.
-					continue
-				}
-				innerRes, errorErr := errReconciler.ReconcileError(ctx, err)
-				res.mergeResult(innerRes, errComponent, nil)
-				if errorErr != nil {
-					// Can't really do much more than log it, sigh. Some day this should set a prometheus metric.
-					ctx.Logger.Error(errorErr, "error running error handler", "instance", instance, "component", errComponent)
-				}
-			}
 			return res, err
 		}
 	}
-	ctx.Logger = ctxLogger
 	return res, nil
 }
 
@@ -252,6 +501,7 @@ func (cr *componentReconciler) modifyStatus(ctx *ComponentContext, statusModifie
 
 	// Something went wrong so we have to do a re-get an apply of the modifiers.
 	for tries := 0; tries < 5; tries++ {
+		statusUpdateRetries.WithLabelValues(cr.name).Inc()
 		err = cr.updateStatus(ctx, ctx.Top, func(instance runtime.Object) error {
 			for _, mod := range statusModifiers {
 				err := mod(instance)
@@ -0,0 +1,192 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+// A component which returns whatever it is told to.
+type reconcilerTestComponent struct {
+	legacyTestComponent
+	skip   bool
+	result Result
+	err    error
+}
+
+func (c *reconcilerTestComponent) IsReconcilable(_ *ComponentContext) bool {
+	return !c.skip
+}
+
+func (c *reconcilerTestComponent) Reconcile(_ *ComponentContext) (Result, error) {
+	return c.result, c.err
+}
+
+// A component with cleanup to do.
+type finalizerTestComponent struct {
+	reconcilerTestComponent
+	finalized bool
+}
+
+func (c *finalizerTestComponent) Finalize(_ *ComponentContext) (Result, error) {
+	c.finalized = true
+	return Result{}, nil
+}
+
+// Read the number of observations for one component from the reconcile duration histogram.
+func reconcileDurationCount(controller, component string) uint64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).ToNot(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != "ridecell_operator_component_reconcile_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			if labels["controller"] == controller && labels["component"] == component {
+				return metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+var _ = Describe("reconciler metrics", func() {
+	var cr *componentReconciler
+	var ctx *ComponentContext
+
+	newReconciler := func(comps ...Component) {
+		graph, err := buildGraph(comps)
+		Expect(err).ToNot(HaveOccurred())
+		cr = &componentReconciler{name: "metrics-test", components: comps, graph: graph, backoff: newComponentBackoff()}
+	}
+
+	BeforeEach(func() {
+		ctx = &ComponentContext{
+			Client:    fake.NewFakeClient(),
+			Logger:    log.Log,
+			Context:   context.TODO(),
+			Top:       &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+			inventory: newInventory(),
+		}
+	})
+
+	It("times each component reconcile", func() {
+		newReconciler(&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"timed"}})
+		before := reconcileDurationCount("metrics-test", "timed")
+
+		_, err := cr.reconcileComponents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconcileDurationCount("metrics-test", "timed")).To(Equal(before + 1))
+	})
+
+	It("counts errors", func() {
+		newReconciler(&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"failing"}, err: errors.New("failed")})
+		before := testutil.ToFloat64(componentErrors.WithLabelValues("metrics-test", "failing"))
+
+		_, err := cr.reconcileComponents(ctx)
+		Expect(err).To(MatchError("failed"))
+		Expect(testutil.ToFloat64(componentErrors.WithLabelValues("metrics-test", "failing"))).To(Equal(before + 1))
+		Expect(reconcileDurationCount("metrics-test", "failing")).ToNot(BeZero())
+	})
+
+	It("counts requeues", func() {
+		newReconciler(&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"requeueing"}, result: Result{RequeueAfter: time.Minute}})
+		before := testutil.ToFloat64(componentRequeues.WithLabelValues("metrics-test", "requeueing"))
+
+		res, err := cr.reconcileComponents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.result.RequeueAfter).To(Equal(time.Minute))
+		Expect(testutil.ToFloat64(componentRequeues.WithLabelValues("metrics-test", "requeueing"))).To(Equal(before + 1))
+	})
+
+	It("counts skips", func() {
+		newReconciler(&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"skipped"}, skip: true})
+		before := testutil.ToFloat64(componentSkips.WithLabelValues("metrics-test", "skipped"))
+		durationBefore := reconcileDurationCount("metrics-test", "skipped")
+
+		_, err := cr.reconcileComponents(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testutil.ToFloat64(componentSkips.WithLabelValues("metrics-test", "skipped"))).To(Equal(before + 1))
+		Expect(reconcileDurationCount("metrics-test", "skipped")).To(Equal(durationBefore))
+	})
+
+	It("counts status update retries", func() {
+		newReconciler()
+		before := testutil.ToFloat64(statusUpdateRetries.WithLabelValues("metrics-test"))
+
+		// The top object was never created, so the fast path fails and the retry finds it gone.
+		Expect(cr.modifyStatus(ctx, nil)).To(Succeed())
+		Expect(testutil.ToFloat64(statusUpdateRetries.WithLabelValues("metrics-test"))).To(Equal(before + 1))
+	})
+})
+
+var _ = Describe("reconciler", func() {
+	It("keeps other requeues after a permanent error", func() {
+		comps := []Component{
+			&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"waiting"}, result: Result{RequeueAfter: time.Minute}},
+			&reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"broken"}, result: Result{Requeue: true, RequeueAfter: time.Second}, err: Permanent(errors.New("bad spec"))},
+		}
+		graph, err := buildGraph(comps)
+		Expect(err).ToNot(HaveOccurred())
+		cr := &componentReconciler{name: "test", components: comps, graph: graph, backoff: newComponentBackoff()}
+		ctx := NewTestContext(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}, nil)
+
+		res, err := cr.reconcileComponents(ctx)
+		Expect(IsPermanent(err)).To(BeTrue())
+		Expect(res.result.Requeue).To(BeFalse())
+		Expect(res.result.RequeueAfter).To(Equal(time.Minute))
+	})
+
+	It("finalizes a paused object which is being deleted", func() {
+		now := metav1.Now()
+		top := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:              "foo",
+			Namespace:         "default",
+			Annotations:       map[string]string{PausedAnnotation: "true"},
+			DeletionTimestamp: &now,
+			Finalizers:        []string{"test.finalizers.ridecell.io"},
+		}}
+		ctx := NewTestContext(top, nil)
+		comp := &finalizerTestComponent{reconcilerTestComponent: reconcilerTestComponent{legacyTestComponent: legacyTestComponent{"cleanup"}}}
+		graph, err := buildGraph([]Component{comp})
+		Expect(err).ToNot(HaveOccurred())
+		cr := &componentReconciler{name: "test", components: []Component{comp}, graph: graph, backoff: newComponentBackoff(), finalizer: "test.finalizers.ridecell.io"}
+
+		_, err = cr.reconcileContext(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(comp.finalized).To(BeTrue())
+		fetched := &corev1.ConfigMap{}
+		Expect(ctx.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, fetched)).To(Succeed())
+		Expect(fetched.Finalizers).To(BeEmpty())
+	})
+})
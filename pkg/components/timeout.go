@@ -0,0 +1,54 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"time"
+)
+
+// Set from the manager's --reconcile-timeout flag. The whole reconcile of one top object, including
+// status updates, must finish within this.
+var ReconcileTimeout = 5 * time.Minute
+
+// Set from the manager's --component-timeout flag. The default deadline for each component's Reconcile,
+// ReconcileError, or Finalize call, it can never be longer than what is left of ReconcileTimeout.
+var ComponentTimeout = 1 * time.Minute
+
+// An optional interface for Components which need a different deadline than ComponentTimeout, like ones
+// which wait on slow external APIs.
+type TimeoutDeclarer interface {
+	Timeout() time.Duration
+}
+
+func componentTimeout(component Component) time.Duration {
+	declarer, ok := component.(TimeoutDeclarer)
+	if ok && declarer.Timeout() > 0 {
+		return declarer.Timeout()
+	}
+	return ComponentTimeout
+}
+
+// Make a shallow copy of the context for a single component, with its own logger and deadline. The copy
+// is safe to use concurrently with other components. Call the returned function once the component is done.
+func (ctx *ComponentContext) forComponent(component Component) (*ComponentContext, context.CancelFunc) {
+	compCtx := *ctx
+	compCtx.Logger = ctx.Logger.WithValues("component", componentName(component))
+	var cancel context.CancelFunc
+	compCtx.Context, cancel = context.WithTimeout(ctx.Context, componentTimeout(component))
+	return &compCtx, cancel
+}
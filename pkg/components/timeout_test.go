@@ -0,0 +1,75 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+type slowTestComponent struct {
+	legacyTestComponent
+}
+
+func (_ *slowTestComponent) Timeout() time.Duration {
+	return time.Hour
+}
+
+var _ = Describe("component deadlines", func() {
+	var ctx *ComponentContext
+
+	BeforeEach(func() {
+		ctx = &ComponentContext{Context: context.Background(), Logger: log.Log.WithName("test")}
+	})
+
+	It("uses ComponentTimeout by default", func() {
+		compCtx, cancel := ctx.forComponent(&legacyTestComponent{"a"})
+		defer cancel()
+		deadline, ok := compCtx.Context.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(time.Until(deadline)).To(BeNumerically("<=", ComponentTimeout))
+		_, ok = ctx.Context.Deadline()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("lets a component declare its own timeout", func() {
+		compCtx, cancel := ctx.forComponent(&slowTestComponent{legacyTestComponent{"a"}})
+		defer cancel()
+		deadline, _ := compCtx.Context.Deadline()
+		Expect(time.Until(deadline)).To(BeNumerically(">", ComponentTimeout))
+	})
+
+	It("never outlives the reconcile deadline", func() {
+		reqCtx, reqCancel := context.WithTimeout(context.Background(), time.Second)
+		defer reqCancel()
+		ctx.Context = reqCtx
+		compCtx, cancel := ctx.forComponent(&slowTestComponent{legacyTestComponent{"a"}})
+		defer cancel()
+		deadline, _ := compCtx.Context.Deadline()
+		Expect(time.Until(deadline)).To(BeNumerically("<=", time.Second))
+	})
+
+	It("cancels the component context when done", func() {
+		compCtx, cancel := ctx.forComponent(&legacyTestComponent{"a"})
+		cancel()
+		Expect(compCtx.Context.Err()).To(Equal(context.Canceled))
+	})
+})
@@ -23,7 +23,9 @@ import (
 
 	"github.com/go-logr/logr"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -39,6 +41,14 @@ type componentReconciler struct {
 	components []Component
 	client     client.Client
 	manager    manager.Manager
+	recorder   record.EventRecorder
+	graph      *componentGraph
+	// The finalizer to add to top objects, empty if no components need one.
+	finalizer string
+	// Template overlays built from override ConfigMaps.
+	overlays overlayCache
+	// Retry delays for components which have failed.
+	backoff *componentBackoff
 }
 
 // A ComponentContext is the state for a single reconcile request to the controller.
@@ -46,9 +56,16 @@ type ComponentContext struct {
 	client.Client
 	templates http.FileSystem
 	Logger    logr.Logger
-	Context   context.Context // This should probably go away
-	Top       runtime.Object
-	Scheme    *runtime.Scheme
+	// Cancelled when the reconcile or component deadline passes, pass it to every outside call.
+	Context context.Context
+	Top     runtime.Object
+	Scheme  *runtime.Scheme
+	// Records Events against the Top object, use Eventf rather than calling this directly.
+	Recorder record.EventRecorder
+	// Set if this is a dry run, the Client is wrapped so writes are only recorded.
+	dryRun *dryRunClient
+	// Everything produced so far in this reconcile, used by the pruner.
+	inventory *inventory
 }
 
 // A function which modifies component status.
@@ -71,11 +88,38 @@ type Component interface {
 	Reconcile(*ComponentContext) (Result, error)
 }
 
-// An optional interface for Components which want to receive errors.
+// An optional interface for Components which want to receive errors. Errors marked with Transient are not
+// passed on, since they should clear up by themselves.
 type ErrorHandler interface {
 	ReconcileError(*ComponentContext, error) (Result, error)
 }
 
+// An optional interface for Components which declare their dependencies rather than relying on their
+// position in the component list. A component only runs once every output it depends on is ready, and
+// components with no path between them in the graph may be reconciled concurrently. Components which
+// don't implement this run after everything listed before them, as they always have.
+type DependencyDeclarer interface {
+	// Names of outputs from other components which must be ready before this one runs.
+	Dependencies() []string
+	// Outputs this component provides to others.
+	Outputs() []Output
+}
+
+// A named output of a component which others can depend on.
+type Output struct {
+	Name string
+	// Check if the output is ready. If nil, the output is ready once its component has reconciled
+	// without error during the current pass.
+	Ready func(*ComponentContext) bool
+}
+
+// An optional interface for Components which need to release external resources when the top object is
+// deleted. If any component implements this, the reconciler will add a finalizer to the top object and
+// only remove it once every Finalize call has succeeded without asking for a requeue.
+type Finalizer interface {
+	Finalize(*ComponentContext) (Result, error)
+}
+
 // An optional interface for Components which want to use EnqueueRequestsFromMapFunc instead of watching owned objects.
 type MapFuncWatcher interface {
 	WatchMap(handler.MapObject) []reconcile.Request
@@ -89,4 +133,7 @@ type Statuser interface {
 	GetStatus() Status
 	SetStatus(Status)
 	SetErrorStatus(string)
+	// Set one condition in the status, leaving the others alone. Components should do this from a
+	// StatusModifier so it is replayed if the status update has to be retried.
+	SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string)
 }
@@ -19,6 +19,7 @@ package components
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -74,3 +75,34 @@ func GetOwnerName(obj runtime.Object, kind string) (*string, error) {
 	}
 	return &ss.List()[0], nil
 }
+
+// Make a StatusModifier which sets a single condition on the top object.
+func SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) StatusModifier {
+	return func(obj runtime.Object) error {
+		obj.(Statuser).SetCondition(conditionType, status, reason, message)
+		return nil
+	}
+}
+
+func hasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func addFinalizer(obj metav1.Object, finalizer string) {
+	obj.SetFinalizers(append(obj.GetFinalizers(), finalizer))
+}
+
+func removeFinalizer(obj metav1.Object, finalizer string) {
+	finalizers := []string{}
+	for _, f := range obj.GetFinalizers() {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	obj.SetFinalizers(finalizers)
+}
@@ -21,6 +21,6 @@ import (
 )
 
 func init() {
-	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
-	AddToManagerFuncs = append(AddToManagerFuncs, iamuser.Add)
+	// AddToManagerFuncs maps controller names to functions to create them and add them to a manager.
+	AddToManagerFuncs["iamuser-controller"] = iamuser.Add
 }
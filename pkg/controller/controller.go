@@ -17,15 +17,29 @@ limitations under the License.
 package controller
 
 import (
+	"github.com/pkg/errors"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
-// AddToManagerFuncs is a list of functions to add all Controllers to the Manager
-var AddToManagerFuncs []func(manager.Manager) error
+// AddToManagerFuncs is a map of controller names to functions to add them to the Manager. The names match
+// the ones each controller passes to components.NewReconciler.
+var AddToManagerFuncs = map[string]func(manager.Manager) error{}
+
+// Set from the manager's --disable-controllers flag. Controllers named here are not added.
+var Disabled = map[string]bool{}
 
 // AddToManager adds all Controllers to the Manager
 func AddToManager(m manager.Manager) error {
-	for _, f := range AddToManagerFuncs {
+	for name := range Disabled {
+		_, ok := AddToManagerFuncs[name]
+		if !ok {
+			return errors.Errorf("unknown controller %s", name)
+		}
+	}
+	for name, f := range AddToManagerFuncs {
+		if Disabled[name] {
+			continue
+		}
 		if err := f(m); err != nil {
 			return err
 		}
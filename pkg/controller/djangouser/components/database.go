@@ -29,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/dbpool"
@@ -72,6 +73,12 @@ func (comp *databaseComponent) Reconcile(ctx *components.ComponentContext) (comp
 		return components.Result{Requeue: true}, err
 	}
 
+	if ctx.IsDryRun() {
+		// The upserts are idempotent, but there is no cheap way to tell if they would change anything.
+		ctx.RecordDryRun("update", "postgres:DjangoUser", instance.Spec.Email, "")
+		return components.Result{}, nil
+	}
+
 	// Big ass SQL.
 	query := `
 INSERT INTO auth_user (username, password, first_name, last_name, email, is_active, is_staff, is_superuser, date_joined)
@@ -87,7 +94,7 @@ INSERT INTO auth_user (username, password, first_name, last_name, email, is_acti
   RETURNING id;`
 
 	// Create the auth_user.
-	row := db.QueryRow(query, instance.Spec.Email, hashedPassword, instance.Spec.FirstName, instance.Spec.LastName, instance.Spec.Active, instance.Spec.Staff, instance.Spec.Superuser)
+	row := db.QueryRowContext(ctx.Context, query, instance.Spec.Email, hashedPassword, instance.Spec.FirstName, instance.Spec.LastName, instance.Spec.Active, instance.Spec.Staff, instance.Spec.Superuser)
 	var id int
 	err = row.Scan(&id)
 	if err != nil {
@@ -103,7 +110,7 @@ INSERT INTO common_userprofile (user_id, is_jumio_verified, created_at, updated_
   RETURNING id;`
 
 	// Create the common_userprofile.
-	row = db.QueryRow(query, id)
+	row = db.QueryRowContext(ctx.Context, query, id)
 	var profileId int
 	err = row.Scan(&profileId)
 	if err != nil {
@@ -121,7 +128,7 @@ INSERT INTO common_staff (user_profile_id, is_active, manager, dispatcher)
 `
 
 	// Create the common_staff.
-	_, err = db.Exec(query, profileId, instance.Spec.Active, instance.Spec.Manager, instance.Spec.Dispatcher)
+	_, err = db.ExecContext(ctx.Context, query, profileId, instance.Spec.Active, instance.Spec.Manager, instance.Spec.Dispatcher)
 	if err != nil {
 		return components.Result{}, errors.Wrap(err, "database: Error running common_staff query")
 	}
@@ -131,6 +138,7 @@ INSERT INTO common_staff (user_profile_id, is_active, manager, dispatcher)
 		instance := obj.(*summonv1beta1.DjangoUser)
 		instance.Status.Status = summonv1beta1.StatusReady
 		instance.Status.Message = fmt.Sprintf("User %v created", id)
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "UserCreated", instance.Status.Message)
 		return nil
 	}}, nil
 }
@@ -33,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 	djangousercomponents "github.com/Ridecell/ridecell-operator/pkg/controller/djangouser/components"
 	"github.com/Ridecell/ridecell-operator/pkg/dbpool"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
@@ -140,6 +141,32 @@ var _ = Describe("DjangoUser Database Component", func() {
 		Expect(instance.Status.Message).To(Equal("User 1 created"))
 	})
 
+	It("doesn't touch the database in a dry run", func() {
+		dbSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "summon.foo-database.credentials", Namespace: "default"},
+			Data: map[string][]byte{
+				"password": []byte("secretdbpass"),
+			},
+		}
+		userSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-credentials", Namespace: "default"},
+			Data: map[string][]byte{
+				"password": []byte("djangopass"),
+			},
+		}
+		instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+		ctx = components.NewTestContext(instance, nil)
+		ctx.Client = fake.NewFakeClient(dbSecret, userSecret)
+
+		comp := djangousercomponents.NewDatabase()
+		Expect(comp).To(ReconcileContext(ctx))
+		changes := ctx.DryRunChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("update"))
+		Expect(changes[0].Kind).To(Equal("postgres:DjangoUser"))
+		Expect(changes[0].Name).To(Equal("foo@example.com"))
+	})
+
 	It("obeys the status flags", func() {
 		instance.Spec.Active = true
 		instance.Spec.Staff = true
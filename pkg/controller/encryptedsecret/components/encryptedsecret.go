@@ -29,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -71,14 +72,15 @@ func (comp *EncryptedSecretComponent) Reconcile(ctx *components.ComponentContext
 	}
 
 	for k, v := range instance.Data {
-		if v == "" {
-			return components.Result{}, errors.Errorf("encryptedsecret: secret[%s] does not have a value", k)
+		err := secretsv1beta1.ValidateEncryptedValue(v)
+		if err != nil {
+			return components.Result{}, components.Permanent(errors.Wrapf(err, "encryptedsecret: secret[%s]", k))
 		}
 		decodedValue, err := base64.StdEncoding.DecodeString(v)
 		if err != nil {
 			return components.Result{}, errors.Wrapf(err, "encryptedsecret: failed to base64 decode secret")
 		}
-		decryptedValue, err := comp.kmsAPI.Decrypt(&kms.DecryptInput{
+		decryptedValue, err := comp.kmsAPI.DecryptWithContext(ctx.Context, &kms.DecryptInput{
 			CiphertextBlob: decodedValue,
 			EncryptionContext: map[string]*string{
 				"RidecellOperator": aws.String("true"),
@@ -116,6 +118,7 @@ func (comp *EncryptedSecretComponent) Reconcile(ctx *components.ComponentContext
 		instance := obj.(*secretsv1beta1.EncryptedSecret)
 		instance.Status.Status = secretsv1beta1.StatusReady
 		instance.Status.Message = "Secret Created"
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "SecretCreated", instance.Status.Message)
 		return nil
 	}}, nil
 }
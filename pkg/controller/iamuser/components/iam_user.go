@@ -35,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -67,14 +68,19 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 
 	// Try to get our user, if it can't be found create it
 	var user *iam.User
-	getUserOutput, err := comp.iamAPI.GetUser(&iam.GetUserInput{UserName: aws.String(instance.Spec.UserName)})
+	getUserOutput, err := comp.iamAPI.GetUserWithContext(ctx.Context, &iam.GetUserInput{UserName: aws.String(instance.Spec.UserName)})
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			if aerr.Code() != iam.ErrCodeNoSuchEntityException {
 				return components.Result{}, errors.Wrapf(aerr, "iam_user: failed to get user")
 			}
 			// If user does not exist create it
-			createUserOutput, err := comp.iamAPI.CreateUser(&iam.CreateUserInput{
+			if ctx.IsDryRun() {
+				// Nothing else can be checked until the user exists.
+				ctx.RecordDryRun("create", "aws:iam:User", instance.Spec.UserName, "")
+				return components.Result{}, nil
+			}
+			createUserOutput, err := comp.iamAPI.CreateUserWithContext(ctx.Context, &iam.CreateUserInput{
 				UserName:            aws.String(instance.Spec.UserName),
 				PermissionsBoundary: aws.String(instance.Spec.PermissionsBoundaryArn),
 			})
@@ -89,7 +95,7 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 	}
 
 	// Get inline user policy names
-	listUserPoliciesOutput, err := comp.iamAPI.ListUserPolicies(&iam.ListUserPoliciesInput{UserName: user.UserName})
+	listUserPoliciesOutput, err := comp.iamAPI.ListUserPoliciesWithContext(ctx.Context, &iam.ListUserPoliciesInput{UserName: user.UserName})
 	if err != nil {
 		return components.Result{}, errors.Wrapf(err, "iam_user: failed to list inline user policies")
 	}
@@ -97,7 +103,7 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 	userPolicies := map[string]string{}
 	for _, userPolicyName := range listUserPoliciesOutput.PolicyNames {
 		// Not actually in use at the moment.
-		getUserPolicy, err := comp.iamAPI.GetUserPolicy(&iam.GetUserPolicyInput{
+		getUserPolicy, err := comp.iamAPI.GetUserPolicyWithContext(ctx.Context, &iam.GetUserPolicyInput{
 			PolicyName: userPolicyName,
 			UserName:   user.UserName,
 		})
@@ -116,7 +122,11 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 	for userPolicyName, _ := range userPolicies {
 		_, ok := instance.Spec.InlinePolicies[userPolicyName]
 		if !ok {
-			_, err = comp.iamAPI.DeleteUserPolicy(&iam.DeleteUserPolicyInput{
+			if ctx.IsDryRun() {
+				ctx.RecordDryRun("delete", "aws:iam:UserPolicy", userPolicyName, "")
+				continue
+			}
+			_, err = comp.iamAPI.DeleteUserPolicyWithContext(ctx.Context, &iam.DeleteUserPolicyInput{
 				PolicyName: aws.String(userPolicyName),
 				UserName:   user.UserName,
 			})
@@ -129,10 +139,9 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 	// Update our user policies
 	for policyName, policyJSON := range instance.Spec.InlinePolicies {
 		// Check for malformed JSON before we even try sending it.
-		var specPolicyObj interface{}
-		err := json.Unmarshal([]byte(policyJSON), &specPolicyObj)
+		specPolicyObj, err := awsv1beta1.DecodePolicy(policyJSON)
 		if err != nil {
-			return components.Result{}, errors.Wrapf(err, "iam_user: user policy from spec %s has invalid JSON", policyName)
+			return components.Result{}, components.Permanent(errors.Wrapf(err, "iam_user: user policy from spec %s has invalid JSON", policyName))
 		}
 
 		// If a policy with the same name was returned compare it to our spec
@@ -149,7 +158,11 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 			}
 		}
 
-		_, err = comp.iamAPI.PutUserPolicy(&iam.PutUserPolicyInput{
+		if ctx.IsDryRun() {
+			ctx.RecordDryRun("update", "aws:iam:UserPolicy", policyName, policyJSON)
+			continue
+		}
+		_, err = comp.iamAPI.PutUserPolicyWithContext(ctx.Context, &iam.PutUserPolicyInput{
 			PolicyDocument: aws.String(policyJSON),
 			PolicyName:     aws.String(policyName),
 			UserName:       user.UserName,
@@ -172,15 +185,17 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 	_, ok0 := fetchAccessKey.Data["AWS_ACCESS_KEY_ID"]
 	_, ok1 := fetchAccessKey.Data["AWS_SECRET_ACCESS_KEY"]
 
-	if !ok0 || !ok1 {
+	if (!ok0 || !ok1) && ctx.IsDryRun() {
+		ctx.RecordDryRun("create", "aws:iam:AccessKey", instance.Spec.UserName, "")
+	} else if !ok0 || !ok1 {
 		// Find any access keys related attached to this user
-		existingAccessKeys, err := comp.iamAPI.ListAccessKeys(&iam.ListAccessKeysInput{UserName: user.UserName})
+		existingAccessKeys, err := comp.iamAPI.ListAccessKeysWithContext(ctx.Context, &iam.ListAccessKeysInput{UserName: user.UserName})
 		if err != nil {
 			return components.Result{}, errors.Wrapf(err, "iam_user: failed to list access keys")
 		}
 		// Delete access keys if they exist
 		for _, accessKeyMeta := range existingAccessKeys.AccessKeyMetadata {
-			_, err := comp.iamAPI.DeleteAccessKey(&iam.DeleteAccessKeyInput{
+			_, err := comp.iamAPI.DeleteAccessKeyWithContext(ctx.Context, &iam.DeleteAccessKeyInput{
 				AccessKeyId: accessKeyMeta.AccessKeyId,
 				UserName:    user.UserName,
 			})
@@ -190,7 +205,7 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 		}
 
 		// Make new access key and put it in a secret
-		createAccessKeyOutput, err := comp.iamAPI.CreateAccessKey(&iam.CreateAccessKeyInput{UserName: user.UserName})
+		createAccessKeyOutput, err := comp.iamAPI.CreateAccessKeyWithContext(ctx.Context, &iam.CreateAccessKeyInput{UserName: user.UserName})
 		if err != nil {
 			return components.Result{}, errors.Wrapf(err, "iam_user: failed to create new access key")
 		}
@@ -214,12 +229,78 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 		if err != nil {
 			return components.Result{}, errors.Wrapf(err, "iam_user: failed to create or update secret")
 		}
+		ctx.Eventf(corev1.EventTypeNormal, "AccessKeyRegenerated", "Created new access key %s", aws.StringValue(createAccessKeyOutput.AccessKey.AccessKeyId))
 	}
 
 	return components.Result{StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*awsv1beta1.IAMUser)
 		instance.Status.Status = awsv1beta1.StatusReady
 		instance.Status.Message = "User exists and has secret"
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "UserReady", instance.Status.Message)
 		return nil
 	}}, nil
 }
+
+func (comp *iamUserComponent) Finalize(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*awsv1beta1.IAMUser)
+	userName := aws.String(instance.Spec.UserName)
+
+	_, err := comp.iamAPI.GetUserWithContext(ctx.Context, &iam.GetUserInput{UserName: userName})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			// Already gone, nothing to do.
+			return components.Result{}, nil
+		}
+		return components.Result{}, errors.Wrapf(err, "iam_user: failed to get user")
+	}
+
+	// A user can't be deleted while it still has policies or access keys attached.
+	listUserPoliciesOutput, err := comp.iamAPI.ListUserPoliciesWithContext(ctx.Context, &iam.ListUserPoliciesInput{UserName: userName})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "iam_user: failed to list inline user policies")
+	}
+	for _, userPolicyName := range listUserPoliciesOutput.PolicyNames {
+		_, err = comp.iamAPI.DeleteUserPolicyWithContext(ctx.Context, &iam.DeleteUserPolicyInput{
+			PolicyName: userPolicyName,
+			UserName:   userName,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_user: failed to delete user policy %s", aws.StringValue(userPolicyName))
+		}
+	}
+
+	existingAccessKeys, err := comp.iamAPI.ListAccessKeysWithContext(ctx.Context, &iam.ListAccessKeysInput{UserName: userName})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "iam_user: failed to list access keys")
+	}
+	for _, accessKeyMeta := range existingAccessKeys.AccessKeyMetadata {
+		_, err = comp.iamAPI.DeleteAccessKeyWithContext(ctx.Context, &iam.DeleteAccessKeyInput{
+			AccessKeyId: accessKeyMeta.AccessKeyId,
+			UserName:    userName,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_user: failed to delete access keys")
+		}
+	}
+
+	// Managed policies attached by hand (or by anything else) also block the delete.
+	attachedPoliciesOutput, err := comp.iamAPI.ListAttachedUserPoliciesWithContext(ctx.Context, &iam.ListAttachedUserPoliciesInput{UserName: userName})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "iam_user: failed to list attached user policies")
+	}
+	for _, attachedPolicy := range attachedPoliciesOutput.AttachedPolicies {
+		_, err = comp.iamAPI.DetachUserPolicyWithContext(ctx.Context, &iam.DetachUserPolicyInput{
+			PolicyArn: attachedPolicy.PolicyArn,
+			UserName:  userName,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_user: failed to detach user policy %s", aws.StringValue(attachedPolicy.PolicyArn))
+		}
+	}
+
+	_, err = comp.iamAPI.DeleteUserWithContext(ctx.Context, &iam.DeleteUserInput{UserName: userName})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "iam_user: failed to delete user")
+	}
+	return components.Result{}, nil
+}
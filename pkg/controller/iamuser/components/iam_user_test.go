@@ -26,12 +26,14 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 	iamusercomponents "github.com/Ridecell/ridecell-operator/pkg/controller/iamuser/components"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -43,6 +45,11 @@ type mockIAMClient struct {
 	mockhasUserPolicies bool
 	mockExtraUserPolicy bool
 	mockHasAccessKey    bool
+	mockAttachedPolicy  bool
+
+	deletedUser bool
+	// Every call which changes something in AWS.
+	writes []string
 }
 
 var _ = Describe("iam_user aws Component", func() {
@@ -132,11 +139,66 @@ var _ = Describe("iam_user aws Component", func() {
 		_, err := comp.Reconcile(ctx)
 		Expect(err).To(MatchError("iam_user: user policy from spec test has invalid JSON: invalid character 'n' looking for beginning of object key string"))
 	})
+
+	It("only records changes in a dry run", func() {
+		mockIAM.mockUserExists = true
+		mockIAM.mockExtraUserPolicy = true
+		instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+		ctx = components.NewTestContext(instance, nil)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.writes).To(BeEmpty())
+		changes := ctx.DryRunChanges()
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].Operation).To(Equal("delete"))
+		Expect(changes[0].Kind).To(Equal("aws:iam:UserPolicy"))
+		Expect(changes[0].Name).To(Equal("mock1"))
+		Expect(changes[1].Operation).To(Equal("create"))
+		Expect(changes[1].Kind).To(Equal("aws:iam:AccessKey"))
+
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "test-user.aws-credentials", Namespace: "default"}, &corev1.Secret{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("doesn't create the user in a dry run", func() {
+		instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+		ctx = components.NewTestContext(instance, nil)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.writes).To(BeEmpty())
+		changes := ctx.DryRunChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("create"))
+		Expect(changes[0].Kind).To(Equal("aws:iam:User"))
+	})
+
+	It("deletes the user and everything attached to it", func() {
+		mockIAM.mockUserExists = true
+		mockIAM.mockExtraUserPolicy = true
+		mockIAM.mockHasAccessKey = true
+
+		Expect(comp).To(FinalizeContext(ctx))
+		Expect(mockIAM.deletedUser).To(BeTrue())
+	})
+
+	It("detaches managed policies before deleting the user", func() {
+		mockIAM.mockUserExists = true
+		mockIAM.mockAttachedPolicy = true
+
+		Expect(comp).To(FinalizeContext(ctx))
+		Expect(mockIAM.mockAttachedPolicy).To(BeFalse())
+		Expect(mockIAM.deletedUser).To(BeTrue())
+	})
+
+	It("finalizes cleanly if the user is already gone", func() {
+		Expect(comp).To(FinalizeContext(ctx))
+		Expect(mockIAM.deletedUser).To(BeFalse())
+	})
 })
 
 // Mock aws functions below
 
-func (m *mockIAMClient) GetUser(input *iam.GetUserInput) (*iam.GetUserOutput, error) {
+func (m *mockIAMClient) GetUserWithContext(_ aws.Context, input *iam.GetUserInput, _ ...request.Option) (*iam.GetUserOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.GetUserOutput{}, errors.New("awsmock_getuser: given username does not match spec")
 	}
@@ -146,14 +208,15 @@ func (m *mockIAMClient) GetUser(input *iam.GetUserInput) (*iam.GetUserOutput, er
 	return &iam.GetUserOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_getuser: user does not exist", errors.New(""))
 }
 
-func (m *mockIAMClient) CreateUser(input *iam.CreateUserInput) (*iam.CreateUserOutput, error) {
+func (m *mockIAMClient) CreateUserWithContext(_ aws.Context, input *iam.CreateUserInput, _ ...request.Option) (*iam.CreateUserOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.CreateUserOutput{}, errors.New("awsmock_createuser: given username does not match spec")
 	}
+	m.writes = append(m.writes, "CreateUser")
 	return &iam.CreateUserOutput{User: &iam.User{UserName: input.UserName}}, nil
 }
 
-func (m *mockIAMClient) ListUserPolicies(input *iam.ListUserPoliciesInput) (*iam.ListUserPoliciesOutput, error) {
+func (m *mockIAMClient) ListUserPoliciesWithContext(_ aws.Context, input *iam.ListUserPoliciesInput, _ ...request.Option) (*iam.ListUserPoliciesOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.ListUserPoliciesOutput{}, errors.New("awsmock_listuserpolicies: given username does not match spec")
 	}
@@ -175,7 +238,7 @@ func (m *mockIAMClient) ListUserPolicies(input *iam.ListUserPoliciesInput) (*iam
 	return &iam.ListUserPoliciesOutput{}, nil
 }
 
-func (m *mockIAMClient) GetUserPolicy(input *iam.GetUserPolicyInput) (*iam.GetUserPolicyOutput, error) {
+func (m *mockIAMClient) GetUserPolicyWithContext(_ aws.Context, input *iam.GetUserPolicyInput, _ ...request.Option) (*iam.GetUserPolicyOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.GetUserPolicyOutput{}, errors.New("awsmock_getuserpolicy: given username does not match spec")
 	}
@@ -193,17 +256,19 @@ func (m *mockIAMClient) GetUserPolicy(input *iam.GetUserPolicyInput) (*iam.GetUs
 	return &iam.GetUserPolicyOutput{}, nil
 }
 
-func (m *mockIAMClient) PutUserPolicy(input *iam.PutUserPolicyInput) (*iam.PutUserPolicyOutput, error) {
+func (m *mockIAMClient) PutUserPolicyWithContext(_ aws.Context, input *iam.PutUserPolicyInput, _ ...request.Option) (*iam.PutUserPolicyOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.PutUserPolicyOutput{}, errors.New("awsmock_putuserpolicy: username did not match spec")
 	}
+	m.writes = append(m.writes, "PutUserPolicy")
 	return &iam.PutUserPolicyOutput{}, nil
 }
 
-func (m *mockIAMClient) DeleteUserPolicy(input *iam.DeleteUserPolicyInput) (*iam.DeleteUserPolicyOutput, error) {
+func (m *mockIAMClient) DeleteUserPolicyWithContext(_ aws.Context, input *iam.DeleteUserPolicyInput, _ ...request.Option) (*iam.DeleteUserPolicyOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.DeleteUserPolicyOutput{}, errors.New("awsmock_deleteuserpolicy: username did not match spec")
 	}
+	m.writes = append(m.writes, "DeleteUserPolicy")
 	_, ok := instance.Spec.InlinePolicies[aws.StringValue(input.PolicyName)]
 	if !ok {
 		return &iam.DeleteUserPolicyOutput{}, nil
@@ -211,10 +276,11 @@ func (m *mockIAMClient) DeleteUserPolicy(input *iam.DeleteUserPolicyInput) (*iam
 	return &iam.DeleteUserPolicyOutput{}, errors.New("awsmock_deleteuserpolicy: policy shouldn't be getting deleted")
 }
 
-func (m *mockIAMClient) CreateAccessKey(input *iam.CreateAccessKeyInput) (*iam.CreateAccessKeyOutput, error) {
+func (m *mockIAMClient) CreateAccessKeyWithContext(_ aws.Context, input *iam.CreateAccessKeyInput, _ ...request.Option) (*iam.CreateAccessKeyOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.CreateAccessKeyOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_createaccesskey: username did not match spec", errors.New(""))
 	}
+	m.writes = append(m.writes, "CreateAccessKey")
 	curTime := time.Now()
 	return &iam.CreateAccessKeyOutput{
 		AccessKey: &iam.AccessKey{
@@ -227,17 +293,18 @@ func (m *mockIAMClient) CreateAccessKey(input *iam.CreateAccessKeyInput) (*iam.C
 	}, nil
 }
 
-func (m *mockIAMClient) DeleteAccessKey(input *iam.DeleteAccessKeyInput) (*iam.DeleteAccessKeyOutput, error) {
+func (m *mockIAMClient) DeleteAccessKeyWithContext(_ aws.Context, input *iam.DeleteAccessKeyInput, _ ...request.Option) (*iam.DeleteAccessKeyOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.DeleteAccessKeyOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_deleteaccesskey: username did not match spec", errors.New(""))
 	}
+	m.writes = append(m.writes, "DeleteAccessKey")
 	if aws.StringValue(input.AccessKeyId) == "123456789" {
 		return &iam.DeleteAccessKeyOutput{}, nil
 	}
 	return &iam.DeleteAccessKeyOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_deleteaccesskey: access key does not exist", errors.New(""))
 }
 
-func (m *mockIAMClient) ListAccessKeys(input *iam.ListAccessKeysInput) (*iam.ListAccessKeysOutput, error) {
+func (m *mockIAMClient) ListAccessKeysWithContext(_ aws.Context, input *iam.ListAccessKeysInput, _ ...request.Option) (*iam.ListAccessKeysOutput, error) {
 	if aws.StringValue(input.UserName) != instance.Spec.UserName {
 		return &iam.ListAccessKeysOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_listaccesskeys: username did not match spec", errors.New(""))
 	}
@@ -246,3 +313,35 @@ func (m *mockIAMClient) ListAccessKeys(input *iam.ListAccessKeysInput) (*iam.Lis
 	}
 	return &iam.ListAccessKeysOutput{}, nil
 }
+
+func (m *mockIAMClient) DeleteUserWithContext(_ aws.Context, input *iam.DeleteUserInput, _ ...request.Option) (*iam.DeleteUserOutput, error) {
+	if aws.StringValue(input.UserName) != instance.Spec.UserName {
+		return &iam.DeleteUserOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_deleteuser: username did not match spec", errors.New(""))
+	}
+	if m.mockAttachedPolicy {
+		return &iam.DeleteUserOutput{}, awserr.New(iam.ErrCodeDeleteConflictException, "awsmock_deleteuser: user still has attached policies", errors.New(""))
+	}
+	m.deletedUser = true
+	return &iam.DeleteUserOutput{}, nil
+}
+
+func (m *mockIAMClient) ListAttachedUserPoliciesWithContext(_ aws.Context, input *iam.ListAttachedUserPoliciesInput, _ ...request.Option) (*iam.ListAttachedUserPoliciesOutput, error) {
+	if aws.StringValue(input.UserName) != instance.Spec.UserName {
+		return &iam.ListAttachedUserPoliciesOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_listattacheduserpolicies: username did not match spec", errors.New(""))
+	}
+	if m.mockAttachedPolicy {
+		return &iam.ListAttachedUserPoliciesOutput{AttachedPolicies: []*iam.AttachedPolicy{{PolicyArn: aws.String("arn:aws:iam::aws:policy/ReadOnlyAccess"), PolicyName: aws.String("ReadOnlyAccess")}}}, nil
+	}
+	return &iam.ListAttachedUserPoliciesOutput{}, nil
+}
+
+func (m *mockIAMClient) DetachUserPolicyWithContext(_ aws.Context, input *iam.DetachUserPolicyInput, _ ...request.Option) (*iam.DetachUserPolicyOutput, error) {
+	if aws.StringValue(input.UserName) != instance.Spec.UserName {
+		return &iam.DetachUserPolicyOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_detachuserpolicy: username did not match spec", errors.New(""))
+	}
+	if !m.mockAttachedPolicy || aws.StringValue(input.PolicyArn) != "arn:aws:iam::aws:policy/ReadOnlyAccess" {
+		return &iam.DetachUserPolicyOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_detachuserpolicy: policy is not attached", errors.New(""))
+	}
+	m.mockAttachedPolicy = false
+	return &iam.DetachUserPolicyOutput{}, nil
+}
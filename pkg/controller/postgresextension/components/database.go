@@ -21,9 +21,12 @@ import (
 
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/components/postgres"
 )
@@ -51,28 +54,35 @@ func (comp *databaseComponent) Reconcile(ctx *components.ComponentContext) (comp
 		return components.Result{Requeue: true}, err
 	}
 
+	if ctx.IsDryRun() {
+		// Both statements are no-ops if the extension is already up to date, but there is no cheap way to
+		// tell ahead of time, so always report them.
+		ctx.RecordDryRun("update", "postgres:Extension", instance.Spec.ExtensionName, instance.Spec.Version)
+		return components.Result{}, nil
+	}
+
 	// Two codepaths because both queries look very different depending on if we have a version or not.
 	if instance.Spec.Version == "" {
 		// Create the extension if it doesn't exist already.
-		_, err = db.Exec(fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", pq.QuoteIdentifier(instance.Spec.ExtensionName)))
+		_, err = db.ExecContext(ctx.Context, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", pq.QuoteIdentifier(instance.Spec.ExtensionName)))
 		if err != nil {
 			return components.Result{}, errors.Wrap(err, "database: Error running CREATE EXTENSION")
 		}
 
 		// Upgrade the extension if it did exist.
-		_, err = db.Exec(fmt.Sprintf("ALTER EXTENSION %s UPDATE", pq.QuoteIdentifier(instance.Spec.ExtensionName)))
+		_, err = db.ExecContext(ctx.Context, fmt.Sprintf("ALTER EXTENSION %s UPDATE", pq.QuoteIdentifier(instance.Spec.ExtensionName)))
 		if err != nil {
 			return components.Result{}, errors.Wrap(err, "database: Error running ALTER EXTENSION")
 		}
 	} else {
 		// Create the extension if it doesn't exist already.
-		_, err = db.Exec(fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH VERSION %s", pq.QuoteIdentifier(instance.Spec.ExtensionName), pq.QuoteIdentifier(instance.Spec.Version)))
+		_, err = db.ExecContext(ctx.Context, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH VERSION %s", pq.QuoteIdentifier(instance.Spec.ExtensionName), pq.QuoteIdentifier(instance.Spec.Version)))
 		if err != nil {
 			return components.Result{}, errors.Wrap(err, "database: Error running CREATE EXTENSION")
 		}
 
 		// Upgrade the extension if it did exist.
-		_, err = db.Exec(fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s", pq.QuoteIdentifier(instance.Spec.ExtensionName), pq.QuoteIdentifier(instance.Spec.Version)))
+		_, err = db.ExecContext(ctx.Context, fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s", pq.QuoteIdentifier(instance.Spec.ExtensionName), pq.QuoteIdentifier(instance.Spec.Version)))
 		if err != nil {
 			return components.Result{}, errors.Wrap(err, "database: Error running ALTER EXTENSION")
 		}
@@ -83,6 +93,34 @@ func (comp *databaseComponent) Reconcile(ctx *components.ComponentContext) (comp
 		instance := obj.(*dbv1beta1.PostgresExtension)
 		instance.Status.Status = dbv1beta1.StatusReady
 		instance.Status.Message = fmt.Sprintf("Extension %v created", instance.Spec.ExtensionName)
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "ExtensionCreated", instance.Status.Message)
 		return nil
 	}}, nil
 }
+
+func (comp *databaseComponent) Finalize(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresExtension)
+
+	// Connect to the database.
+	db, err := postgres.Open(ctx, &instance.Spec.Database)
+	if err != nil {
+		if k8serrors.IsNotFound(errors.Cause(err)) {
+			// The credentials are gone, so the database is almost certainly being torn down too.
+			ctx.Logger.Info("database credentials not found, skipping extension cleanup")
+			return components.Result{}, nil
+		}
+		return components.Result{Requeue: true}, err
+	}
+
+	_, err = db.ExecContext(ctx.Context, fmt.Sprintf("DROP EXTENSION IF EXISTS %s", pq.QuoteIdentifier(instance.Spec.ExtensionName)))
+	if err != nil {
+		pqErr, ok := err.(*pq.Error)
+		if ok && pqErr.Code.Name() == "dependent_objects_still_exist" {
+			// Something in the database is still using the extension, so it isn't really leaked. Leave it alone.
+			ctx.Logger.Info("extension still in use, not dropping it", "extension", instance.Spec.ExtensionName)
+			return components.Result{}, nil
+		}
+		return components.Result{}, errors.Wrap(err, "database: Error running DROP EXTENSION")
+	}
+	return components.Result{}, nil
+}
@@ -20,6 +20,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
@@ -29,6 +30,7 @@ import (
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	apihelpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 	pecomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresextension/components"
 	"github.com/Ridecell/ridecell-operator/pkg/dbpool"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
@@ -89,4 +91,35 @@ var _ = Describe("PostgresExtension Database Component", func() {
 		Expect(instance.Status.Message).To(Equal("Extension postgis created"))
 	})
 
+	It("doesn't touch the database in a dry run", func() {
+		instance.Spec.Version = "2.5"
+		instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+		client := ctx.Client
+		ctx = components.NewTestContext(instance, nil)
+		ctx.Client = client
+
+		comp := pecomponents.NewDatabase()
+		Expect(comp).To(ReconcileContext(ctx))
+		changes := ctx.DryRunChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("update"))
+		Expect(changes[0].Kind).To(Equal("postgres:Extension"))
+		Expect(changes[0].Name).To(Equal("postgis"))
+		Expect(changes[0].Diff).To(Equal("2.5"))
+	})
+
+	It("drops an extension", func() {
+		dbMock.ExpectExec("DROP EXTENSION IF EXISTS \"postgis\"").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+
+		comp := pecomponents.NewDatabase()
+		Expect(comp).To(FinalizeContext(ctx))
+	})
+
+	It("leaves an extension that is still in use", func() {
+		dbMock.ExpectExec("DROP EXTENSION IF EXISTS \"postgis\"").WithArgs().WillReturnError(&pq.Error{Code: "2BP01"})
+
+		comp := pecomponents.NewDatabase()
+		Expect(comp).To(FinalizeContext(ctx))
+	})
+
 })
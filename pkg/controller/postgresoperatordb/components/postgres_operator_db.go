@@ -19,10 +19,13 @@ package components
 import (
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 )
 
@@ -66,6 +69,36 @@ func (comp *PostgresOperatorDatabaseComponent) Reconcile(ctx *components.Compone
 		instance := obj.(*dbv1beta1.PostgresOperatorDatabase)
 		instance.Status.Status = dbv1beta1.StatusReady
 		instance.Status.Message = "Ready"
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "DatabaseReady", instance.Status.Message)
 		return nil
 	}}, nil
 }
+
+func (comp *PostgresOperatorDatabaseComponent) Finalize(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresOperatorDatabase)
+	fetchDatabase := &postgresv1.Postgresql{}
+	err := ctx.Client.Get(ctx.Context, types.NamespacedName{Name: instance.Spec.DatabaseRef.Name, Namespace: instance.Namespace}, fetchDatabase)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Database is already gone, nothing to remove.
+			return components.Result{}, nil
+		}
+		return components.Result{}, errors.Wrapf(err, "postgres_operatordb: Unable to get specified database")
+	}
+
+	_, hasUser := fetchDatabase.Spec.Users[instance.Spec.Database]
+	_, hasDatabase := fetchDatabase.Spec.Databases[instance.Spec.Database]
+	if !hasUser && !hasDatabase {
+		return components.Result{}, nil
+	}
+
+	// Remove the Users and Databases entries we added.
+	delete(fetchDatabase.Spec.Users, instance.Spec.Database)
+	delete(fetchDatabase.Spec.Databases, instance.Spec.Database)
+
+	err = ctx.Update(ctx.Context, fetchDatabase)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_operatordb: Failed to update Postgresql object")
+	}
+	return components.Result{}, nil
+}
@@ -134,4 +134,43 @@ var _ = Describe("operatordatabase Component", func() {
 		instance.Spec.Database = "test-db"
 		Expect(comp).To(ReconcileContext(ctx))
 	})
+	It("removes its database and user on finalize", func() {
+		comp := postgresoperatordbcomponents.NewPostgresOperatorDB()
+		postgresObj := &postgresv1.Postgresql{
+			ObjectMeta: metav1.ObjectMeta{Name: "fakedb", Namespace: instance.Namespace},
+			Spec: postgresv1.PostgresSpec{
+				TeamID:            instance.Name,
+				NumberOfInstances: int32(1),
+				Databases: map[string]string{
+					"test-db":  "test-db",
+					"test-db2": "test-db2",
+				},
+				Users: map[string]postgresv1.UserFlags{
+					"test-db":  postgresv1.UserFlags{},
+					"test-db2": postgresv1.UserFlags{},
+				},
+			},
+		}
+		ctx.Client = fake.NewFakeClient(postgresObj)
+		instance.Spec.DatabaseRef = dbv1beta1.PostgresDBRef{
+			Name: "fakedb",
+		}
+		instance.Spec.Database = "test-db2"
+
+		Expect(comp).To(FinalizeContext(ctx))
+
+		fetchPostgresObj := &postgresv1.Postgresql{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "fakedb", Namespace: instance.Namespace}, fetchPostgresObj)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fetchPostgresObj.Spec.Databases).To(Equal(map[string]string{"test-db": "test-db"}))
+		Expect(fetchPostgresObj.Spec.Users).To(Equal(map[string]postgresv1.UserFlags{"test-db": postgresv1.UserFlags{}}))
+	})
+
+	It("finalizes cleanly if the postgres object is gone", func() {
+		comp := postgresoperatordbcomponents.NewPostgresOperatorDB()
+		ctx.Client = fake.NewFakeClient()
+
+		instance.Spec.Database = "test-db"
+		Expect(comp).To(FinalizeContext(ctx))
+	})
 })
@@ -22,6 +22,7 @@ import (
 	//corev1 "k8s.io/api/core/v1"
 
 	"github.com/Ridecell/ridecell-operator/pkg/test_helpers"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
@@ -146,4 +147,54 @@ var _ = Describe("PostgresOperatorDatabase controller", func() {
 		}).Should(Equal(dbv1beta1.StatusReady))
 	})
 
+	It("cleans up the postgresql object on delete", func() {
+		postgresObj := &postgresv1.Postgresql{
+			ObjectMeta: metav1.ObjectMeta{Name: "fakedb", Namespace: helpers.Namespace},
+			Spec: postgresv1.PostgresSpec{
+				TeamID:            "test",
+				NumberOfInstances: int32(1),
+				Users: map[string]postgresv1.UserFlags{
+					"test-user": postgresv1.UserFlags{"superuser"},
+				},
+				Databases: map[string]string{
+					"test": "test",
+				},
+			},
+		}
+		err := helpers.Client.Create(context.TODO(), postgresObj)
+		Expect(err).ToNot(HaveOccurred())
+
+		instance := &dbv1beta1.PostgresOperatorDatabase{
+			ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: helpers.Namespace},
+			Spec: dbv1beta1.PostgresOperatorDatabaseSpec{
+				Database: "test-db",
+				DatabaseRef: dbv1beta1.PostgresDBRef{
+					Name: "fakedb",
+				},
+			},
+		}
+		err = helpers.Client.Create(context.TODO(), instance)
+		Expect(err).ToNot(HaveOccurred())
+
+		fetchInstance := &dbv1beta1.PostgresOperatorDatabase{}
+		Eventually(func() []string {
+			err = helpers.Client.Get(context.TODO(), types.NamespacedName{Name: "test.example.com", Namespace: helpers.Namespace}, fetchInstance)
+			Expect(err).ToNot(HaveOccurred())
+			return fetchInstance.Finalizers
+		}, timeout).Should(ContainElement("postgres-operator-database-controller.finalizers.ridecell.io"))
+
+		err = helpers.Client.Delete(context.TODO(), fetchInstance)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() map[string]string {
+			fetchedPostgresObj := &postgresv1.Postgresql{}
+			err := helpers.Client.Get(context.TODO(), types.NamespacedName{Name: "fakedb", Namespace: helpers.Namespace}, fetchedPostgresObj)
+			Expect(err).ToNot(HaveOccurred())
+			return fetchedPostgresObj.Spec.Databases
+		}, timeout).Should(Equal(map[string]string{"test": "test"}))
+		Eventually(func() bool {
+			err := helpers.Client.Get(context.TODO(), types.NamespacedName{Name: "test.example.com", Namespace: helpers.Namespace}, fetchInstance)
+			return kerrors.IsNotFound(err)
+		}, timeout).Should(BeTrue())
+	})
 })
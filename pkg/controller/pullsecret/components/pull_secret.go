@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
@@ -90,6 +91,7 @@ func (comp *pullSecretComponent) Reconcile(ctx *components.ComponentContext) (co
 	return components.Result{StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*secretsv1beta1.PullSecret)
 		instance.Status.Status = secretsv1beta1.StatusReady
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "SecretCopied", instance.Status.Message)
 		return nil
 	}}, nil
 }
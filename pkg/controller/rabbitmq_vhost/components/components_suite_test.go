@@ -17,6 +17,7 @@ limitations under the License.
 package components_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/onsi/ginkgo"
@@ -44,5 +45,5 @@ var _ = ginkgo.BeforeEach(func() {
 	instance = &dbv1beta1.RabbitmqVhost{
 		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
 	}
-	ctx = &components.ComponentContext{Top: instance, Client: fake.NewFakeClient(), Scheme: scheme.Scheme}
+	ctx = &components.ComponentContext{Top: instance, Context: context.TODO(), Client: fake.NewFakeClient(), Scheme: scheme.Scheme}
 })
@@ -17,13 +17,21 @@ limitations under the License.
 package components
 
 import (
+	"context"
 	"crypto/tls"
-	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
-	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
 	"github.com/michaelklishin/rabbit-hole"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"net/http"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
 type vhostComponent struct {
@@ -33,6 +41,7 @@ type vhostComponent struct {
 type RabbitMQManager interface {
 	ListVhosts() ([]rabbithole.VhostInfo, error)
 	PutVhost(string, rabbithole.VhostSettings) (*http.Response, error)
+	DeleteVhost(string) (*http.Response, error)
 }
 
 type NewTLSClientFactory func(uri string, user string, pass string, t *http.Transport) (RabbitMQManager, error)
@@ -60,28 +69,15 @@ func (_ *vhostComponent) IsReconcilable(_ *components.ComponentContext) bool {
 func (comp *vhostComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*dbv1beta1.RabbitmqVhost)
 
-	transport := &http.Transport{TLSClientConfig: &tls.Config{
-		InsecureSkipVerify: instance.Spec.Connection.InsecureSkip,
-	},
-	}
-
-	hostPassword, err := instance.Spec.Connection.Password.Resolve(ctx, "password")
-
+	rmqc, err := comp.connect(ctx, instance)
 	if err != nil {
-		return components.Result{}, errors.Wrapf(err, "error resolving rabbitmq connection credentials")
-	}
-
-	// Connect to the rabbitmq cluster
-	rmqc, err := comp.Client(instance.Spec.Connection.Host, instance.Spec.Connection.Username, hostPassword, transport)
-
-	if err != nil {
-		return components.Result{}, errors.Wrapf(err, "error creating rabbitmq client")
+		return components.Result{}, err
 	}
 
 	// Create the required vhost if it does not exist
 	xs, err := rmqc.ListVhosts()
 	if err != nil {
-		return components.Result{}, errors.Wrapf(err, "error connecting or fetching rabbitmq vhosts")
+		return components.Result{}, components.Transient(errors.Wrapf(err, "error connecting or fetching rabbitmq vhosts"), 0)
 	}
 
 	var vhost_exists bool
@@ -90,11 +86,71 @@ func (comp *vhostComponent) Reconcile(ctx *components.ComponentContext) (compone
 			vhost_exists = true
 		}
 	}
+	if !vhost_exists && ctx.IsDryRun() {
+		ctx.RecordDryRun("create", "rabbitmq:Vhost", instance.Spec.VhostName, "")
+		return components.Result{}, nil
+	}
 	if !vhost_exists {
 		resp, _ := rmqc.PutVhost(instance.Spec.VhostName, rabbithole.VhostSettings{Tracing: false})
 		if resp.StatusCode != 201 {
 			return components.Result{}, errors.Wrapf(err, "unable to create vhost %s", instance.Spec.VhostName)
 		}
 	}
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.RabbitmqVhost)
+		instance.Status.Status = dbv1beta1.StatusReady
+		instance.Status.Message = fmt.Sprintf("Vhost %s exists", instance.Spec.VhostName)
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "VhostReady", instance.Status.Message)
+		return nil
+	}}, nil
+}
+
+func (comp *vhostComponent) Finalize(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.RabbitmqVhost)
+
+	rmqc, err := comp.connect(ctx, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+
+	resp, err := rmqc.DeleteVhost(instance.Spec.VhostName)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "error deleting vhost %s", instance.Spec.VhostName)
+	}
+	// 404 means it was already deleted, which is fine.
+	if resp.StatusCode != 204 && resp.StatusCode != 404 {
+		return components.Result{}, errors.Errorf("unable to delete vhost %s, got status %d", instance.Spec.VhostName, resp.StatusCode)
+	}
 	return components.Result{}, nil
 }
+
+func (comp *vhostComponent) connect(ctx *components.ComponentContext, instance *dbv1beta1.RabbitmqVhost) (RabbitMQManager, error) {
+	transport := &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: instance.Spec.Connection.InsecureSkip,
+	},
+	}
+	// rabbit-hole doesn't take a context, so tie the transport to ours instead. Connections are dialed
+	// with the reconcile context and responses have to arrive before its deadline.
+	dialer := &net.Dialer{}
+	transport.DialContext = func(_ context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx.Context, network, addr)
+	}
+	deadline, ok := ctx.Context.Deadline()
+	if ok {
+		transport.ResponseHeaderTimeout = time.Until(deadline)
+	}
+
+	hostPassword, err := instance.Spec.Connection.Password.Resolve(ctx, "password")
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error resolving rabbitmq connection credentials")
+	}
+
+	// Connect to the rabbitmq cluster
+	rmqc, err := comp.Client(instance.Spec.Connection.Host, instance.Spec.Connection.Username, hostPassword, transport)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating rabbitmq client")
+	}
+	return rmqc, nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/michaelklishin/rabbit-hole"
 	"net/http"
 
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 	rmqvcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/rabbitmq_vhost/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 	corev1 "k8s.io/api/core/v1"
@@ -54,6 +55,16 @@ func (frc *fakeRabbitClient) PutVhost(vhostname string, settings rabbithole.Vhos
 	return &http.Response{StatusCode: 200}, nil
 }
 
+func (frc *fakeRabbitClient) DeleteVhost(vhostname string) (*http.Response, error) {
+	for i, element := range frc.FakeVhostList {
+		if element.Name == vhostname {
+			frc.FakeVhostList = append(frc.FakeVhostList[:i], frc.FakeVhostList[i+1:]...)
+			return &http.Response{StatusCode: 204}, nil
+		}
+	}
+	return &http.Response{StatusCode: 404}, nil
+}
+
 var _ = Describe("RabbitmqVhost Vhost Component", func() {
 	BeforeEach(func() {
 		// Set password in secrets
@@ -92,6 +103,26 @@ var _ = Describe("RabbitmqVhost Vhost Component", func() {
 		Expect(comp).To(ReconcileContext(ctx))
 		Expect(mgr.FakeVhostList).To(HaveLen(1))
 	})
+	It("Doesn't create the vhost in a dry run", func() {
+		comp := rmqvcomponents.NewVhost()
+		instance.Spec.VhostName = "foo"
+		instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+		client := ctx.Client
+		ctx = components.NewTestContext(instance, nil)
+		ctx.Client = client
+		mgr := &fakeRabbitClient{}
+		fakeFunc := func(uri string, user string, pass string, t *http.Transport) (rmqvcomponents.RabbitMQManager, error) {
+			return mgr, nil
+		}
+		comp.InjectFakeNewTLSClient(fakeFunc)
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mgr.FakeVhostList).To(BeEmpty())
+		changes := ctx.DryRunChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("create"))
+		Expect(changes[0].Kind).To(Equal("rabbitmq:Vhost"))
+		Expect(changes[0].Name).To(Equal("foo"))
+	})
 	It("Fails to connect to unavailable rabbitmq host", func() {
 		comp := rmqvcomponents.NewVhost()
 		Expect(comp).ToNot(ReconcileContext(ctx))
@@ -101,4 +132,24 @@ var _ = Describe("RabbitmqVhost Vhost Component", func() {
 		instance.Spec.Connection.Host = "htt://127.0.0.1:80"
 		Expect(comp).ToNot(ReconcileContext(ctx))
 	})
+	It("Deletes the vhost on finalize", func() {
+		comp := rmqvcomponents.NewVhost()
+		instance.Spec.VhostName = "foo"
+		mgr := &fakeRabbitClient{FakeVhostList: []rabbithole.VhostInfo{{Name: "foo"}, {Name: "bar"}}}
+		fakeFunc := func(uri string, user string, pass string, t *http.Transport) (rmqvcomponents.RabbitMQManager, error) {
+			return mgr, nil
+		}
+		comp.InjectFakeNewTLSClient(fakeFunc)
+		Expect(comp).To(FinalizeContext(ctx))
+		Expect(mgr.FakeVhostList).To(Equal([]rabbithole.VhostInfo{{Name: "bar"}}))
+	})
+	It("Finalizes cleanly if the vhost is already gone", func() {
+		comp := rmqvcomponents.NewVhost()
+		instance.Spec.VhostName = "foo"
+		fakeFunc := func(uri string, user string, pass string, t *http.Transport) (rmqvcomponents.RabbitMQManager, error) {
+			return &fakeRabbitClient{}, nil
+		}
+		comp.InjectFakeNewTLSClient(fakeFunc)
+		Expect(comp).To(FinalizeContext(ctx))
+	})
 })
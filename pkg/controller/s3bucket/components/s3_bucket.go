@@ -27,9 +27,11 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 )
 
 type S3Factory func(region string) (s3iface.S3API, error)
@@ -80,7 +82,7 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 
 	// Run a ListBucket call to check if this bucket exists.
 	bucketExists := true
-	_, err = s3Service.ListObjects(&s3.ListObjectsInput{
+	_, err = s3Service.ListObjectsWithContext(ctx.Context, &s3.ListObjectsInput{
 		Bucket:  aws.String(instance.Spec.BucketName),
 		MaxKeys: aws.Int64(1), // We don't actually care about the keys, so set this down for perf.
 	})
@@ -95,7 +97,12 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 
 	// If the bucket does not exist create it
 	if !bucketExists {
-		_, err = s3Service.CreateBucket(&s3.CreateBucketInput{
+		if ctx.IsDryRun() {
+			// The policy can't be checked until the bucket exists.
+			ctx.RecordDryRun("create", "aws:s3:Bucket", instance.Spec.BucketName, "")
+			return components.Result{}, nil
+		}
+		_, err = s3Service.CreateBucketWithContext(ctx.Context, &s3.CreateBucketInput{
 			Bucket: aws.String(instance.Spec.BucketName),
 			CreateBucketConfiguration: &s3.CreateBucketConfiguration{
 				LocationConstraint: aws.String(instance.Spec.Region),
@@ -108,7 +115,7 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 
 	// Try to grab the existing bucket policy.
 	bucketHasPolicy := true
-	getBucketPolicyObj, err := s3Service.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: aws.String(instance.Spec.BucketName)})
+	getBucketPolicyObj, err := s3Service.GetBucketPolicyWithContext(ctx.Context, &s3.GetBucketPolicyInput{Bucket: aws.String(instance.Spec.BucketName)})
 	if err != nil {
 		aerr, ok := err.(awserr.Error)
 		if ok && aerr.Code() == "NoSuchBucketPolicy" { // There is no ErrCode const for this error. What?
@@ -120,8 +127,10 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 
 	// If the policy is "", we need to delete if set. Otherwise we need to check for == and then put.
 	if instance.Spec.BucketPolicy == "" {
-		if bucketHasPolicy {
-			_, err := s3Service.DeleteBucketPolicy(&s3.DeleteBucketPolicyInput{
+		if bucketHasPolicy && ctx.IsDryRun() {
+			ctx.RecordDryRun("delete", "aws:s3:BucketPolicy", instance.Spec.BucketName, "")
+		} else if bucketHasPolicy {
+			_, err := s3Service.DeleteBucketPolicyWithContext(ctx.Context, &s3.DeleteBucketPolicyInput{
 				Bucket: aws.String(instance.Spec.BucketName),
 			})
 			if err != nil {
@@ -138,9 +147,9 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 			if err != nil {
 				return components.Result{}, errors.Wrapf(err, "s3_bucket: error decoding existing bucket policy for bucket %s", instance.Spec.BucketName)
 			}
-			err = json.Unmarshal([]byte(instance.Spec.BucketPolicy), &goalPolicy)
+			goalPolicy, err = awsv1beta1.DecodePolicy(instance.Spec.BucketPolicy)
 			if err != nil {
-				return components.Result{}, errors.Wrapf(err, "s3_bucket: error decoding goal bucket policy for bucket %s", instance.Spec.BucketName)
+				return components.Result{}, components.Permanent(errors.Wrapf(err, "s3_bucket: error decoding goal bucket policy for bucket %s", instance.Spec.BucketName))
 			}
 			bucketPolicyNeedsUpdate = !reflect.DeepEqual(existingPolicy, goalPolicy)
 		} else {
@@ -149,8 +158,10 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 		}
 
 		// Update or create the bucket policy.
-		if bucketPolicyNeedsUpdate {
-			_, err := s3Service.PutBucketPolicy(&s3.PutBucketPolicyInput{
+		if bucketPolicyNeedsUpdate && ctx.IsDryRun() {
+			ctx.RecordDryRun("update", "aws:s3:BucketPolicy", instance.Spec.BucketName, instance.Spec.BucketPolicy)
+		} else if bucketPolicyNeedsUpdate {
+			_, err := s3Service.PutBucketPolicyWithContext(ctx.Context, &s3.PutBucketPolicyInput{
 				Bucket: aws.String(instance.Spec.BucketName),
 				Policy: aws.String(instance.Spec.BucketPolicy),
 			})
@@ -164,10 +175,35 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 		instance := obj.(*awsv1beta1.S3Bucket)
 		instance.Status.Status = awsv1beta1.StatusReady
 		instance.Status.Message = "Bucket exists and has correct policy"
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "BucketReady", instance.Status.Message)
 		return nil
 	}}, nil
 }
 
+func (comp *s3BucketComponent) Finalize(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*awsv1beta1.S3Bucket)
+
+	s3Service, err := comp.getS3(instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+
+	// Only empty buckets are removed, we never want to throw away data as a side effect of deleting a CR.
+	_, err = s3Service.DeleteBucketWithContext(ctx.Context, &s3.DeleteBucketInput{Bucket: aws.String(instance.Spec.BucketName)})
+	if err != nil {
+		aerr, ok := err.(awserr.Error)
+		if ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+			// Already gone.
+			return components.Result{}, nil
+		}
+		if ok && aerr.Code() == "BucketNotEmpty" { // Also no ErrCode const for this one.
+			return components.Result{}, errors.Errorf("s3_bucket: bucket %s is not empty, remove its contents to finish deleting", instance.Spec.BucketName)
+		}
+		return components.Result{}, errors.Wrapf(err, "s3_bucket: failed to delete bucket %s", instance.Spec.BucketName)
+	}
+	return components.Result{}, nil
+}
+
 func (comp *s3BucketComponent) getS3(instance *awsv1beta1.S3Bucket) (s3iface.S3API, error) {
 	s3Service, ok := comp.s3Services[instance.Spec.Region]
 	if ok {
@@ -25,22 +25,27 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/pkg/errors"
 
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 	s3bucketcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/s3bucket/components"
 )
 
 type mockS3Client struct {
 	s3iface.S3API
-	mockBucketExists    bool
-	mockBucketPolicy    *string
-	mockBucketNameTaken bool
+	mockBucketExists     bool
+	mockBucketPolicy     *string
+	mockBucketNameTaken  bool
+	mockBucketHasObjects bool
 
+	createBucket     bool
 	putPolicy        bool
 	putPolicyContent string
 	deletePolicy     bool
+	deleteBucket     bool
 }
 
 var _ = Describe("s3bucket aws Component", func() {
@@ -132,11 +137,66 @@ var _ = Describe("s3bucket aws Component", func() {
 		Expect(mockS3.putPolicy).To(BeFalse())
 		Expect(mockS3.deletePolicy).To(BeTrue())
 	})
+
+	It("doesn't create the bucket in a dry run", func() {
+		instance.Spec.BucketName = "foo-default-static"
+		instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+		ctx = components.NewTestContext(instance, nil)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockS3.createBucket).To(BeFalse())
+		changes := ctx.DryRunChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("create"))
+		Expect(changes[0].Kind).To(Equal("aws:s3:Bucket"))
+		Expect(changes[0].Name).To(Equal("foo-default-static"))
+	})
+
+	It("doesn't put the bucket policy in a dry run", func() {
+		mockS3.mockBucketExists = true
+		instance.Spec.BucketName = "foo-default-static"
+		instance.Spec.BucketPolicy = `{"Version": "2008-10-17", "Statement": []}`
+		instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+		ctx = components.NewTestContext(instance, nil)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockS3.putPolicy).To(BeFalse())
+		changes := ctx.DryRunChanges()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Operation).To(Equal("update"))
+		Expect(changes[0].Kind).To(Equal("aws:s3:BucketPolicy"))
+		Expect(changes[0].Diff).To(Equal(instance.Spec.BucketPolicy))
+	})
+
+	It("deletes an empty bucket", func() {
+		mockS3.mockBucketExists = true
+		instance.Spec.BucketName = "foo-default-static"
+
+		Expect(comp).To(FinalizeContext(ctx))
+		Expect(mockS3.deleteBucket).To(BeTrue())
+	})
+
+	It("finalizes cleanly if the bucket is already gone", func() {
+		instance.Spec.BucketName = "foo-default-static"
+
+		Expect(comp).To(FinalizeContext(ctx))
+		Expect(mockS3.deleteBucket).To(BeFalse())
+	})
+
+	It("refuses to delete a bucket with objects in it", func() {
+		mockS3.mockBucketExists = true
+		mockS3.mockBucketHasObjects = true
+		instance.Spec.BucketName = "foo-default-static"
+
+		_, err := comp.Finalize(ctx)
+		Expect(err).To(MatchError("s3_bucket: bucket foo-default-static is not empty, remove its contents to finish deleting"))
+		Expect(mockS3.deleteBucket).To(BeFalse())
+	})
 })
 
 // Mock aws functions below
 
-func (m *mockS3Client) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+func (m *mockS3Client) ListObjectsWithContext(_ aws.Context, input *s3.ListObjectsInput, _ ...request.Option) (*s3.ListObjectsOutput, error) {
 	if m.mockBucketExists {
 		return &s3.ListObjectsOutput{}, nil
 	} else {
@@ -144,7 +204,7 @@ func (m *mockS3Client) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsO
 	}
 }
 
-func (m *mockS3Client) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+func (m *mockS3Client) CreateBucketWithContext(_ aws.Context, input *s3.CreateBucketInput, _ ...request.Option) (*s3.CreateBucketOutput, error) {
 	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
 		return &s3.CreateBucketOutput{}, errors.New("awsmock_createbucket: bucket name was incorrect")
 	}
@@ -154,10 +214,11 @@ func (m *mockS3Client) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBuck
 	if m.mockBucketNameTaken {
 		return &s3.CreateBucketOutput{}, errors.New("awsmock_createbucket: bucket name taken")
 	}
+	m.createBucket = true
 	return &s3.CreateBucketOutput{}, nil
 }
 
-func (m *mockS3Client) GetBucketPolicy(input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+func (m *mockS3Client) GetBucketPolicyWithContext(_ aws.Context, input *s3.GetBucketPolicyInput, _ ...request.Option) (*s3.GetBucketPolicyOutput, error) {
 	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
 		return &s3.GetBucketPolicyOutput{}, errors.New("awsmock_getbucketpolicy: bucketname was incorrect")
 	}
@@ -167,7 +228,7 @@ func (m *mockS3Client) GetBucketPolicy(input *s3.GetBucketPolicyInput) (*s3.GetB
 	return &s3.GetBucketPolicyOutput{Policy: m.mockBucketPolicy}, nil
 }
 
-func (m *mockS3Client) PutBucketPolicy(input *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error) {
+func (m *mockS3Client) PutBucketPolicyWithContext(_ aws.Context, input *s3.PutBucketPolicyInput, _ ...request.Option) (*s3.PutBucketPolicyOutput, error) {
 	// Check bucket name.
 	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
 		return nil, awserr.New("NoSuchBucket", "", nil)
@@ -183,7 +244,7 @@ func (m *mockS3Client) PutBucketPolicy(input *s3.PutBucketPolicyInput) (*s3.PutB
 	return &s3.PutBucketPolicyOutput{}, nil
 }
 
-func (m *mockS3Client) DeleteBucketPolicy(input *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+func (m *mockS3Client) DeleteBucketPolicyWithContext(_ aws.Context, input *s3.DeleteBucketPolicyInput, _ ...request.Option) (*s3.DeleteBucketPolicyOutput, error) {
 	// Check bucket name.
 	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
 		return nil, awserr.New("NoSuchBucket", "", nil)
@@ -191,3 +252,17 @@ func (m *mockS3Client) DeleteBucketPolicy(input *s3.DeleteBucketPolicyInput) (*s
 	m.deletePolicy = true
 	return &s3.DeleteBucketPolicyOutput{}, nil
 }
+
+func (m *mockS3Client) DeleteBucketWithContext(_ aws.Context, input *s3.DeleteBucketInput, _ ...request.Option) (*s3.DeleteBucketOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, errors.New("awsmock_deletebucket: bucket name was incorrect")
+	}
+	if !m.mockBucketExists {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	if m.mockBucketHasObjects {
+		return nil, awserr.New("BucketNotEmpty", "", nil)
+	}
+	m.deleteBucket = true
+	return &s3.DeleteBucketOutput{}, nil
+}
@@ -30,7 +30,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
-	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -66,16 +65,34 @@ func (comp *appSecretComponent) WatchTypes() []runtime.Object {
 	}
 }
 
-func (_ *appSecretComponent) IsReconcilable(ctx *components.ComponentContext) bool {
-	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+func (_ *appSecretComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the database and other secrets via Dependencies.
+	return true
+}
+
+func (_ *appSecretComponent) Dependencies() []string {
+	return []string{outputPostgres, outputSecretKey, outputFernetKeys, outputIAMUser}
+}
 
-	if instance.Status.PostgresStatus != postgresv1.ClusterStatusRunning {
-		return false
+func (_ *appSecretComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputAppSecrets},
 	}
-	return true
 }
 
 func (comp *appSecretComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	res, err := comp.reconcile(ctx)
+	if err != nil {
+		res.StatusModifier = components.SetCondition(summonv1beta1.ConditionSecretsReady, corev1.ConditionFalse, "Error", err.Error())
+	} else if res.Requeue {
+		res.StatusModifier = components.SetCondition(summonv1beta1.ConditionSecretsReady, corev1.ConditionFalse, "WaitingForInputs", "Waiting for input secrets to be created")
+	} else {
+		res.StatusModifier = components.SetCondition(summonv1beta1.ConditionSecretsReady, corev1.ConditionTrue, "SecretsCreated", "")
+	}
+	return res, err
+}
+
+func (comp *appSecretComponent) reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
 	var rawAppSecrets []*corev1.Secret
@@ -102,7 +119,7 @@ func (comp *appSecretComponent) Reconcile(ctx *components.ComponentContext) (com
 			// Don't trigger an error on notfound so it doesn't notify. Just try again.
 			return components.Result{Requeue: true}, nil
 		} else {
-			return components.Result{Requeue: true}, errors.Wrapf(err, "app_secrets: Postgres password not found")
+			return components.Result{}, components.Transient(errors.Wrapf(err, "app_secrets: Postgres password not found"), 0)
 		}
 	}
 	postgresPassword, ok := postgresSecret.Data["password"]
@@ -117,7 +134,7 @@ func (comp *appSecretComponent) Reconcile(ctx *components.ComponentContext) (com
 			// Don't trigger an error on notfound so it doesn't notify. Just try again.
 			return components.Result{Requeue: true}, nil
 		} else {
-			return components.Result{Requeue: true}, errors.Wrapf(err, "app_secrets: Fernet keys secret not found")
+			return components.Result{}, components.Transient(errors.Wrapf(err, "app_secrets: Fernet keys secret not found"), 0)
 		}
 	}
 	if len(fernetKeys.Data) == 0 {
@@ -136,7 +153,7 @@ func (comp *appSecretComponent) Reconcile(ctx *components.ComponentContext) (com
 			// Don't trigger an error on notfound so it doesn't notify. Just try again.
 			return components.Result{Requeue: true}, nil
 		} else {
-			return components.Result{Requeue: true}, errors.Wrapf(err, "app_secrets: Unable to get SECRET_KEY")
+			return components.Result{}, components.Transient(errors.Wrapf(err, "app_secrets: Unable to get SECRET_KEY"), 0)
 		}
 	}
 	val, ok := secretKey.Data["SECRET_KEY"]
@@ -151,7 +168,7 @@ func (comp *appSecretComponent) Reconcile(ctx *components.ComponentContext) (com
 			// Don't trigger an error on notfound so it doesn't notify. Just try again.
 			return components.Result{Requeue: true}, nil
 		} else {
-			return components.Result{Requeue: true}, errors.Wrapf(err, "app_secrets: Unable to get aws credentials secret")
+			return components.Result{}, components.Transient(errors.Wrapf(err, "app_secrets: Unable to get aws credentials secret"), 0)
 		}
 	}
 
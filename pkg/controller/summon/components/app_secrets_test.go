@@ -52,15 +52,9 @@ var _ = Describe("app_secrets Component", func() {
 		instance.Spec.Database.ExclusiveDatabase = true
 	})
 
-	It("Unreconcilable when db not ready", func() {
+	It("depends on the database and other secrets", func() {
 		comp := summoncomponents.NewAppSecret()
-		Expect(comp.IsReconcilable(ctx)).To(Equal(false))
-	})
-
-	It("Reconcilable when db is ready", func() {
-		comp := summoncomponents.NewAppSecret()
-		instance.Status.PostgresStatus = postgresv1.ClusterStatusRunning
-		Expect(comp.IsReconcilable(ctx)).To(Equal(true))
+		Expect(comp.Dependencies()).To(ConsistOf("postgres", "secret-key", "fernet-keys", "iam-user"))
 	})
 
 	It("Run reconcile without a postgres password", func() {
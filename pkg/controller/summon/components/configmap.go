@@ -46,6 +46,16 @@ func (_ *configmapComponent) IsReconcilable(_ *components.ComponentContext) bool
 	return true
 }
 
+func (_ *configmapComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *configmapComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputConfigMap},
+	}
+}
+
 func (comp *configmapComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
@@ -65,12 +75,6 @@ func (comp *configmapComponent) Reconcile(ctx *components.ComponentContext) (com
 	extra := map[string]interface{}{}
 	extra["SummonYaml"] = string(b)
 
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, extra, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*corev1.ConfigMap)
-		existing := existingObj.(*corev1.ConfigMap)
-		// Copy the data over.
-		existing.Data = goal.Data
-		return nil
-	})
+	res, _, err := ctx.Apply(comp.templatePath, extra)
 	return res, err
 }
@@ -97,6 +97,22 @@ var _ = Describe("SummonPlatform Defaults Component", func() {
 		Expect(instance.Spec.StaticReplicas).To(PointTo(BeEquivalentTo(2)))
 	})
 
+	It("sets autoscaler defaults", func() {
+		instance.Spec = summonv1beta1.SummonPlatformSpec{
+			Autoscaling: summonv1beta1.AutoscalingSpec{
+				Web:     &summonv1beta1.AutoscalerSpec{MaxReplicas: 5},
+				Celeryd: &summonv1beta1.AutoscalerSpec{MaxReplicas: 5, TargetMemoryUtilization: intp(70)},
+			},
+		}
+
+		comp := summoncomponents.NewDefaults()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.Autoscaling.Web.MinReplicas).To(PointTo(BeEquivalentTo(1)))
+		Expect(instance.Spec.Autoscaling.Web.TargetCPUUtilization).To(PointTo(BeEquivalentTo(80)))
+		Expect(instance.Spec.Autoscaling.Celeryd.TargetCPUUtilization).To(BeNil())
+		Expect(instance.Spec.Autoscaling.Daphne).To(BeNil())
+	})
+
 	It("Sets a default Secret for dev", func() {
 		instance.Spec = summonv1beta1.SummonPlatformSpec{}
 		instance.Namespace = "dev"
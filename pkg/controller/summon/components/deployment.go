@@ -24,49 +24,62 @@ import (
 
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
-	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta1 "k8s.io/api/autoscaling/v2beta1"
 	corev1 "k8s.io/api/core/v1"
 )
 
 type deploymentComponent struct {
 	templatePath string
+	// Follow the rollout strategy, with a Deployment per slot. See rollout.go.
+	slotted bool
 }
 
 func NewDeployment(templatePath string) *deploymentComponent {
 	return &deploymentComponent{templatePath: templatePath}
 }
 
+// Like NewDeployment, but for subsystems which follow the rollout strategy. With BlueGreen or Canary the
+// template is rendered once for each slot in use, with .Extra.slot set to the slot name.
+func NewRolloutDeployment(templatePath string) *deploymentComponent {
+	return &deploymentComponent{templatePath: templatePath, slotted: true}
+}
+
+func (comp *deploymentComponent) String() string {
+	return fmt.Sprintf("deployment(%s)", comp.templatePath)
+}
+
 func (comp *deploymentComponent) WatchTypes() []runtime.Object {
 	return []runtime.Object{
 		&appsv1.Deployment{},
+		&autoscalingv2beta1.HorizontalPodAutoscaler{},
 	}
 }
 
-func (comp *deploymentComponent) IsReconcilable(ctx *components.ComponentContext) bool {
-	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
-	// Check on the pull secret. Not technically needed in some cases, but just wait.
-	if instance.Status.PullSecretStatus != secretsv1beta1.StatusReady {
-		return false
-	}
-	// We do want the database, so check all the database statuses.
-	if instance.Status.PostgresStatus != postgresv1.ClusterStatusRunning {
-		return false
-	}
-	if instance.Status.PostgresExtensionStatus != summonv1beta1.StatusReady {
-		return false
-	}
-	if instance.Status.MigrateVersion != instance.Spec.Version {
-		return false
-	}
+func (comp *deploymentComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the pull secret, database and migrations via Dependencies.
 	return true
 }
 
+func (comp *deploymentComponent) Dependencies() []string {
+	deps := []string{outputPullSecret, outputPostgres, outputPostgresExtensions, outputMigrations, outputPostMigrateHooks, outputAppSecrets, outputConfigMap}
+	if comp.slotted {
+		deps = append(deps, outputRollout)
+	}
+	return deps
+}
+
+func (_ *deploymentComponent) Outputs() []components.Output {
+	return nil
+}
+
 func (comp *deploymentComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
@@ -99,19 +112,138 @@ func (comp *deploymentComponent) Reconcile(ctx *components.ComponentContext) (co
 	extra["configHash"] = string(configMapHash)
 	extra["appSecretsHash"] = string(appSecretsHash)
 
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, extra, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*appsv1.Deployment)
-		existing := existingObj.(*appsv1.Deployment)
-		// Copy the Spec over.
-		existing.Spec = goal.Spec
-		return nil
-	})
-	if err != nil {
-		return res, errors.Wrapf(err, "deployment: failed to update template")
+	slots := []slotContext{{ctx: servingContext(ctx)}}
+	if comp.slotted {
+		slots = rolloutSlots(ctx)
+	}
+	for _, slot := range slots {
+		extra["slot"] = slot.slot
+		// Autoscaled subsystems render a HorizontalPodAutoscaler after the Deployment.
+		objs, err := slot.ctx.GetTemplateList(comp.templatePath, extra)
+		if err != nil {
+			return components.Result{}, err
+		}
+		for _, obj := range objs {
+			err = comp.keepLiveReplicas(slot.ctx, obj)
+			if err != nil {
+				return components.Result{Requeue: true}, err
+			}
+			res, _, err := slot.ctx.ApplyObject(obj)
+			if err != nil {
+				return res, errors.Wrapf(err, "deployment: failed to update template")
+			}
+		}
+	}
+	if comp.slotted {
+		err = comp.removeUnusedSlots(ctx, slots, extra)
+		if err != nil {
+			return components.Result{}, err
+		}
 	}
 	return components.Result{}, nil
 }
 
+// Delete the Deployment and autoscaler of each slot which isn't rendered any more, like the old active slot
+// after a promotion. The Services move over in the same pass, so a Deployment which one of them still sends
+// traffic to is left for the next reconcile.
+func (comp *deploymentComponent) removeUnusedSlots(ctx *components.ComponentContext, slots []slotContext, extra map[string]interface{}) error {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	rendered := map[string]bool{}
+	for _, slot := range slots {
+		rendered[slot.slot] = true
+	}
+	var services *corev1.ServiceList
+	for _, slot := range []string{"", "blue", "green"} {
+		if rendered[slot] {
+			continue
+		}
+		extra["slot"] = slot
+		objs, err := ctx.GetTemplateList(comp.templatePath, extra)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			deployment, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				continue
+			}
+			existing := &appsv1.Deployment{}
+			err := ctx.Get(ctx.Context, types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, existing)
+			if kerrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return errors.Wrapf(err, "deployment: unable to get deployment %s/%s", deployment.Namespace, deployment.Name)
+			}
+			// Labels are easy to copy around, only touch things we actually own.
+			if !metav1.IsControlledBy(existing, instance) {
+				continue
+			}
+			if services == nil {
+				services = &corev1.ServiceList{}
+				err = ctx.List(ctx.Context, client.InNamespace(instance.Namespace), services)
+				if err != nil {
+					return errors.Wrapf(err, "deployment: unable to list services")
+				}
+			}
+			if selectedByService(services, existing.Spec.Template.Labels) {
+				continue
+			}
+
+			err = ctx.Delete(ctx.Context, existing, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !kerrors.IsNotFound(err) {
+				return errors.Wrapf(err, "deployment: unable to delete deployment %s/%s", existing.Namespace, existing.Name)
+			}
+			// The autoscaler shares the Deployment's name, whether or not the template still renders one.
+			hpa := &autoscalingv2beta1.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: existing.Name, Namespace: existing.Namespace}}
+			err = ctx.Delete(ctx.Context, hpa)
+			if err != nil && !kerrors.IsNotFound(err) {
+				return errors.Wrapf(err, "deployment: unable to delete autoscaler %s/%s", existing.Namespace, existing.Name)
+			}
+			ctx.Eventf(corev1.EventTypeNormal, "SlotRemoved", "Deleted Deployment %s which no longer gets any traffic", existing.Name)
+		}
+	}
+	return nil
+}
+
+// Check if any of the Services selects pods with these labels.
+func selectedByService(services *corev1.ServiceList, podLabels map[string]string) bool {
+	for _, service := range services.Items {
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+		matches := true
+		for key, value := range service.Spec.Selector {
+			if podLabels[key] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// A Deployment switched over to an autoscaler still has spec.replicas in its last applied annotation, so
+// leaving them out would make Apply clear them and the API server would reset it to 1 replica. Render the
+// live count instead, which also leaves whatever the autoscaler picks alone.
+func (_ *deploymentComponent) keepLiveReplicas(ctx *components.ComponentContext, obj runtime.Object) error {
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok || deployment.Spec.Replicas != nil {
+		return nil
+	}
+	existing := &appsv1.Deployment{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, existing)
+	if kerrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "deployment: unable to get deployment %s/%s", deployment.Namespace, deployment.Name)
+	}
+	deployment.Spec.Replicas = existing.Spec.Replicas
+	return nil
+}
+
 func (_ *deploymentComponent) hashItem(data []byte) string {
 	hash := sha1.New().Sum(data)
 	encodedHash := hex.EncodeToString(hash)
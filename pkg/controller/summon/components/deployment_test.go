@@ -27,9 +27,13 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta1 "k8s.io/api/autoscaling/v2beta1"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -144,4 +148,275 @@ var _ = Describe("deployment Component", func() {
 		Expect(deploymentPodAnnotations["summon.ridecell.io/configHash"]).To(Equal(expectedConfigHash))
 
 	})
+
+	It("keeps the last good version while rolled back", func() {
+		comp := summoncomponents.NewDeployment("static/deployment.yml.tpl")
+		instance.Spec.StaticReplicas = intp(1)
+		instance.Spec.Rollback.MigrationFailures = 2
+		instance.Status.MigrateVersion = "1.2.2"
+		instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1.2.3", Count: 2, RolledBack: true}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-static", Namespace: instance.Namespace}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.2"))
+		Expect(instance.Spec.Version).To(Equal("1.2.3"))
+	})
+
+	It("leaves the replica count to an autoscaler", func() {
+		comp := summoncomponents.NewDeployment("static/deployment.yml.tpl")
+		instance.Spec.StaticReplicas = intp(1)
+		instance.Spec.Autoscaling.Static = &summonv1beta1.AutoscalerSpec{MinReplicas: intp(2), MaxReplicas: 5, TargetCPUUtilization: intp(80)}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		hpa := &autoscalingv2beta1.HorizontalPodAutoscaler{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-static", Namespace: instance.Namespace}, hpa)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal("foo-static"))
+		Expect(hpa.Spec.MinReplicas).To(Equal(intp(2)))
+		Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+		Expect(hpa.Spec.Metrics).To(HaveLen(1))
+		Expect(hpa.Spec.Metrics[0].Resource.Name).To(Equal(corev1.ResourceCPU))
+		Expect(hpa.Spec.Metrics[0].Resource.TargetAverageUtilization).To(Equal(intp(80)))
+
+		// Scale up like the autoscaler would, the next reconcile should leave it alone.
+		deployment := &appsv1.Deployment{}
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-static", Namespace: instance.Namespace}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Replicas).To(BeNil())
+		deployment.Spec.Replicas = intp(4)
+		err = ctx.Client.Update(context.TODO(), deployment)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(comp).To(ReconcileContext(ctx))
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-static", Namespace: instance.Namespace}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Replicas).To(Equal(intp(4)))
+	})
+
+	It("keeps the replica count when switching to an autoscaler", func() {
+		comp := summoncomponents.NewDeployment("static/deployment.yml.tpl")
+		instance.Spec.StaticReplicas = intp(3)
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-static", Namespace: instance.Namespace}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Replicas).To(Equal(intp(3)))
+
+		// The last applied annotation still has 3 replicas, which must not turn into a reset.
+		instance.Spec.Autoscaling.Static = &summonv1beta1.AutoscalerSpec{MinReplicas: intp(2), MaxReplicas: 5, TargetCPUUtilization: intp(80)}
+		Expect(comp).To(ReconcileContext(ctx))
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-static", Namespace: instance.Namespace}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Replicas).To(Equal(intp(3)))
+	})
+
+	It("applies pod overrides", func() {
+		comp := summoncomponents.NewDeployment("celeryd/deployment.yml.tpl")
+		instance.Spec.WorkerReplicas = intp(1)
+		instance.Spec.Overrides.Celeryd = summonv1beta1.PodOverrides{
+			Resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			},
+			NodeSelector:      map[string]string{"pool": "workers"},
+			Tolerations:       []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+			PriorityClassName: "low",
+			Env:               []corev1.EnvVar{{Name: "CELERY_CONCURRENCY", Value: "8"}},
+		}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-celeryd", Namespace: instance.Namespace}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		podSpec := deployment.Spec.Template.Spec
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"pool": "workers"}))
+		Expect(podSpec.Tolerations).To(HaveLen(1))
+		Expect(podSpec.PriorityClassName).To(Equal("low"))
+		container := podSpec.Containers[0]
+		Expect(container.Env).To(Equal([]corev1.EnvVar{{Name: "CELERY_CONCURRENCY", Value: "8"}}))
+		Expect(container.Resources.Requests.Cpu().String()).To(Equal("2"))
+		Expect(container.Resources.Limits).To(BeEmpty())
+	})
+
+	It("runs the active and candidate slots side by side", func() {
+		comp := summoncomponents.NewRolloutDeployment("web/deployment.yml.tpl")
+		instance.Spec.WebReplicas = intp(1)
+		instance.Spec.Rollout.Strategy = summonv1beta1.RolloutBlueGreen
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.2", CandidateVersion: "1.2.3"}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		active := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-green", Namespace: instance.Namespace}, active)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(active.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.2"))
+		Expect(active.Spec.Selector.MatchLabels["app.kubernetes.io/instance"]).To(Equal("foo-web-green"))
+
+		candidate := &appsv1.Deployment{}
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-blue", Namespace: instance.Namespace}, candidate)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(candidate.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.3"))
+
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web", Namespace: instance.Namespace}, &appsv1.Deployment{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("after a promotion", func() {
+		var oldSlot *appsv1.Deployment
+		var oldAutoscaler *autoscalingv2beta1.HorizontalPodAutoscaler
+		var configMap *corev1.ConfigMap
+		var appSecrets *corev1.Secret
+
+		BeforeEach(func() {
+			instance.UID = "1234"
+			instance.Spec.WebReplicas = intp(1)
+			instance.Spec.Rollout.Strategy = summonv1beta1.RolloutBlueGreen
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.3"}
+
+			owner := metav1.NewControllerRef(instance, summonv1beta1.SchemeGroupVersion.WithKind("SummonPlatform"))
+			oldSlot = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-web-blue", Namespace: instance.Namespace, OwnerReferences: []metav1.OwnerReference{*owner}},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: intp(4),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app.kubernetes.io/instance": "foo-web-blue"}},
+					},
+				},
+			}
+			oldAutoscaler = &autoscalingv2beta1.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-web-blue", Namespace: instance.Namespace},
+			}
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+			}
+			appSecrets = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+			}
+		})
+
+		It("deletes the old slot and its autoscaler", func() {
+			comp := summoncomponents.NewRolloutDeployment("web/deployment.yml.tpl")
+			ctx.Client = fake.NewFakeClient(appSecrets, configMap, oldSlot, oldAutoscaler)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-green", Namespace: instance.Namespace}, &appsv1.Deployment{})
+			Expect(err).ToNot(HaveOccurred())
+			err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-blue", Namespace: instance.Namespace}, &appsv1.Deployment{})
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+			err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-blue", Namespace: instance.Namespace}, &autoscalingv2beta1.HorizontalPodAutoscaler{})
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("keeps the old slot while a Service still sends it traffic", func() {
+			comp := summoncomponents.NewRolloutDeployment("web/deployment.yml.tpl")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-web", Namespace: instance.Namespace},
+				Spec:       corev1.ServiceSpec{Selector: map[string]string{"app.kubernetes.io/instance": "foo-web-blue"}},
+			}
+			ctx.Client = fake.NewFakeClient(appSecrets, configMap, oldSlot, oldAutoscaler, service)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-blue", Namespace: instance.Namespace}, &appsv1.Deployment{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("leaves Deployments it doesn't own alone", func() {
+			comp := summoncomponents.NewRolloutDeployment("web/deployment.yml.tpl")
+			oldSlot.OwnerReferences = nil
+			ctx.Client = fake.NewFakeClient(appSecrets, configMap, oldSlot)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-blue", Namespace: instance.Namespace}, &appsv1.Deployment{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	It("starts the blue slot next to the single Deployment when switching over", func() {
+		comp := summoncomponents.NewRolloutDeployment("web/deployment.yml.tpl")
+		instance.Spec.WebReplicas = intp(1)
+		instance.Spec.Rollout.Strategy = summonv1beta1.RolloutBlueGreen
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveVersion: "1.2.2"}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		for _, name := range []string{"foo-web", "foo-web-blue"} {
+			deployment := &appsv1.Deployment{}
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: instance.Namespace}, deployment)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.2"))
+		}
+	})
+
+	It("starts the single Deployment next to the active slot when switching back", func() {
+		comp := summoncomponents.NewRolloutDeployment("web/deployment.yml.tpl")
+		instance.Spec.WebReplicas = intp(1)
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.2"}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("summon.%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		active := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-green", Namespace: instance.Namespace}, active)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(active.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.2"))
+
+		single := &appsv1.Deployment{}
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web", Namespace: instance.Namespace}, single)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(single.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.3"))
+	})
 })
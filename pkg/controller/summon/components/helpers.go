@@ -23,6 +23,24 @@ import (
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
+// Names of the outputs summon components provide to each other, see components.DependencyDeclarer.
+const (
+	outputDefaults           = "defaults"
+	outputPullSecret         = "pull-secret"
+	outputPostgres           = "postgres"
+	outputPostgresExtensions = "postgres-extensions"
+	outputIAMUser            = "iam-user"
+	outputS3Bucket           = "s3-bucket"
+	outputSecretKey          = "secret-key"
+	outputFernetKeys         = "fernet-keys"
+	outputAppSecrets         = "app-secrets"
+	outputConfigMap          = "configmap"
+	outputPreMigrateHooks    = "pre-migrate-hooks"
+	outputMigrations         = "migrations"
+	outputPostMigrateHooks   = "post-migrate-hooks"
+	outputRollout            = "rollout"
+)
+
 // Helper function for use as a StatusModifier which just sets the main status.
 func setStatus(status string) components.StatusModifier {
 	return func(obj runtime.Object) error {
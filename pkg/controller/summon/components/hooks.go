@@ -0,0 +1,269 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+type hooksComponent struct {
+	templatePath string
+	phase        summonv1beta1.HookPhase
+}
+
+// Run the hooks for one phase, one Job at a time in spec order. PreMigrate hooks hold up the migrations
+// and PostMigrate hooks hold up the Deployments, see NewPostDeployHooks for the last phase.
+func NewHooks(templatePath string, phase summonv1beta1.HookPhase) *hooksComponent {
+	return &hooksComponent{templatePath: templatePath, phase: phase}
+}
+
+// Name the component after its phase, there is one of these per phase.
+func (comp *hooksComponent) String() string {
+	return fmt.Sprintf("hooks(%s)", comp.phase)
+}
+
+func (comp *hooksComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{
+		&batchv1.Job{},
+	}
+}
+
+func (_ *hooksComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the database and app config (and migrations after that) via Dependencies.
+	return true
+}
+
+func (comp *hooksComponent) Dependencies() []string {
+	deps := []string{outputPullSecret, outputPostgres, outputPostgresExtensions, outputAppSecrets, outputConfigMap}
+	if comp.phase == summonv1beta1.HookPostMigrate {
+		deps = append(deps, outputMigrations)
+	}
+	return deps
+}
+
+func (comp *hooksComponent) Outputs() []components.Output {
+	switch comp.phase {
+	case summonv1beta1.HookPreMigrate:
+		return []components.Output{{Name: outputPreMigrateHooks, Ready: comp.ready}}
+	case summonv1beta1.HookPostMigrate:
+		return []components.Output{{Name: outputPostMigrateHooks, Ready: comp.ready}}
+	}
+	return nil
+}
+
+func (comp *hooksComponent) ready(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	if comp.skipped(instance) {
+		return true
+	}
+	version := comp.version(instance)
+	for _, hook := range instance.Spec.Hooks {
+		if hook.Phase == comp.phase && !hookDone(instance, hook, version) {
+			return false
+		}
+	}
+	return true
+}
+
+// PreMigrate hooks have nothing left to do once the migrations they were guarding have run.
+func (comp *hooksComponent) skipped(instance *summonv1beta1.SummonPlatform) bool {
+	return comp.phase == summonv1beta1.HookPreMigrate && (instance.Status.MigrateVersion == instance.Spec.Version || rolledBack(instance))
+}
+
+// The version hooks in this phase run for. PreMigrate hooks only ever see the new version, the later
+// phases follow the Deployments back to the last good version while rolled back.
+func (comp *hooksComponent) version(instance *summonv1beta1.SummonPlatform) string {
+	if comp.phase == summonv1beta1.HookPreMigrate {
+		return instance.Spec.Version
+	}
+	return servingVersion(instance)
+}
+
+func (comp *hooksComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	if comp.skipped(instance) {
+		return components.Result{}, nil
+	}
+	version := comp.version(instance)
+	for _, hook := range instance.Spec.Hooks {
+		if hook.Phase != comp.phase || hookDone(instance, hook, version) {
+			continue
+		}
+		// Only one hook at a time, the next one starts once this one is done.
+		return comp.reconcileHook(ctx, hook, version)
+	}
+	return components.Result{}, nil
+}
+
+func (comp *hooksComponent) reconcileHook(ctx *components.ComponentContext, hook summonv1beta1.HookSpec, version string) (components.Result, error) {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	obj, err := versionContext(ctx, version).GetTemplate(comp.templatePath, map[string]interface{}{"hook": hook})
+	if err != nil {
+		return components.Result{}, err
+	}
+	job := obj.(*batchv1.Job)
+
+	existing := &batchv1.Job{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, existing)
+	if err != nil && kerrors.IsNotFound(err) {
+		glog.Infof("Creating hook Job %s/%s\n", job.Namespace, job.Name)
+		err = controllerutil.SetControllerReference(instance, job, ctx.Scheme)
+		if err != nil {
+			return components.Result{}, err
+		}
+		err = ctx.Create(ctx.Context, job)
+		if err != nil {
+			return components.Result{Requeue: true}, errors.Wrapf(err, "hooks: error creating hook job %s/%s, might have lost the race condition", job.Namespace, job.Name)
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "HookJobCreated", "Created job %s for hook %s on version %s", job.Name, hook.Name, version)
+		return components.Result{}, nil
+	} else if err != nil {
+		return components.Result{}, err
+	}
+
+	// Only a job for the version we want counts, anything else is left over from an earlier deploy.
+	existingVersion, ok := existing.Labels["app.kubernetes.io/version"]
+	if !ok || existingVersion != version {
+		glog.Infof("[%s/%s] hooks: Found existing hook job %s with bad version %#v\n", instance.Namespace, instance.Name, existing.Name, existingVersion)
+		err = ctx.Delete(ctx.Context, existing, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		return components.Result{Requeue: true}, errors.Wrapf(err, "hooks: found existing hook job %s/%s with bad version %#v", existing.Namespace, existing.Name, existingVersion)
+	}
+
+	status := hookStatus(instance, hook.Name)
+	if existing.Status.Succeeded > 0 {
+		glog.V(2).Infof("[%s/%s] Deleting hook Job %s/%s\n", instance.Namespace, instance.Name, existing.Namespace, existing.Name)
+		err = ctx.Delete(ctx.Context, existing, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil {
+			return components.Result{Requeue: true}, errors.Wrapf(err, "hooks: error deleting successful hook job %s/%s", existing.Namespace, existing.Name)
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "HookSucceeded", "Hook %s for version %s succeeded", hook.Name, version)
+		status.Version = version
+		return components.Result{StatusModifier: setHookStatus(status)}, nil
+	}
+
+	if existing.Status.Failed > 0 {
+		// Leave the job for debugging, deleting it runs the hook again.
+		alreadyFailed := status.FailedVersion == version
+		if !alreadyFailed {
+			glog.Errorf("[%s/%s] Hook job failed, leaving job %s/%s for debugging purposes\n", instance.Namespace, instance.Name, existing.Namespace, existing.Name)
+			ctx.Eventf(corev1.EventTypeWarning, "HookFailed", "Job %s for hook %s on version %s failed", existing.Name, hook.Name, version)
+		}
+		status.FailedVersion = version
+		status.JobLogs = fmt.Sprintf("kubectl logs --namespace %s job/%s", existing.Namespace, existing.Name)
+		res := components.Result{StatusModifier: setHookStatus(status)}
+		if hook.FailurePolicy == summonv1beta1.HookFailureIgnore {
+			// The notification component reports it, carry on with the deploy.
+			return res, nil
+		}
+		if comp.phase == summonv1beta1.HookPostDeploy && alreadyFailed {
+			// Already reported as an error for this version. The deploy itself is up, so don't keep flipping the
+			// status back to Error on every pass, the failure stays recorded in the hook status.
+			return res, nil
+		}
+		return res, errors.Errorf("hooks: hook job %s/%s failed", existing.Namespace, existing.Name)
+	}
+
+	// Job is still running, will get reconciled when it finishes.
+	return components.Result{}, nil
+}
+
+type postDeployHooksComponent struct {
+	hooks *hooksComponent
+}
+
+// Run the PostDeploy hooks once the status component has marked the deploy Ready. Like the status
+// component this doesn't declare dependencies, so it runs after everything listed before it.
+func NewPostDeployHooks(templatePath string) *postDeployHooksComponent {
+	return &postDeployHooksComponent{hooks: NewHooks(templatePath, summonv1beta1.HookPostDeploy)}
+}
+
+func (comp *postDeployHooksComponent) String() string {
+	return comp.hooks.String()
+}
+
+func (comp *postDeployHooksComponent) WatchTypes() []runtime.Object {
+	return comp.hooks.WatchTypes()
+}
+
+func (_ *postDeployHooksComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Always reconcilable so a deploy in progress doesn't hold up the pruner.
+	return true
+}
+
+func (comp *postDeployHooksComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	if instance.Status.Status != summonv1beta1.StatusReady {
+		return components.Result{}, nil
+	}
+	return comp.hooks.Reconcile(ctx)
+}
+
+// A hook is done for a version once it succeeded, or failed with the Ignore policy.
+func hookDone(instance *summonv1beta1.SummonPlatform, hook summonv1beta1.HookSpec, version string) bool {
+	status := hookStatus(instance, hook.Name)
+	if status.Version == version {
+		return true
+	}
+	return hook.FailurePolicy == summonv1beta1.HookFailureIgnore && status.FailedVersion == version
+}
+
+func hookStatus(instance *summonv1beta1.SummonPlatform, name string) summonv1beta1.HookStatus {
+	for _, status := range instance.Status.Hooks {
+		if status.Name == name {
+			return status
+		}
+	}
+	return summonv1beta1.HookStatus{Name: name}
+}
+
+// Replace the status for one hook, dropping any for hooks no longer in the spec.
+func putHookStatus(instance *summonv1beta1.SummonPlatform, status summonv1beta1.HookStatus) {
+	hooks := []summonv1beta1.HookStatus{}
+	for _, hook := range instance.Spec.Hooks {
+		if hook.Name == status.Name {
+			hooks = append(hooks, status)
+			continue
+		}
+		for _, existing := range instance.Status.Hooks {
+			if existing.Name == hook.Name {
+				hooks = append(hooks, existing)
+			}
+		}
+	}
+	instance.Status.Hooks = hooks
+}
+
+func setHookStatus(status summonv1beta1.HookStatus) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		putHookStatus(obj.(*summonv1beta1.SummonPlatform), status)
+		return nil
+	}
+}
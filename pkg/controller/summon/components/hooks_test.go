@@ -0,0 +1,188 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("SummonPlatform Hooks Component", func() {
+	BeforeEach(func() {
+		instance.Spec.Hooks = []summonv1beta1.HookSpec{
+			{Name: "backup", Phase: summonv1beta1.HookPreMigrate, Command: "python manage.py backup", FailurePolicy: summonv1beta1.HookFailureFail},
+			{Name: "warmup", Phase: summonv1beta1.HookPostDeploy, Command: "python manage.py warmup", FailurePolicy: summonv1beta1.HookFailureIgnore},
+		}
+	})
+
+	getJob := func(name string) (*batchv1.Job, error) {
+		job := &batchv1.Job{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, job)
+		return job, err
+	}
+
+	hookJob := func(name string, status batchv1.JobStatus) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{"app.kubernetes.io/version": "1.2.3"},
+			},
+			Status: status,
+		}
+	}
+
+	Describe(".Dependencies()", func() {
+		It("waits for migrations after migrating", func() {
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPostMigrate)
+			Expect(comp.Dependencies()).To(ContainElement("migrations"))
+		})
+
+		It("doesn't wait for migrations before migrating", func() {
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			Expect(comp.Dependencies()).ToNot(ContainElement("migrations"))
+		})
+	})
+
+	Describe(".Outputs()", func() {
+		It("is not ready with a hook left to run", func() {
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			outputs := comp.Outputs()
+			Expect(outputs).To(HaveLen(1))
+			Expect(outputs[0].Name).To(Equal("pre-migrate-hooks"))
+			Expect(outputs[0].Ready(ctx)).To(BeFalse())
+		})
+
+		It("is ready once the hook succeeded for this version", func() {
+			instance.Status.Hooks = []summonv1beta1.HookStatus{{Name: "backup", Version: "1.2.3"}}
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			Expect(comp.Outputs()[0].Ready(ctx)).To(BeTrue())
+		})
+
+		It("is ready once migrations are applied", func() {
+			instance.Status.MigrateVersion = "1.2.3"
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			Expect(comp.Outputs()[0].Ready(ctx)).To(BeTrue())
+		})
+
+		It("is ready with no hooks in its phase", func() {
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPostMigrate)
+			Expect(comp.Outputs()[0].Name).To(Equal("post-migrate-hooks"))
+			Expect(comp.Outputs()[0].Ready(ctx)).To(BeTrue())
+		})
+	})
+
+	Describe(".Reconcile()", func() {
+		It("creates a job for the first hook", func() {
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			job, err := getJob("foo-hook-backup")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(job.Spec.Template.Spec.Containers[0].Command).To(ContainElement("python manage.py backup"))
+			events := ctx.Recorder.(*record.FakeRecorder).Events
+			Expect(events).To(Receive(Equal("Normal HookJobCreated Created job foo-hook-backup for hook backup on version 1.2.3")))
+		})
+
+		It("uses the hooks overrides rather than the migrations ones", func() {
+			instance.Spec.Overrides.Migrations = summonv1beta1.PodOverrides{NodeSelector: map[string]string{"pool": "migrations"}}
+			instance.Spec.Overrides.Hooks = summonv1beta1.PodOverrides{NodeSelector: map[string]string{"pool": "hooks"}}
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			job, err := getJob("foo-hook-backup")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"pool": "hooks"}))
+		})
+
+		It("records a successful hook and deletes the job", func() {
+			ctx.Client = fake.NewFakeClient(hookJob("foo-hook-backup", batchv1.JobStatus{Succeeded: 1}))
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			_, err := getJob("foo-hook-backup")
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+			Expect(instance.Status.Hooks).To(Equal([]summonv1beta1.HookStatus{{Name: "backup", Version: "1.2.3"}}))
+		})
+
+		It("fails the deploy when a Fail hook fails", func() {
+			ctx.Client = fake.NewFakeClient(hookJob("foo-hook-backup", batchv1.JobStatus{Failed: 1}))
+			comp := summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate)
+			res, err := comp.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(res.StatusModifier(instance)).To(Succeed())
+
+			_, err = getJob("foo-hook-backup")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Status.Hooks[0].FailedVersion).To(Equal("1.2.3"))
+			Expect(instance.Status.Hooks[0].JobLogs).To(Equal("kubectl logs --namespace default job/foo-hook-backup"))
+			Expect(comp.Outputs()[0].Ready(ctx)).To(BeFalse())
+		})
+
+		It("does nothing after deploying until the instance is ready", func() {
+			comp := summoncomponents.NewPostDeployHooks("hook.yml.tpl")
+			instance.Status.Status = summonv1beta1.StatusDeploying
+			Expect(comp).To(ReconcileContext(ctx))
+
+			_, err := getJob("foo-hook-warmup")
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("only errors once for a failed Fail hook after deploying", func() {
+			instance.Spec.Hooks[1].FailurePolicy = summonv1beta1.HookFailureFail
+			ctx.Client = fake.NewFakeClient(hookJob("foo-hook-warmup", batchv1.JobStatus{Failed: 1}))
+			comp := summoncomponents.NewPostDeployHooks("hook.yml.tpl")
+			instance.Status.Status = summonv1beta1.StatusReady
+			res, err := comp.Reconcile(ctx)
+			Expect(err).To(MatchError("hooks: hook job default/foo-hook-warmup failed"))
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(instance.Status.Hooks).To(Equal([]summonv1beta1.HookStatus{{Name: "warmup", FailedVersion: "1.2.3", JobLogs: "kubectl logs --namespace default job/foo-hook-warmup"}}))
+
+			// The failure is recorded, so the next pass leaves the status alone.
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Hooks[0].FailedVersion).To(Equal("1.2.3"))
+			_, err = getJob("foo-hook-warmup")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("carries on when an Ignore hook fails", func() {
+			ctx.Client = fake.NewFakeClient(hookJob("foo-hook-warmup", batchv1.JobStatus{Failed: 1}))
+			comp := summoncomponents.NewPostDeployHooks("hook.yml.tpl")
+			instance.Status.Status = summonv1beta1.StatusReady
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Hooks).To(Equal([]summonv1beta1.HookStatus{{Name: "warmup", FailedVersion: "1.2.3", JobLogs: "kubectl logs --namespace default job/foo-hook-warmup"}}))
+
+			// Nothing left to do for this version.
+			ctx.Client = fake.NewFakeClient()
+			Expect(comp).To(ReconcileContext(ctx))
+			_, err := getJob("foo-hook-warmup")
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
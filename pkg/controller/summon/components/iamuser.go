@@ -45,6 +45,16 @@ func (_ *iamUserComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	return true
 }
 
+func (_ *iamUserComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *iamUserComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputIAMUser},
+	}
+}
+
 func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 
 	permissionsBoundaryArn := os.Getenv("PERMISSIONS_BOUNDARY_ARN")
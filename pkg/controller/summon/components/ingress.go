@@ -17,6 +17,8 @@ limitations under the License.
 package components
 
 import (
+	"fmt"
+
 	extv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -31,6 +33,10 @@ func NewIngress(templatePath string) *ingressComponent {
 	return &ingressComponent{templatePath: templatePath}
 }
 
+func (comp *ingressComponent) String() string {
+	return fmt.Sprintf("ingress(%s)", comp.templatePath)
+}
+
 func (comp *ingressComponent) WatchTypes() []runtime.Object {
 	return []runtime.Object{
 		&extv1beta1.Ingress{},
@@ -41,13 +47,15 @@ func (_ *ingressComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	return true
 }
 
+func (_ *ingressComponent) Dependencies() []string {
+	return []string{outputDefaults, outputRollout}
+}
+
+func (_ *ingressComponent) Outputs() []components.Output {
+	return nil
+}
+
 func (comp *ingressComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, nil, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*extv1beta1.Ingress)
-		existing := existingObj.(*extv1beta1.Ingress)
-		// Copy the Spec over.
-		existing.Spec = goal.Spec
-		return nil
-	})
+	res, _, err := ctx.Apply(comp.templatePath, nil)
 	return res, err
 }
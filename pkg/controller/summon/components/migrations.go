@@ -17,10 +17,12 @@ limitations under the License.
 package components
 
 import (
+	"fmt"
+
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
-	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -28,7 +30,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
-	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
@@ -47,28 +48,36 @@ func (comp *migrationComponent) WatchTypes() []runtime.Object {
 	}
 }
 
-func (_ *migrationComponent) IsReconcilable(ctx *components.ComponentContext) bool {
-	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
-	if instance.Status.PostgresStatus != postgresv1.ClusterStatusRunning {
-		// Database not ready yet.
-		return false
-	}
-	if instance.Status.PostgresExtensionStatus != summonv1beta1.StatusReady {
-		// Extensions not installed yet.
-		return false
-	}
-	if instance.Status.PullSecretStatus != secretsv1beta1.StatusReady {
-		// Pull secret not ready yet.
-		return false
-	}
+func (_ *migrationComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the database, pull secret, app config and PreMigrate hooks via Dependencies.
 	return true
 }
 
+func (_ *migrationComponent) Dependencies() []string {
+	return []string{outputPullSecret, outputPostgres, outputPostgresExtensions, outputAppSecrets, outputConfigMap, outputPreMigrateHooks}
+}
+
+func (_ *migrationComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputMigrations, Ready: migrationsReady},
+	}
+}
+
+func migrationsReady(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	return instance.Status.MigrateVersion == instance.Spec.Version || rolledBack(instance)
+}
+
 func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 	if instance.Spec.Version == instance.Status.MigrateVersion {
 		// Already migrated, update status and move on.
-		return components.Result{StatusModifier: setStatus(summonv1beta1.StatusDeploying)}, nil
+		return components.Result{StatusModifier: setMigrationStatus(summonv1beta1.StatusDeploying, corev1.ConditionTrue, "MigrationsApplied", "")}, nil
+	}
+	if rolledBack(instance) {
+		// Gave up on this version, keep deploying the last good one until the spec moves on.
+		message := fmt.Sprintf("Migrations for %s failed, serving %s", instance.Spec.Version, instance.Status.MigrateVersion)
+		return components.Result{StatusModifier: setMigrationStatus(summonv1beta1.StatusDeploying, corev1.ConditionFalse, "RolledBack", message)}, nil
 	}
 
 	obj, err := ctx.GetTemplate(comp.templatePath, nil)
@@ -90,8 +99,9 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 			// If this fails, someone else might have started a migraton job between the Get and here, so just try again.
 			return components.Result{Requeue: true}, errors.Wrapf(err, "migrations: error creation migration job %s/%s, might have lost the race condition", job.Namespace, job.Name)
 		}
+		ctx.Eventf(corev1.EventTypeNormal, "MigrationJobCreated", "Created migration job %s for version %s", job.Name, instance.Spec.Version)
 		// Job is started, so we're done for now.
-		return components.Result{StatusModifier: setStatus(summonv1beta1.StatusMigrating)}, nil
+		return components.Result{StatusModifier: setMigrationStatus(summonv1beta1.StatusMigrating, corev1.ConditionFalse, "Migrating", "")}, nil
 	} else if err != nil {
 		// Some other real error, bail.
 		return components.Result{}, err
@@ -117,6 +127,7 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 		}
 
 		glog.Infof("[%s/%s] migrations: Migration job succeeded, updating MigrateVersion from %s to %s\n", instance.Namespace, instance.Name, instance.Status.MigrateVersion, instance.Spec.Version)
+		ctx.Eventf(corev1.EventTypeNormal, "MigrationsSucceeded", "Migrations for version %s succeeded", instance.Spec.Version)
 		// Store migrate version in the closure to avoid concurrent edits to Spec.Version resulting in incorrectly advancing MigrateVersion.
 		migrateVersion := instance.Spec.Version
 		// Onward to deploying!
@@ -124,6 +135,7 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 			instance := obj.(*summonv1beta1.SummonPlatform)
 			instance.Status.Status = summonv1beta1.StatusDeploying
 			instance.Status.MigrateVersion = migrateVersion
+			instance.SetCondition(summonv1beta1.ConditionMigrationsComplete, corev1.ConditionTrue, "MigrationsApplied", "")
 			return nil
 		}}, nil
 	}
@@ -131,10 +143,73 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 	// ... Or if the job failed.
 	if existing.Status.Failed > 0 {
 		// If it was an outdated job, we would have already deleted it, so this means it's a failed migration for the current version.
-		glog.Errorf("[%s/%s] Migration job failed, leaving job %s/%s for debugging purposes\n", instance.Namespace, instance.Name, existing.Namespace, existing.Name)
-		return components.Result{}, errors.Errorf("migrations: migration job %s/%s failed", existing.Namespace, existing.Name)
+		return comp.handleFailure(ctx, existing)
 	}
 
 	// Job is still running, will get reconciled when it finishes.
-	return components.Result{StatusModifier: setStatus(summonv1beta1.StatusMigrating)}, nil
+	return components.Result{StatusModifier: setMigrationStatus(summonv1beta1.StatusMigrating, corev1.ConditionFalse, "Migrating", "")}, nil
+}
+
+// Count a failed migration job and apply the rollback policy. Without a policy the job is left for
+// debugging and the error stands until someone fixes the version.
+func (comp *migrationComponent) handleFailure(ctx *components.ComponentContext, job *batchv1.Job) (components.Result, error) {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	failure := instance.Status.MigrationFailure
+	if failure.Version != instance.Spec.Version {
+		failure = summonv1beta1.MigrationFailureStatus{Version: instance.Spec.Version}
+	}
+	if failure.JobUID != string(job.UID) {
+		failure.Count++
+		failure.JobUID = string(job.UID)
+	}
+	failure.JobLogs = fmt.Sprintf("kubectl logs --namespace %s job/%s", job.Namespace, job.Name)
+	limit := instance.Spec.Rollback.MigrationFailures
+
+	if limit > 0 && failure.Count < limit {
+		// Try again with a fresh job.
+		glog.Errorf("[%s/%s] Migration job %s/%s failed (%d of %d), retrying\n", instance.Namespace, instance.Name, job.Namespace, job.Name, failure.Count, limit)
+		ctx.Eventf(corev1.EventTypeWarning, "MigrationsRetrying", "Migration job %s for version %s failed (%d of %d), retrying", job.Name, instance.Spec.Version, failure.Count, limit)
+		err := ctx.Delete(ctx.Context, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !kerrors.IsNotFound(err) {
+			return components.Result{Requeue: true}, errors.Wrapf(err, "migrations: error deleting failed migration job %s/%s", job.Namespace, job.Name)
+		}
+		message := fmt.Sprintf("Migration job %s failed %d of %d times", job.Name, failure.Count, limit)
+		return components.Result{Requeue: true, StatusModifier: setMigrationFailure(failure, summonv1beta1.StatusMigrating, "MigrationsRetrying", message)}, nil
+	}
+
+	glog.Errorf("[%s/%s] Migration job failed, leaving job %s/%s for debugging purposes\n", instance.Namespace, instance.Name, job.Namespace, job.Name)
+	ctx.Eventf(corev1.EventTypeWarning, "MigrationsFailed", "Migration job %s for version %s failed", job.Name, instance.Spec.Version)
+	if limit > 0 && instance.Status.MigrateVersion != "" {
+		// Out of attempts, go back to the last good version. The notification component reports it.
+		failure.RolledBack = true
+		ctx.Eventf(corev1.EventTypeWarning, "RolledBack", "Migrations for version %s failed %d times, rolling back to %s", instance.Spec.Version, failure.Count, instance.Status.MigrateVersion)
+		message := fmt.Sprintf("Migrations for %s failed, serving %s", instance.Spec.Version, instance.Status.MigrateVersion)
+		return components.Result{StatusModifier: setMigrationFailure(failure, summonv1beta1.StatusDeploying, "RolledBack", message)}, nil
+	}
+
+	res := components.Result{StatusModifier: setMigrationFailure(failure, "", "MigrationsFailed", fmt.Sprintf("Migration job %s failed", job.Name))}
+	return res, errors.Errorf("migrations: migration job %s/%s failed", job.Namespace, job.Name)
+}
+
+// Record a failed migration, along with the overall status (if not empty) and the MigrationsComplete condition.
+func setMigrationFailure(failure summonv1beta1.MigrationFailureStatus, status string, reason, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.MigrationFailure = failure
+		if status != "" {
+			instance.Status.Status = status
+		}
+		instance.SetCondition(summonv1beta1.ConditionMigrationsComplete, corev1.ConditionFalse, reason, message)
+		return nil
+	}
+}
+
+// Set both the overall status and the MigrationsComplete condition.
+func setMigrationStatus(status string, conditionStatus corev1.ConditionStatus, reason, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.Status = status
+		instance.SetCondition(summonv1beta1.ConditionMigrationsComplete, conditionStatus, reason, message)
+		return nil
+	}
 }
@@ -21,79 +21,58 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
-	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 )
 
 var _ = Describe("SummonPlatform Migrations Component", func() {
-	Describe(".IsReconcilable()", func() {
-		Context("with a zero status", func() {
-			It("returns false", func() {
-				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
-				ok := comp.IsReconcilable(ctx)
-				Expect(ok).To(BeFalse())
-			})
+	Describe(".Dependencies()", func() {
+		It("waits for the database, pull secret, app config and PreMigrate hooks", func() {
+			comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+			Expect(comp.Dependencies()).To(ConsistOf("pull-secret", "postgres", "postgres-extensions", "app-secrets", "configmap", "pre-migrate-hooks"))
 		})
+	})
 
-		Context("with Postgres not ready", func() {
-			BeforeEach(func() {
-				instance.Status.PostgresStatus = postgresv1.ClusterStatusCreating
-			})
-
-			It("returns false", func() {
-				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
-				ok := comp.IsReconcilable(ctx)
-				Expect(ok).To(BeFalse())
-			})
-		})
-
-		Context("with Postgres ready", func() {
-			BeforeEach(func() {
-				instance.Status.PostgresStatus = postgresv1.ClusterStatusRunning
-			})
-
-			It("returns false", func() {
+	Describe(".Outputs()", func() {
+		Context("with a zero status", func() {
+			It("is not ready", func() {
 				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
-				ok := comp.IsReconcilable(ctx)
-				Expect(ok).To(BeFalse())
+				outputs := comp.Outputs()
+				Expect(outputs).To(HaveLen(1))
+				Expect(outputs[0].Name).To(Equal("migrations"))
+				Expect(outputs[0].Ready(ctx)).To(BeFalse())
 			})
 		})
 
-		Context("with Postgres and pull secret ready", func() {
+		Context("with migrations for an old version applied", func() {
 			BeforeEach(func() {
-				instance.Status.PostgresStatus = postgresv1.ClusterStatusRunning
-				instance.Status.PostgresExtensionStatus = summonv1beta1.StatusReady
-				instance.Status.PullSecretStatus = secretsv1beta1.StatusReady
+				instance.Status.MigrateVersion = "1.2.2"
 			})
 
-			It("returns true", func() {
+			It("is not ready", func() {
 				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
-				ok := comp.IsReconcilable(ctx)
-				Expect(ok).To(BeTrue())
+				Expect(comp.Outputs()[0].Ready(ctx)).To(BeFalse())
 			})
 		})
 
 		Context("with migrations already applied", func() {
 			BeforeEach(func() {
-				instance.Status.PostgresStatus = postgresv1.ClusterStatusRunning
-				instance.Status.PostgresExtensionStatus = summonv1beta1.StatusReady
-				instance.Status.PullSecretStatus = secretsv1beta1.StatusReady
 				instance.Status.MigrateVersion = "1.2.3"
 			})
 
-			It("returns true", func() {
+			It("is ready", func() {
 				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
-				ok := comp.IsReconcilable(ctx)
-				Expect(ok).To(BeTrue())
+				Expect(comp.Outputs()[0].Ready(ctx)).To(BeTrue())
 			})
 		})
 	})
@@ -108,6 +87,29 @@ var _ = Describe("SummonPlatform Migrations Component", func() {
 				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-migrations", Namespace: "default"}, job)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(instance.Status.MigrateVersion).To(Equal(""))
+				events := ctx.Recorder.(*record.FakeRecorder).Events
+				Expect(events).To(Receive(Equal("Normal MigrationJobCreated Created migration job foo-migrations for version 1.2.3")))
+			})
+		})
+
+		Context("with migrations overrides", func() {
+			BeforeEach(func() {
+				instance.Spec.Overrides.Migrations = summonv1beta1.PodOverrides{
+					NodeSelector: map[string]string{"pool": "jobs"},
+					Env:          []corev1.EnvVar{{Name: "DJANGO_MIGRATE_VERBOSE", Value: "1"}},
+				}
+			})
+
+			It("applies them to the job", func() {
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				Expect(comp).To(ReconcileContext(ctx))
+
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-migrations", Namespace: "default"}, job)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"pool": "jobs"}))
+				Expect(job.Spec.Template.Spec.Containers[0].Env).To(HaveLen(1))
+				Expect(job.Spec.Template.Spec.Containers[0].Resources.Requests.Memory().String()).To(Equal("1G"))
 			})
 		})
 
@@ -159,6 +161,7 @@ var _ = Describe("SummonPlatform Migrations Component", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(jobs.Items).To(BeEmpty())
 				Expect(instance.Status.MigrateVersion).To(Equal("1.2.3"))
+				Expect(helpers.IsConditionTrue(instance.Status.Conditions, summonv1beta1.ConditionMigrationsComplete)).To(BeTrue())
 			})
 		})
 
@@ -186,6 +189,117 @@ var _ = Describe("SummonPlatform Migrations Component", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(instance.Status.MigrateVersion).To(Equal(""))
 			})
+
+			It("sets the MigrationsComplete condition to false", func() {
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				res, err := comp.Reconcile(ctx)
+				Expect(err).To(HaveOccurred())
+				Expect(res.StatusModifier(instance)).To(Succeed())
+				condition := helpers.GetCondition(instance.Status.Conditions, summonv1beta1.ConditionMigrationsComplete)
+				Expect(condition).ToNot(BeNil())
+				Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("MigrationsFailed"))
+			})
+		})
+
+		Context("with a failed migration job and a rollback policy", func() {
+			BeforeEach(func() {
+				instance.Spec.Rollback.MigrationFailures = 2
+				instance.Status.MigrateVersion = "1.2.2"
+				job := &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-migrations",
+						Namespace: "default",
+						UID:       "job1",
+						Labels:    map[string]string{"app.kubernetes.io/version": "1.2.3"},
+					},
+					Status: batchv1.JobStatus{
+						Failed: 1,
+					},
+				}
+				ctx.Client = fake.NewFakeClient(job)
+			})
+
+			It("deletes the job to retry", func() {
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				res, err := comp.Reconcile(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.Requeue).To(BeTrue())
+				Expect(res.StatusModifier(instance)).To(Succeed())
+				Expect(instance.Status.MigrationFailure.Version).To(Equal("1.2.3"))
+				Expect(instance.Status.MigrationFailure.Count).To(Equal(int32(1)))
+				Expect(instance.Status.MigrationFailure.RolledBack).To(BeFalse())
+
+				job := &batchv1.Job{}
+				err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-migrations", Namespace: "default"}, job)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("rolls back once out of attempts", func() {
+				instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1.2.3", Count: 1, JobUID: "job0"}
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				res, err := comp.Reconcile(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.StatusModifier(instance)).To(Succeed())
+				Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+				Expect(instance.Status.MigrateVersion).To(Equal("1.2.2"))
+				Expect(instance.Status.MigrationFailure.Count).To(Equal(int32(2)))
+				Expect(instance.Status.MigrationFailure.RolledBack).To(BeTrue())
+				Expect(instance.Status.MigrationFailure.JobLogs).To(Equal("kubectl logs --namespace default job/foo-migrations"))
+				Expect(comp.Outputs()[0].Ready(ctx)).To(BeTrue())
+
+				// The failed job is kept for its logs.
+				job := &batchv1.Job{}
+				err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-migrations", Namespace: "default"}, job)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("does not count the same job twice", func() {
+				instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1.2.3", Count: 1, JobUID: "job1"}
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				res, err := comp.Reconcile(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.StatusModifier(instance)).To(Succeed())
+				Expect(instance.Status.MigrationFailure.Count).To(Equal(int32(1)))
+			})
+
+			It("does not roll back without a good version", func() {
+				instance.Status.MigrateVersion = ""
+				instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1.2.3", Count: 1, JobUID: "job0"}
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				Expect(comp).NotTo(ReconcileContext(ctx))
+				Expect(instance.Status.MigrationFailure.RolledBack).To(BeFalse())
+			})
+		})
+
+		Context("when rolled back", func() {
+			BeforeEach(func() {
+				instance.Spec.Rollback.MigrationFailures = 2
+				instance.Status.MigrateVersion = "1.2.2"
+				instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1.2.3", Count: 2, RolledBack: true}
+			})
+
+			It("does not start a new job", func() {
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				Expect(comp).To(ReconcileContext(ctx))
+
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-migrations", Namespace: "default"}, job)
+				Expect(err).To(HaveOccurred())
+				Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+			})
+
+			It("tries again for a new version", func() {
+				instance.Spec.Version = "1.2.4"
+				comp := summoncomponents.NewMigrations("migrations.yml.tpl")
+				Expect(comp.Outputs()[0].Ready(ctx)).To(BeFalse())
+				Expect(comp).To(ReconcileContext(ctx))
+
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-migrations", Namespace: "default"}, job)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(job.Labels["app.kubernetes.io/version"]).To(Equal("1.2.4"))
+			})
 		})
 
 		Context("with a failed migration job from a previous version", func() {
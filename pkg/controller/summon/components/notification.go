@@ -19,22 +19,16 @@ package components
 import (
 	"fmt"
 	"os"
-	"regexp"
 	"sync"
 
 	"github.com/nlopes/slack"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
-var versionRegex *regexp.Regexp
-
-func init() {
-	versionRegex = regexp.MustCompile(`^(\d+)-([0-9a-fA-F]+)-(\S+)$`)
-}
-
 // Interface for a Slack client to allow for a mock implementation.
 //go:generate moq -out zz_generated.mock_slackclient_test.go . SlackClient
 type SlackClient interface {
@@ -84,10 +78,19 @@ func (_ *notificationComponent) IsReconcilable(ctx *components.ComponentContext)
 func (c *notificationComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
-	if instance.Status.Status == summonv1beta1.StatusReady {
-		return c.handleSuccess(instance)
+	// Hooks which failed without stopping the deploy don't show up anywhere else.
+	res, err := c.handleHookFailures(ctx, instance)
+	if err != nil || res.StatusModifier != nil {
+		return res, err
+	}
+
+	if rolledBack(instance) {
+		// Rolled back versions never get a deploy notification, only this one.
+		return c.handleRollback(ctx, instance)
+	} else if instance.Status.Status == summonv1beta1.StatusReady {
+		return c.handleSuccess(ctx, instance)
 	} else if instance.Status.Status == summonv1beta1.StatusError {
-		return c.handleError(instance, instance.Status.Message)
+		return c.handleError(ctx, instance, instance.Status.Message)
 	}
 
 	// No notifications needed.
@@ -97,11 +100,11 @@ func (c *notificationComponent) Reconcile(ctx *components.ComponentContext) (com
 // ReconcileError implements components.ErrorHandler.
 func (c *notificationComponent) ReconcileError(ctx *components.ComponentContext, err error) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
-	return c.handleError(instance, fmt.Sprintf("%s", err))
+	return c.handleError(ctx, instance, fmt.Sprintf("%s", err))
 }
 
 // Send a deploy notification if needed.
-func (c *notificationComponent) handleSuccess(instance *summonv1beta1.SummonPlatform) (components.Result, error) {
+func (c *notificationComponent) handleSuccess(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform) (components.Result, error) {
 	if instance.Spec.Version == instance.Status.Notification.NotifyVersion {
 		// Already notified about this version, we're good.
 		return components.Result{}, nil
@@ -116,8 +119,13 @@ func (c *notificationComponent) handleSuccess(instance *summonv1beta1.SummonPlat
 
 	// Send to Slack.
 	attachment := c.formatSuccessNotification(instance)
+	if ctx.IsDryRun() {
+		ctx.RecordDryRun("create", "slack:Message", instance.Spec.Notifications.SlackChannel, attachment.Fallback)
+		return components.Result{}, nil
+	}
 	_, _, err := c.slackClient.PostMessage(instance.Spec.Notifications.SlackChannel, attachment)
 	if err != nil {
+		ctx.Eventf(corev1.EventTypeWarning, "NotificationFailed", "Unable to send deploy notification to %s: %s", instance.Spec.Notifications.SlackChannel, err)
 		return components.Result{}, err
 	}
 
@@ -132,7 +140,7 @@ func (c *notificationComponent) handleSuccess(instance *summonv1beta1.SummonPlat
 }
 
 // Send an error notification if needed.
-func (c *notificationComponent) handleError(instance *summonv1beta1.SummonPlatform, errorMessage string) (components.Result, error) {
+func (c *notificationComponent) handleError(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform, errorMessage string) (components.Result, error) {
 	// Check if this is a duplicate message.
 	dupCacheKey := fmt.Sprintf("%s/%s", instance.Namespace, instance.Name)
 	lastdupCacheValue, ok := c.dupCache.Load(dupCacheKey)
@@ -143,8 +151,13 @@ func (c *notificationComponent) handleError(instance *summonv1beta1.SummonPlatfo
 
 	// Send to Slack.
 	attachment := c.formatErrorNotification(instance, errorMessage)
+	if ctx.IsDryRun() {
+		ctx.RecordDryRun("create", "slack:Message", instance.Spec.Notifications.SlackChannel, attachment.Fallback)
+		return components.Result{}, nil
+	}
 	_, _, err := c.slackClient.PostMessage(instance.Spec.Notifications.SlackChannel, attachment)
 	if err != nil {
+		ctx.Eventf(corev1.EventTypeWarning, "NotificationFailed", "Unable to send error notification to %s: %s", instance.Spec.Notifications.SlackChannel, err)
 		return components.Result{}, err
 	}
 
@@ -153,11 +166,77 @@ func (c *notificationComponent) handleError(instance *summonv1beta1.SummonPlatfo
 	return components.Result{}, nil
 }
 
+// Send a rollback notification if needed.
+func (c *notificationComponent) handleRollback(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform) (components.Result, error) {
+	if instance.Spec.Version == instance.Status.Notification.RollbackVersion {
+		// Already notified about this rollback.
+		return components.Result{}, nil
+	}
+
+	// Send to Slack.
+	attachment := c.formatRollbackNotification(instance)
+	if ctx.IsDryRun() {
+		ctx.RecordDryRun("create", "slack:Message", instance.Spec.Notifications.SlackChannel, attachment.Fallback)
+		return components.Result{}, nil
+	}
+	_, _, err := c.slackClient.PostMessage(instance.Spec.Notifications.SlackChannel, attachment)
+	if err != nil {
+		ctx.Eventf(corev1.EventTypeWarning, "NotificationFailed", "Unable to send rollback notification to %s: %s", instance.Spec.Notifications.SlackChannel, err)
+		return components.Result{}, err
+	}
+
+	// Update status. Close over `version` in case it changes during a collision.
+	version := instance.Spec.Version
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.Notification.RollbackVersion = version
+		return nil
+	}}, nil
+}
+
+// Send a notification for the first Ignore hook with a failure we haven't reported yet.
+func (c *notificationComponent) handleHookFailures(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform) (components.Result, error) {
+	for _, hook := range instance.Spec.Hooks {
+		if hook.FailurePolicy != summonv1beta1.HookFailureIgnore {
+			// Anything else fails the deploy and goes out as an error notification.
+			continue
+		}
+		status := hookStatus(instance, hook.Name)
+		if status.FailedVersion == "" || status.FailedVersion == status.NotifiedVersion {
+			continue
+		}
+
+		// Send to Slack.
+		attachment := c.formatHookFailureNotification(instance, status)
+		if ctx.IsDryRun() {
+			ctx.RecordDryRun("create", "slack:Message", instance.Spec.Notifications.SlackChannel, attachment.Fallback)
+			return components.Result{}, nil
+		}
+		_, _, err := c.slackClient.PostMessage(instance.Spec.Notifications.SlackChannel, attachment)
+		if err != nil {
+			ctx.Eventf(corev1.EventTypeWarning, "NotificationFailed", "Unable to send hook failure notification to %s: %s", instance.Spec.Notifications.SlackChannel, err)
+			return components.Result{}, err
+		}
+
+		// Update status. Close over `name` and `version` in case they change during a collision.
+		name := hook.Name
+		version := status.FailedVersion
+		return components.Result{StatusModifier: func(obj runtime.Object) error {
+			instance := obj.(*summonv1beta1.SummonPlatform)
+			status := hookStatus(instance, name)
+			status.NotifiedVersion = version
+			putHookStatus(instance, status)
+			return nil
+		}}, nil
+	}
+	return components.Result{}, nil
+}
+
 // Render the nofiication attachement for a deploy notification.
 func (comp *notificationComponent) formatSuccessNotification(instance *summonv1beta1.SummonPlatform) slack.Attachment {
 	fields := []slack.AttachmentField{}
 	// Try to parse the version string using our usual conventions.
-	matches := versionRegex.FindStringSubmatch(instance.Spec.Version)
+	matches := summonv1beta1.VersionRegex.FindStringSubmatch(instance.Spec.Version)
 	if matches != nil {
 		// Build fields for each thing.
 		buildField := slack.AttachmentField{
@@ -198,3 +277,32 @@ func (comp *notificationComponent) formatErrorNotification(instance *summonv1bet
 		Fallback:  fmt.Sprintf("%s has error: %s", instance.Spec.Hostname, errorMessage),
 	}
 }
+
+// Render the notification attachment for a rollback.
+func (comp *notificationComponent) formatRollbackNotification(instance *summonv1beta1.SummonPlatform) slack.Attachment {
+	failure := instance.Status.MigrationFailure
+	return slack.Attachment{
+		Title:     fmt.Sprintf("%s Deployment", instance.Spec.Hostname),
+		TitleLink: fmt.Sprintf("https://%s/", instance.Spec.Hostname),
+		Color:     "danger",
+		Text:      fmt.Sprintf("<https://%s/|%s> rolled back to version %s, migrations for %s failed %d times", instance.Spec.Hostname, instance.Spec.Hostname, instance.Status.MigrateVersion, instance.Spec.Version, failure.Count),
+		Fallback:  fmt.Sprintf("%s rolled back to version %s, migrations for %s failed %d times", instance.Spec.Hostname, instance.Status.MigrateVersion, instance.Spec.Version, failure.Count),
+		Fields: []slack.AttachmentField{
+			{Title: "Logs", Value: fmt.Sprintf("`%s`", failure.JobLogs)},
+		},
+	}
+}
+
+// Render the notification attachment for a hook which failed without stopping the deploy.
+func (comp *notificationComponent) formatHookFailureNotification(instance *summonv1beta1.SummonPlatform, status summonv1beta1.HookStatus) slack.Attachment {
+	return slack.Attachment{
+		Title:     fmt.Sprintf("%s Deployment", instance.Spec.Hostname),
+		TitleLink: fmt.Sprintf("https://%s/", instance.Spec.Hostname),
+		Color:     "warning",
+		Text:      fmt.Sprintf("<https://%s/|%s> hook %s failed for version %s, continuing the deploy", instance.Spec.Hostname, instance.Spec.Hostname, status.Name, status.FailedVersion),
+		Fallback:  fmt.Sprintf("%s hook %s failed for version %s, continuing the deploy", instance.Spec.Hostname, status.Name, status.FailedVersion),
+		Fields: []slack.AttachmentField{
+			{Title: "Logs", Value: fmt.Sprintf("`%s`", status.JobLogs)},
+		},
+	}
+}
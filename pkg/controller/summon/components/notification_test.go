@@ -22,8 +22,11 @@ import (
 	"github.com/nlopes/slack"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
 
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/controller/summon"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 )
@@ -119,6 +122,46 @@ var _ = Describe("SummonPlatform Notification Component", func() {
 			Expect(instance.Status.Notification.NotifyVersion).To(Equal("1234"))
 		})
 
+		It("sends a warning for an ignored hook failure", func() {
+			instance.Spec.Hooks = []summonv1beta1.HookSpec{
+				{Name: "warmup", Phase: summonv1beta1.HookPostDeploy, Command: "python manage.py warmup", FailurePolicy: summonv1beta1.HookFailureIgnore},
+			}
+			instance.Status.Hooks = []summonv1beta1.HookStatus{
+				{Name: "warmup", FailedVersion: "1.2.3", JobLogs: "kubectl logs --namespace default job/foo-hook-warmup"},
+			}
+			instance.Status.Status = summonv1beta1.StatusReady
+			instance.Status.Notification.NotifyVersion = "1.2.3"
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(1))
+			post := mockedSlackClient.PostMessageCalls()[0]
+			Expect(post.In2.Color).To(Equal("warning"))
+			Expect(post.In2.Fallback).To(Equal("foo.ridecell.us hook warmup failed for version 1.2.3, continuing the deploy"))
+			Expect(post.In2.Fields[0].Value).To(Equal("`kubectl logs --namespace default job/foo-hook-warmup`"))
+			Expect(instance.Status.Hooks[0].NotifiedVersion).To(Equal("1.2.3"))
+
+			// Only once.
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(1))
+		})
+
+		It("sends a rollback notification instead of a success one", func() {
+			instance.Spec.Version = "1235-eb6b515-master"
+			instance.Spec.Rollback.MigrationFailures = 2
+			instance.Status.MigrateVersion = "1234-eb6b515-master"
+			instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1235-eb6b515-master", Count: 2, JobLogs: "kubectl logs --namespace default job/foo-migrations", RolledBack: true}
+			instance.Status.Status = summonv1beta1.StatusReady
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(1))
+			post := mockedSlackClient.PostMessageCalls()[0]
+			Expect(post.In2.Fallback).To(Equal("foo.ridecell.us rolled back to version 1234-eb6b515-master, migrations for 1235-eb6b515-master failed 2 times"))
+			Expect(post.In2.Fields[0].Value).To(Equal("`kubectl logs --namespace default job/foo-migrations`"))
+			Expect(instance.Status.Notification.RollbackVersion).To(Equal("1235-eb6b515-master"))
+
+			// Only once.
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(1))
+		})
+
 		It("sends an error notification on a new error", func() {
 			instance.Status.Message = "Someone set us up the bomb"
 			instance.Status.Status = summonv1beta1.StatusError
@@ -146,6 +189,47 @@ var _ = Describe("SummonPlatform Notification Component", func() {
 			Expect(comp).To(ReconcileContext(ctx))
 			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(2))
 		})
+
+		It("only records notifications in a dry run", func() {
+			instance.Spec.Version = "1234-eb6b515-master"
+			instance.Status.Status = summonv1beta1.StatusReady
+			instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+			ctx = components.NewTestContext(instance, summon.Templates)
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(0))
+			Expect(instance.Status.Notification.NotifyVersion).To(Equal("1.2.3"))
+			changes := ctx.DryRunChanges()
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Operation).To(Equal("create"))
+			Expect(changes[0].Kind).To(Equal("slack:Message"))
+			Expect(changes[0].Name).To(Equal("#test-channel"))
+			Expect(changes[0].Diff).To(Equal("foo.ridecell.us deployed version 1234-eb6b515-master successfully"))
+		})
+
+		It("still sends an error after a dry run saw it", func() {
+			instance.Status.Message = "Someone set us up the bomb"
+			instance.Status.Status = summonv1beta1.StatusError
+			instance.Annotations = map[string]string{components.DryRunAnnotation: "true"}
+			ctx = components.NewTestContext(instance, summon.Templates)
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(0))
+
+			instance.Annotations = nil
+			ctx = components.NewTestContext(instance, summon.Templates)
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(1))
+		})
+
+		It("records an event if slack fails", func() {
+			mockedSlackClient.PostMessageFunc = func(_ string, _ slack.Attachment) (string, string, error) {
+				return "", "", fmt.Errorf("channel_not_found")
+			}
+			instance.Status.Message = "Someone set us up the bomb"
+			instance.Status.Status = summonv1beta1.StatusError
+			Expect(comp).ToNot(ReconcileContext(ctx))
+			events := ctx.Recorder.(*record.FakeRecorder).Events
+			Expect(events).To(Receive(Equal("Warning NotificationFailed Unable to send error notification to #test-channel: channel_not_found")))
+		})
 	})
 
 	Describe("ReconcileError", func() {
@@ -19,6 +19,7 @@ package components
 import (
 	"github.com/pkg/errors"
 	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -49,6 +50,21 @@ func (_ *postgresComponent) IsReconcilable(_ *components.ComponentContext) bool
 	return true
 }
 
+func (_ *postgresComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *postgresComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputPostgres, Ready: postgresReady},
+	}
+}
+
+func postgresReady(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	return instance.Status.PostgresStatus == postgresv1.ClusterStatusRunning
+}
+
 func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
@@ -65,7 +81,17 @@ func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (comp
 	setPostgresStatus := func(obj runtime.Object) error {
 		if db != nil {
 			// db can be nil if an API call fails.
+			instance := obj.(*summonv1beta1.SummonPlatform)
 			instance.Status.PostgresStatus = db.Status
+			if db.Status == postgresv1.ClusterStatusRunning {
+				instance.SetCondition(summonv1beta1.ConditionDatabaseReady, corev1.ConditionTrue, "Running", "")
+			} else {
+				reason := string(db.Status)
+				if reason == "" {
+					reason = "Unknown"
+				}
+				instance.SetCondition(summonv1beta1.ConditionDatabaseReady, corev1.ConditionFalse, reason, db.Error)
+			}
 		}
 		return nil
 	}
@@ -19,7 +19,6 @@ import (
 	"fmt"
 
 	"github.com/pkg/errors"
-	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
@@ -27,6 +26,17 @@ import (
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
+type postgresExtension struct {
+	ExtensionName string
+	ObjectName    string
+}
+
+// The extensions every SummonPlatform database gets, in the order errors are reported.
+var postgresExtensions = []postgresExtension{
+	{ExtensionName: "postgis", ObjectName: "postgis"},
+	{ExtensionName: "postgis_topology", ObjectName: "postgis-topology"},
+}
+
 type postgresExtensionsComponent struct{}
 
 func NewPostgresExtensions() *postgresExtensionsComponent {
@@ -39,65 +49,63 @@ func (comp *postgresExtensionsComponent) WatchTypes() []runtime.Object {
 	}
 }
 
-func (_ *postgresExtensionsComponent) IsReconcilable(ctx *components.ComponentContext) bool {
-	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
-	if instance.Status.PostgresStatus != postgresv1.ClusterStatusRunning {
-		// Database not ready yet.
-		return false
-	}
+func (_ *postgresExtensionsComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the database via Dependencies.
 	return true
 }
 
+func (_ *postgresExtensionsComponent) Dependencies() []string {
+	return []string{outputPostgres}
+}
+
+func (_ *postgresExtensionsComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputPostgresExtensions, Ready: postgresExtensionsReady},
+	}
+}
+
+func postgresExtensionsReady(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	return instance.Status.PostgresExtensionStatus == summonv1beta1.StatusReady
+}
+
 func (_ *postgresExtensionsComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
-	var existingPostgis *dbv1beta1.PostgresExtension
-	var existingPostgisToplogy *dbv1beta1.PostgresExtension
+	existingExtensions := map[string]*dbv1beta1.PostgresExtension{}
 
 	// Make the template extras.
 	extras := map[string]interface{}{}
-
-	// Create the postgis extension.
-	extras["ExtensionName"] = "postgis"
-	extras["ObjectName"] = "postgis"
-	res, _, err := ctx.CreateOrUpdate("postgres_extension.yml.tpl", extras, func(goalObj, existingObj runtime.Object) error {
+	extras["Extensions"] = postgresExtensions
+	res, err := ctx.CreateOrUpdateAll("postgres_extensions.yml.tpl", extras, func(goalObj, existingObj runtime.Object) error {
 		goal := goalObj.(*dbv1beta1.PostgresExtension)
-		existingPostgis = existingObj.(*dbv1beta1.PostgresExtension)
+		existing := existingObj.(*dbv1beta1.PostgresExtension)
 		// Copy the Spec over.
-		existingPostgis.Spec = goal.Spec
+		existing.Spec = goal.Spec
+		existingExtensions[goal.Spec.ExtensionName] = existing
 		return nil
 	})
 	if err != nil {
-		return res, errors.Wrap(err, "Error from postgis extension")
-	}
-
-	// Create the postgis_topology extension.
-	extras["ExtensionName"] = "postgis_topology"
-	extras["ObjectName"] = "postgis-topology"
-	res, _, err = ctx.CreateOrUpdate("postgres_extension.yml.tpl", extras, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*dbv1beta1.PostgresExtension)
-		existingPostgisToplogy = existingObj.(*dbv1beta1.PostgresExtension)
-		// Copy the Spec over.
-		existingPostgisToplogy.Spec = goal.Spec
-		return nil
-	})
-	if err != nil {
-		return res, errors.Wrap(err, "Error from postgis_topology extension")
+		return res, errors.Wrap(err, "Error from postgres extensions")
 	}
 
 	return components.Result{StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*summonv1beta1.SummonPlatform)
 		// Figure out status-y things.
-		if existingPostgis.Status.Status == dbv1beta1.StatusError {
-			// Postgis error'd, grab its message and error the whole thing.
-			instance.Status.Status = summonv1beta1.StatusError
-			instance.Status.PostgresExtensionStatus = summonv1beta1.StatusError
-			instance.Status.Message = fmt.Sprintf("postgis: %s", existingPostgis.Status.Message)
-		} else if existingPostgisToplogy.Status.Status == dbv1beta1.StatusError {
-			// Postgis_topology, same as above but with a different error message (hopefully).
-			instance.Status.Status = summonv1beta1.StatusError
-			instance.Status.PostgresExtensionStatus = summonv1beta1.StatusError
-			instance.Status.Message = fmt.Sprintf("postgis_topology: %s", existingPostgisToplogy.Status.Message)
-		} else if existingPostgis.Status.Status == dbv1beta1.StatusReady && existingPostgisToplogy.Status.Status == dbv1beta1.StatusReady {
-			// Both are ready, we're good to go!
+		allReady := true
+		for _, extension := range postgresExtensions {
+			existing := existingExtensions[extension.ExtensionName]
+			if existing.Status.Status == dbv1beta1.StatusError {
+				// Grab the first error message and error the whole thing.
+				instance.Status.Status = summonv1beta1.StatusError
+				instance.Status.PostgresExtensionStatus = summonv1beta1.StatusError
+				instance.Status.Message = fmt.Sprintf("%s: %s", extension.ExtensionName, existing.Status.Message)
+				return nil
+			}
+			if existing.Status.Status != dbv1beta1.StatusReady {
+				allReady = false
+			}
+		}
+		if allReady {
+			// All ready, we're good to go!
 			instance.Status.PostgresExtensionStatus = summonv1beta1.StatusReady
 			instance.Status.Status = summonv1beta1.StatusInitializing
 		} else {
@@ -47,6 +47,20 @@ var _ = Describe("SummonPlatform Postgres Component", func() {
 
 	})
 
+	Describe("Outputs", func() {
+		It("is ready once the database is running", func() {
+			comp := summoncomponents.NewPostgres("postgres.yml.tpl", "postgres_operator/postgresoperator.yml.tpl")
+			outputs := comp.Outputs()
+			Expect(outputs).To(HaveLen(1))
+			Expect(outputs[0].Name).To(Equal("postgres"))
+			Expect(outputs[0].Ready(ctx)).To(BeFalse())
+			instance.Status.PostgresStatus = postgresv1.ClusterStatusCreating
+			Expect(outputs[0].Ready(ctx)).To(BeFalse())
+			instance.Status.PostgresStatus = postgresv1.ClusterStatusRunning
+			Expect(outputs[0].Ready(ctx)).To(BeTrue())
+		})
+	})
+
 	Describe("Reconcile", func() {
 		It("creates a postgres object by default", func() {
 			comp := summoncomponents.NewPostgres("postgres.yml.tpl", "postgres_operator/postgresoperator.yml.tpl")
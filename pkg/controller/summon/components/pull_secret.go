@@ -47,6 +47,21 @@ func (_ *pullSecretComponent) IsReconcilable(_ *components.ComponentContext) boo
 	return true
 }
 
+func (_ *pullSecretComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *pullSecretComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputPullSecret, Ready: pullSecretReady},
+	}
+}
+
+func pullSecretReady(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	return instance.Status.PullSecretStatus == secretsv1beta1.StatusReady
+}
+
 func (comp *pullSecretComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	var existing *secretsv1beta1.PullSecret
 	res, _, err := ctx.CreateOrUpdate(comp.templatePath, nil, func(goalObj, existingObj runtime.Object) error {
@@ -17,8 +17,6 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
-	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
-	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -36,22 +34,20 @@ func (comp *redisDeploymentComponent) WatchTypes() []runtime.Object {
 	}
 }
 
-func (comp *redisDeploymentComponent) IsReconcilable(ctx *components.ComponentContext) bool {
-	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
-	// Check on the pull secret. Not technically needed in some cases, but just wait.
-	if instance.Status.PullSecretStatus != secretsv1beta1.StatusReady {
-		return false
-	}
+func (comp *redisDeploymentComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the pull secret via Dependencies.
 	return true
 }
 
+func (_ *redisDeploymentComponent) Dependencies() []string {
+	return []string{outputPullSecret}
+}
+
+func (_ *redisDeploymentComponent) Outputs() []components.Output {
+	return nil
+}
+
 func (comp *redisDeploymentComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, nil, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*appsv1.Deployment)
-		existing := existingObj.(*appsv1.Deployment)
-		// Copy the Spec over.
-		existing.Spec = goal.Spec
-		return nil
-	})
+	res, _, err := ctx.Apply(comp.templatePath, nil)
 	return res, err
 }
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+// Check if the rollback policy gave up on the requested version and the last good one is being served.
+func rolledBack(instance *summonv1beta1.SummonPlatform) bool {
+	failure := instance.Status.MigrationFailure
+	return instance.Spec.Rollback.MigrationFailures > 0 && failure.RolledBack && failure.Version == instance.Spec.Version && instance.Status.MigrateVersion != ""
+}
+
+// The version the Deployments and StatefulSets should be running, the last good one while rolled back.
+func servingVersion(instance *summonv1beta1.SummonPlatform) string {
+	if rolledBack(instance) {
+		return instance.Status.MigrateVersion
+	}
+	return instance.Spec.Version
+}
+
+// The context to render Deployments and StatefulSets with. While rolled back the templates see the last
+// good version as Spec.Version, everything else still sees the real spec.
+func servingContext(ctx *components.ComponentContext) *components.ComponentContext {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	return versionContext(ctx, servingVersion(instance))
+}
+
+// A copy of the context whose templates see the given version as Spec.Version.
+func versionContext(ctx *components.ComponentContext, version string) *components.ComponentContext {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	if version == instance.Spec.Version {
+		return ctx
+	}
+	serving := instance.DeepCopy()
+	serving.Spec.Version = version
+	versionCtx := *ctx
+	versionCtx.Top = serving
+	return &versionCtx
+}
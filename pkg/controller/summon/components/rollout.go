@@ -0,0 +1,231 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+// Works out which versions the web and daphne Deployments should be running in each slot. Promoting the
+// candidate is up to the status component, since it knows when the new pods are ready.
+type rolloutComponent struct{}
+
+func NewRollout() *rolloutComponent {
+	return &rolloutComponent{}
+}
+
+func (_ *rolloutComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *rolloutComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	return true
+}
+
+func (_ *rolloutComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *rolloutComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputRollout},
+	}
+}
+
+func (comp *rolloutComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	rollout, err := nextRollout(ctx)
+	if err != nil {
+		return components.Result{}, err
+	}
+	if reflect.DeepEqual(rollout, instance.Status.Rollout) {
+		return components.Result{}, nil
+	}
+	if rollout.CandidateVersion != "" && rollout.CandidateVersion != instance.Status.Rollout.CandidateVersion {
+		ctx.Eventf(corev1.EventTypeNormal, "RolloutStarted", "Rolling out version %s in the %s slot", rollout.CandidateVersion, rollout.CandidateSlot())
+	}
+	if instance.Status.Rollout.ActiveVersion != "" && rollout.ActiveSlot != instance.Status.Rollout.ActiveSlot {
+		if rollout.ActiveSlot == "" {
+			ctx.Eventf(corev1.EventTypeNormal, "RolloutSwitched", "Moved traffic back to the single Deployments")
+		} else {
+			ctx.Eventf(corev1.EventTypeNormal, "RolloutSwitched", "Moved traffic to the %s slot", rollout.ActiveSlot)
+		}
+	}
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.Rollout = rollout
+		return nil
+	}}, nil
+}
+
+// Work out the rollout status for the current spec. Switching between a rolling update and the slots starts
+// the new Deployments next to the old ones, the traffic only moves once they are available.
+func nextRollout(ctx *components.ComponentContext) (summonv1beta1.RolloutStatus, error) {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	rollout := *instance.Status.Rollout.DeepCopy()
+	version := servingVersion(instance)
+
+	if !usesSlots(instance) {
+		if rollout.ActiveSlot == "" {
+			// A plain rolling update, one Deployment each and nothing to track. This also drops a switch to
+			// the slots which hadn't finished, the traffic never left the single Deployments.
+			return summonv1beta1.RolloutStatus{}, nil
+		}
+		// Switching back to a rolling update, the active slot keeps the traffic until the single
+		// Deployments are up.
+		available, err := slotAvailable(ctx, "")
+		if err != nil || !available {
+			return summonv1beta1.RolloutStatus{ActiveSlot: rollout.ActiveSlot, ActiveVersion: rollout.ActiveVersion}, err
+		}
+		return summonv1beta1.RolloutStatus{}, nil
+	}
+
+	if rollout.ActiveVersion == "" {
+		live, err := deploymentExists(ctx, "")
+		if err != nil {
+			return rollout, err
+		}
+		if !live {
+			// A new instance, nothing is serving yet so it can go straight into the blue slot.
+			return summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: version}, nil
+		}
+		// Switching over from a rolling update. The blue slot starts with the last migrated version, which
+		// is what the single Deployments run unless they are mid-update.
+		return summonv1beta1.RolloutStatus{ActiveVersion: instance.Status.MigrateVersion}, nil
+	}
+	if rollout.ActiveSlot == "" {
+		available, err := slotAvailable(ctx, "blue")
+		if err != nil || !available {
+			return rollout, err
+		}
+		return summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: rollout.ActiveVersion}, nil
+	}
+
+	if version == rollout.ActiveVersion {
+		// Nothing to roll out, or the spec went back to the active version mid-rollout.
+		return summonv1beta1.RolloutStatus{ActiveSlot: rollout.ActiveSlot, ActiveVersion: rollout.ActiveVersion}, nil
+	}
+	if rollout.CandidateVersion == "" {
+		live, err := deploymentExists(ctx, rollout.ActiveSlot)
+		if err != nil {
+			return rollout, err
+		}
+		if !live {
+			// Nothing has been started in the active slot yet, so it follows the spec. The Deployments
+			// wait for the migrations anyway.
+			return summonv1beta1.RolloutStatus{ActiveSlot: rollout.ActiveSlot, ActiveVersion: version}, nil
+		}
+	}
+	if version != instance.Status.MigrateVersion {
+		// The new pods wait for their migrations, same as a rolling update. Leave any current candidate be.
+		return rollout, nil
+	}
+	if rollout.CandidateVersion != version {
+		rollout.CandidateVersion = version
+		rollout.CanaryWeight = 0
+		rollout.CanaryStartTime = nil
+	}
+	return rollout, nil
+}
+
+// Check if the instance is set up for a BlueGreen or Canary rollout.
+func usesSlots(instance *summonv1beta1.SummonPlatform) bool {
+	strategy := instance.Spec.Rollout.Strategy
+	return strategy == summonv1beta1.RolloutBlueGreen || strategy == summonv1beta1.RolloutCanary
+}
+
+// Check if the web Deployment for a slot has been created.
+func deploymentExists(ctx *components.ComponentContext, slot string) (bool, error) {
+	err := getSlotDeployment(ctx, slotName("web", slot), &appsv1.Deployment{})
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Check if the web and daphne Deployments for a slot have all their replicas available.
+func slotAvailable(ctx *components.ComponentContext, slot string) (bool, error) {
+	for _, part := range []string{"web", "daphne"} {
+		deployment := &appsv1.Deployment{}
+		err := getSlotDeployment(ctx, slotName(part, slot), deployment)
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if deployment.Spec.Replicas == nil || deployment.Status.AvailableReplicas != *deployment.Spec.Replicas {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Part is the name without the instance prefix, like web-blue. NotFound errors are passed back as they are.
+func getSlotDeployment(ctx *components.ComponentContext, part string, deployment *appsv1.Deployment) error {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	name := types.NamespacedName{Name: fmt.Sprintf("%s-%s", instance.Name, part), Namespace: instance.Namespace}
+	err := ctx.Get(ctx.Context, name, deployment)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrapf(err, "rollout: unable to get Deployment %s", name)
+	}
+	return err
+}
+
+// A Deployment slot to render, along with a context which has its version as Spec.Version.
+type slotContext struct {
+	slot string
+	ctx  *components.ComponentContext
+}
+
+// The slots to render the web and daphne Deployments in. A rolling update has a single Deployment with no
+// slot, otherwise there is one for the active version and one for the candidate if there is a rollout.
+// While switching between the two, the new Deployments run next to the ones which still have the traffic.
+func rolloutSlots(ctx *components.ComponentContext) []slotContext {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	rollout := instance.Status.Rollout
+	if rollout.ActiveVersion == "" {
+		return []slotContext{{ctx: servingContext(ctx)}}
+	}
+	slots := []slotContext{{slot: rollout.ActiveSlot, ctx: versionContext(ctx, rollout.ActiveVersion)}}
+	if rollout.ActiveSlot == "" {
+		slots = append(slots, slotContext{slot: "blue", ctx: versionContext(ctx, rollout.ActiveVersion)})
+	} else if !usesSlots(instance) {
+		slots = append(slots, slotContext{ctx: servingContext(ctx)})
+	} else if rollout.CandidateVersion != "" {
+		slots = append(slots, slotContext{slot: rollout.CandidateSlot(), ctx: versionContext(ctx, rollout.CandidateVersion)})
+	}
+	return slots
+}
+
+// Name of the Deployment for a subsystem in a slot.
+func slotName(part, slot string) string {
+	if slot == "" {
+		return part
+	}
+	return fmt.Sprintf("%s-%s", part, slot)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("SummonPlatform Rollout Component", func() {
+	var comp components.Component
+
+	// Set up the web and daphne Deployments for the given slots, with the given number of available pods.
+	liveSlots := func(available int32, slots ...string) {
+		objs := []runtime.Object{}
+		for _, slot := range slots {
+			for _, part := range []string{"web", "daphne"} {
+				name := "foo-" + part
+				if slot != "" {
+					name += "-" + slot
+				}
+				objs = append(objs, &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: intp(1)},
+					Status:     appsv1.DeploymentStatus{AvailableReplicas: available},
+				})
+			}
+		}
+		ctx.Client = fake.NewFakeClient(objs...)
+	}
+
+	BeforeEach(func() {
+		comp = summoncomponents.NewRollout()
+		instance.Spec.Rollout.Strategy = summonv1beta1.RolloutBlueGreen
+		instance.Status.MigrateVersion = "1.2.2"
+		liveSlots(1, "blue")
+	})
+
+	It("tracks nothing for a rolling update", func() {
+		instance.Spec.Rollout.Strategy = summonv1beta1.RolloutRollingUpdate
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{}))
+	})
+
+	It("starts a new instance in the blue slot", func() {
+		liveSlots(1)
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.3"}))
+	})
+
+	It("follows the spec while nothing runs in the active slot", func() {
+		liveSlots(1)
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2"}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.3"}))
+	})
+
+	Context("switching over from a rolling update", func() {
+		It("starts the blue slot with the migrated version and keeps the traffic where it is", func() {
+			liveSlots(1, "")
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveVersion: "1.2.2"}))
+		})
+
+		It("waits for the blue slot to be available", func() {
+			liveSlots(0, "", "blue")
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveVersion: "1.2.2"}
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveVersion: "1.2.2"}))
+		})
+
+		It("moves the traffic to the blue slot once it is available", func() {
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveVersion: "1.2.2"}
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2"}))
+		})
+	})
+
+	Context("switching back to a rolling update", func() {
+		BeforeEach(func() {
+			instance.Spec.Rollout.Strategy = summonv1beta1.RolloutRollingUpdate
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2", CandidateVersion: "1.2.3"}
+		})
+
+		It("keeps the traffic on the active slot until the single Deployments are available", func() {
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2"}))
+		})
+
+		It("stops tracking the slots once the single Deployments are available", func() {
+			liveSlots(1, "", "blue")
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{}))
+		})
+
+		It("drops a switch to the slots which hadn't finished", func() {
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveVersion: "1.2.2"}
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{}))
+		})
+	})
+
+	It("waits for migrations before starting a candidate", func() {
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2"}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Rollout.CandidateVersion).To(BeEmpty())
+	})
+
+	It("starts a candidate in the other slot once migrated", func() {
+		instance.Status.MigrateVersion = "1.2.3"
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2"}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Rollout.ActiveVersion).To(Equal("1.2.2"))
+		Expect(instance.Status.Rollout.CandidateVersion).To(Equal("1.2.3"))
+		Expect(instance.Status.Rollout.CandidateSlot()).To(Equal("green"))
+	})
+
+	It("drops the candidate when the spec goes back to the active version", func() {
+		instance.Spec.Version = "1.2.2"
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2", CandidateVersion: "1.2.3", CanaryWeight: 10}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2"}))
+	})
+
+	It("doesn't start a candidate for a rolled back version", func() {
+		instance.Spec.Rollback.MigrationFailures = 2
+		instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1.2.3", Count: 2, RolledBack: true}
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.2"}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Rollout.CandidateVersion).To(BeEmpty())
+	})
+})
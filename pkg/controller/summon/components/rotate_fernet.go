@@ -56,6 +56,16 @@ func (_ *fernetRotateComponent) IsReconcilable(_ *components.ComponentContext) b
 	return true
 }
 
+func (_ *fernetRotateComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *fernetRotateComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputFernetKeys},
+	}
+}
+
 func (comp *fernetRotateComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
@@ -123,6 +133,7 @@ func (comp *fernetRotateComponent) Reconcile(ctx *components.ComponentContext) (
 	if err != nil {
 		return components.Result{}, errors.Wrap(err, "rotate_fernet: Failed to update secret")
 	}
+	ctx.Eventf(corev1.EventTypeNormal, "FernetKeyRotated", "Added new fernet key %s", timeStamp)
 
 	return components.Result{}, nil
 }
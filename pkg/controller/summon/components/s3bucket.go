@@ -42,6 +42,16 @@ func (_ *s3BucketComponent) IsReconcilable(_ *components.ComponentContext) bool
 	return true
 }
 
+func (_ *s3BucketComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *s3BucketComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputS3Bucket},
+	}
+}
+
 func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	var existing *awsv1beta1.S3Bucket
 	res, _, err := ctx.CreateOrUpdate(comp.templatePath, nil, func(goalObj, existingObj runtime.Object) error {
@@ -50,6 +50,16 @@ func (_ *secretKeyComponent) IsReconcilable(ctx *components.ComponentContext) bo
 	return true
 }
 
+func (_ *secretKeyComponent) Dependencies() []string {
+	return []string{outputDefaults}
+}
+
+func (_ *secretKeyComponent) Outputs() []components.Output {
+	return []components.Output{
+		{Name: outputSecretKey},
+	}
+}
+
 func (comp *secretKeyComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
@@ -17,20 +17,38 @@ limitations under the License.
 package components
 
 import (
+	"fmt"
+
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
 type serviceComponent struct {
 	templatePath string
+	// Follow the rollout strategy, with a candidate Service during a rollout. See rollout.go.
+	slotted bool
 }
 
 func NewService(templatePath string) *serviceComponent {
 	return &serviceComponent{templatePath: templatePath}
 }
 
+// Like NewService, but for subsystems which follow the rollout strategy. The template renders a second
+// Service for the candidate pods, which is deleted again once the rollout is over.
+func NewRolloutService(templatePath string) *serviceComponent {
+	return &serviceComponent{templatePath: templatePath, slotted: true}
+}
+
+func (comp *serviceComponent) String() string {
+	return fmt.Sprintf("service(%s)", comp.templatePath)
+}
+
 func (comp *serviceComponent) WatchTypes() []runtime.Object {
 	return []runtime.Object{
 		&corev1.Service{},
@@ -38,19 +56,43 @@ func (comp *serviceComponent) WatchTypes() []runtime.Object {
 }
 
 func (_ *serviceComponent) IsReconcilable(_ *components.ComponentContext) bool {
-	// Services have no dependencies, always reconcile.
+	// Only waits on the defaults and rollout status via Dependencies.
 	return true
 }
 
+func (_ *serviceComponent) Dependencies() []string {
+	return []string{outputDefaults, outputRollout}
+}
+
+func (_ *serviceComponent) Outputs() []components.Output {
+	return nil
+}
+
 func (comp *serviceComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, nil, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*corev1.Service)
-		existing := existingObj.(*corev1.Service)
-		// Special case: Services mutate the ClusterIP value in the Spec and it should be preserved.
-		goal.Spec.ClusterIP = existing.Spec.ClusterIP
-		// Copy the Spec over.
-		existing.Spec = goal.Spec
-		return nil
-	})
-	return res, err
+	// Templates following the rollout strategy can render a second Service for the candidate pods.
+	objs, err := ctx.GetTemplateList(comp.templatePath, nil)
+	if err != nil {
+		return components.Result{}, err
+	}
+	for _, obj := range objs {
+		// The ClusterIP is filled in by the API server, Apply leaves it alone since the template doesn't set it.
+		res, _, err := ctx.ApplyObject(obj)
+		if err != nil {
+			return res, err
+		}
+	}
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	if comp.slotted && len(objs) > 0 && instance.Status.Rollout.CandidateVersion == "" {
+		// Left over from the last rollout, and it would keep the old slot from being cleaned up.
+		service := objs[0].(*corev1.Service)
+		candidate := &corev1.Service{}
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: service.Name + "-candidate", Namespace: service.Namespace}, candidate)
+		if err == nil {
+			err = ctx.Delete(ctx.Context, candidate)
+		}
+		if err != nil && !kerrors.IsNotFound(err) {
+			return components.Result{}, errors.Wrapf(err, "service: unable to delete service %s/%s-candidate", service.Namespace, service.Name)
+		}
+	}
+	return components.Result{}, nil
 }
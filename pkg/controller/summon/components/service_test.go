@@ -0,0 +1,68 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("SummonPlatform Service Component", func() {
+	BeforeEach(func() {
+		instance.Spec.Rollout.Strategy = summonv1beta1.RolloutBlueGreen
+	})
+
+	It("points the Service at the active slot", func() {
+		comp := summoncomponents.NewRolloutService("web/service.yml.tpl")
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.2", CandidateVersion: "1.2.3"}
+		Expect(comp).To(ReconcileContext(ctx))
+
+		service := &corev1.Service{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web", Namespace: "default"}, service)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(service.Spec.Selector["app.kubernetes.io/instance"]).To(Equal("foo-web-green"))
+		candidate := &corev1.Service{}
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-candidate", Namespace: "default"}, candidate)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(candidate.Spec.Selector["app.kubernetes.io/instance"]).To(Equal("foo-web-blue"))
+	})
+
+	It("deletes the candidate Service once the rollout is over", func() {
+		comp := summoncomponents.NewRolloutService("web/service.yml.tpl")
+		instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.3"}
+		candidate := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-web-candidate", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app.kubernetes.io/instance": "foo-web-blue"}},
+		}
+		ctx.Client = fake.NewFakeClient(candidate)
+		Expect(comp).To(ReconcileContext(ctx))
+
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-web-candidate", Namespace: "default"}, &corev1.Service{})
+		Expect(kerrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -17,12 +17,11 @@ limitations under the License.
 package components
 
 import (
-	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
-	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
-	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -35,42 +34,36 @@ func NewStatefulSet(templatePath string, waitForDatabase bool) *statefulsetCompo
 	return &statefulsetComponent{templatePath: templatePath, waitForDatabase: waitForDatabase}
 }
 
+func (comp *statefulsetComponent) String() string {
+	return fmt.Sprintf("statefulset(%s)", comp.templatePath)
+}
+
 func (comp *statefulsetComponent) WatchTypes() []runtime.Object {
 	return []runtime.Object{
 		&appsv1.StatefulSet{},
 	}
 }
 
-func (comp *statefulsetComponent) IsReconcilable(ctx *components.ComponentContext) bool {
-	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+func (_ *statefulsetComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the pull secret and possibly the database via Dependencies.
+	return true
+}
+
+func (comp *statefulsetComponent) Dependencies() []string {
 	// Check on the pull secret. Not technically needed in some cases, but just wait.
-	if instance.Status.PullSecretStatus != secretsv1beta1.StatusReady {
-		return false
-	}
-	// If we don't need the database, we're ready.
-	if !comp.waitForDatabase {
-		return true
+	deps := []string{outputPullSecret}
+	if comp.waitForDatabase {
+		// We do want the database, so wait for it to be migrated too.
+		deps = append(deps, outputPostgres, outputPostgresExtensions, outputMigrations, outputPostMigrateHooks, outputAppSecrets, outputConfigMap)
 	}
-	// We do want the database, so check all the database statuses.
-	if instance.Status.PostgresStatus != postgresv1.ClusterStatusRunning {
-		return false
-	}
-	if instance.Status.PostgresExtensionStatus != summonv1beta1.StatusReady {
-		return false
-	}
-	if instance.Status.MigrateVersion != instance.Spec.Version {
-		return false
-	}
-	return true
+	return deps
+}
+
+func (_ *statefulsetComponent) Outputs() []components.Output {
+	return nil
 }
 
 func (comp *statefulsetComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, nil, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*appsv1.StatefulSet)
-		existing := existingObj.(*appsv1.StatefulSet)
-		// Copy the Spec over.
-		existing.Spec = goal.Spec
-		return nil
-	})
+	res, _, err := servingContext(ctx).Apply(comp.templatePath, nil)
 	return res, err
 }
@@ -18,21 +18,41 @@ package components
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
-type statusComponent struct{}
+// How long to wait before trying a failed rollout check again.
+const rolloutCheckInterval = 30 * time.Second
+
+type statusComponent struct {
+	httpClient *http.Client
+}
 
 func NewStatus() *statusComponent {
-	return &statusComponent{}
+	return &statusComponent{httpClient: &http.Client{
+		Timeout: 10 * time.Second,
+		// A redirect would most likely be to the public hostname, which isn't the pods we want to check.
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (comp *statusComponent) InjectHTTPClient(client *http.Client) {
+	comp.httpClient = client
 }
 
 func (comp *statusComponent) WatchTypes() []runtime.Object {
@@ -54,6 +74,22 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 		return components.Result{}, nil
 	}
 
+	// With a BlueGreen or Canary rollout, check the web and daphne pods which are about to get the traffic.
+	rollout := instance.Status.Rollout
+	version := servingVersion(instance)
+	if usesSlots(instance) && rollout.ActiveVersion != "" && version != rollout.ActiveVersion && version != rollout.CandidateVersion {
+		// Migrations just finished, the candidate pods will be started on the next pass.
+		return components.Result{StatusModifier: components.SetCondition(summonv1beta1.ConditionDeployed, corev1.ConditionFalse, "Deploying", fmt.Sprintf("Waiting for the rollout of %s to start", version))}, nil
+	}
+	slot := rollout.ActiveSlot
+	if rollout.CandidateVersion != "" {
+		slot = rollout.CandidateSlot()
+	}
+	if !usesSlots(instance) {
+		// Switching back to a rolling update, the single Deployments are the ones taking over.
+		slot = ""
+	}
+
 	// Grab all (important) Deployments and make sure they are all ready.
 	web := &appsv1.Deployment{}
 	daphne := &appsv1.Deployment{}
@@ -63,11 +99,11 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 	celerybeat := &appsv1.StatefulSet{}
 
 	// Go's lack of generics can fuck right off.
-	err := comp.get(ctx, "web", web)
+	err := comp.get(ctx, slotName("web", slot), web)
 	if err != nil {
 		return components.Result{}, err
 	}
-	err = comp.get(ctx, "daphne", daphne)
+	err = comp.get(ctx, slotName("daphne", slot), daphne)
 	if err != nil {
 		return components.Result{}, err
 	}
@@ -89,27 +125,99 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 	}
 
 	// The big check!
-	if web.Spec.Replicas != nil && web.Status.AvailableReplicas == *web.Spec.Replicas &&
+	if !(web.Spec.Replicas != nil && web.Status.AvailableReplicas == *web.Spec.Replicas &&
 		daphne.Spec.Replicas != nil && daphne.Status.AvailableReplicas == *daphne.Spec.Replicas &&
 		celeryd.Spec.Replicas != nil && celeryd.Status.AvailableReplicas == *celeryd.Spec.Replicas &&
 		channelworker.Spec.Replicas != nil && channelworker.Status.AvailableReplicas == *channelworker.Spec.Replicas &&
 		static.Spec.Replicas != nil && static.Status.AvailableReplicas == *static.Spec.Replicas &&
 		// Note this one is different, available vs ready.
-		celerybeat.Spec.Replicas != nil && celerybeat.Status.ReadyReplicas == *celerybeat.Spec.Replicas {
-		// TODO: Add an actual HTTP self check in here.
-		return components.Result{StatusModifier: func(obj runtime.Object) error {
-			instance := obj.(*summonv1beta1.SummonPlatform)
-			instance.Status.Status = summonv1beta1.StatusReady
-			instance.Status.Message = fmt.Sprintf("Cluster %s ready", instance.Name)
-			return nil
-		}}, nil
-	}
-
-	// Not ready, alas.
-	return components.Result{}, nil
+		celerybeat.Spec.Replicas != nil && celerybeat.Status.ReadyReplicas == *celerybeat.Spec.Replicas) {
+		// Not ready, alas.
+		return components.Result{StatusModifier: components.SetCondition(summonv1beta1.ConditionDeployed, corev1.ConditionFalse, "Deploying", "Waiting for all deployments to be available")}, nil
+	}
+
+	if instance.Spec.Rollout.Check.Path != "" {
+		err := comp.checkHTTP(ctx)
+		if err != nil {
+			ctx.Logger.Info("rollout check failed", "error", err.Error())
+			return components.Result{RequeueAfter: rolloutCheckInterval, StatusModifier: components.SetCondition(summonv1beta1.ConditionDeployed, corev1.ConditionFalse, "RolloutCheckFailed", err.Error())}, nil
+		}
+	}
+
+	if rollout.CandidateVersion != "" && instance.Spec.Rollout.Strategy == summonv1beta1.RolloutCanary {
+		if rollout.CanaryStartTime == nil {
+			// Start sending some of the requests to the candidate, the ingress components pick this up.
+			ctx.Eventf(corev1.EventTypeNormal, "CanaryStarted", "Sending %d%% of requests to version %s", instance.Spec.Rollout.CanaryWeight, rollout.CandidateVersion)
+			return components.Result{RequeueAfter: canaryDuration(instance), StatusModifier: func(obj runtime.Object) error {
+				instance := obj.(*summonv1beta1.SummonPlatform)
+				now := metav1.Now()
+				instance.Status.Rollout.CanaryWeight = instance.Spec.Rollout.CanaryWeight
+				instance.Status.Rollout.CanaryStartTime = &now
+				instance.SetCondition(summonv1beta1.ConditionDeployed, corev1.ConditionFalse, "Canary", fmt.Sprintf("Sending %d%% of requests to %s", instance.Spec.Rollout.CanaryWeight, instance.Status.Rollout.CandidateVersion))
+				return nil
+			}}, nil
+		}
+		remaining := rollout.CanaryStartTime.Add(canaryDuration(instance)).Sub(time.Now())
+		if remaining > 0 {
+			return components.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	if rollout.CandidateVersion != "" {
+		ctx.Eventf(corev1.EventTypeNormal, "RolloutPromoted", "Switching traffic to version %s in the %s slot", rollout.CandidateVersion, slot)
+	}
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		if rollout.CandidateVersion != "" {
+			// Promote the candidate, the services will switch over to it on the next pass.
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: slot, ActiveVersion: rollout.CandidateVersion}
+		}
+		instance.Status.Status = summonv1beta1.StatusReady
+		instance.Status.Message = fmt.Sprintf("Cluster %s ready", instance.Name)
+		if rolledBack(instance) {
+			instance.Status.Message = fmt.Sprintf("Cluster %s ready, rolled back to %s", instance.Name, instance.Status.MigrateVersion)
+		}
+		instance.SetCondition(summonv1beta1.ConditionDeployed, corev1.ConditionTrue, "DeploymentsAvailable", "")
+		instance.SetCondition(helpers.ConditionReady, corev1.ConditionTrue, "Ready", instance.Status.Message)
+		return nil
+	}}, nil
+}
+
+// Request the rollout check path from the web pods which are about to get the traffic.
+func (comp *statusComponent) checkHTTP(ctx *components.ComponentContext) error {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	service := fmt.Sprintf("%s-web", instance.Name)
+	if instance.Status.Rollout.CandidateVersion != "" {
+		service = fmt.Sprintf("%s-web-candidate", instance.Name)
+	}
+	url := fmt.Sprintf("http://%s.%s.svc:8000%s", service, instance.Namespace, instance.Spec.Rollout.Check.Path)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "status: error building rollout check request for %s", url)
+	}
+	// Look like a normal request through the ingress, so Django's host and HTTPS checks pass.
+	req.Host = instance.Spec.Hostname
+	req.Header.Set("X-Forwarded-Proto", "https")
+	resp, err := comp.httpClient.Do(req.WithContext(ctx.Context))
+	if err != nil {
+		return errors.Wrapf(err, "status: error requesting rollout check %s", url)
+	}
+	defer resp.Body.Close()
+	if int32(resp.StatusCode) != instance.Spec.Rollout.Check.ExpectedStatus {
+		return errors.Errorf("status: rollout check %s returned %d, expected %d", url, resp.StatusCode, instance.Spec.Rollout.Check.ExpectedStatus)
+	}
+	return nil
+}
+
+// How long a canary runs before it is promoted.
+func canaryDuration(instance *summonv1beta1.SummonPlatform) time.Duration {
+	if instance.Spec.Rollout.CanaryDuration == nil {
+		return 0
+	}
+	return instance.Spec.Rollout.CanaryDuration.Duration
 }
 
-// Short helper because we need to do this 6 times.
+// Short helper because we need to do this 6 times. Part is the name without the instance prefix, like web-blue.
 func (comp *statusComponent) get(ctx *components.ComponentContext, part string, obj runtime.Object) error {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 	name := types.NamespacedName{Name: fmt.Sprintf("%s-%s", instance.Name, part), Namespace: instance.Namespace}
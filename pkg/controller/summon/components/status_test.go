@@ -17,14 +17,22 @@ limitations under the License.
 package components_test
 
 import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 )
@@ -105,6 +113,8 @@ var _ = Describe("SummonPlatform Status Component", func() {
 		comp := summoncomponents.NewStatus()
 		Expect(comp).To(ReconcileContext(ctx))
 		Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusReady))
+		Expect(helpers.IsConditionTrue(instance.Status.Conditions, summonv1beta1.ConditionDeployed)).To(BeTrue())
+		Expect(helpers.IsConditionTrue(instance.Status.Conditions, helpers.ConditionReady)).To(BeTrue())
 	})
 
 	It("doesn't update if still migrating", func() {
@@ -121,6 +131,7 @@ var _ = Describe("SummonPlatform Status Component", func() {
 		comp := summoncomponents.NewStatus()
 		Expect(comp).To(ReconcileContext(ctx))
 		Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+		Expect(helpers.GetCondition(instance.Status.Conditions, summonv1beta1.ConditionDeployed).Status).To(Equal(corev1.ConditionFalse))
 	})
 
 	It("doesn't update if deployments are partially ready", func() {
@@ -141,4 +152,120 @@ var _ = Describe("SummonPlatform Status Component", func() {
 		Expect(comp).To(ReconcileContext(ctx))
 		Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
 	})
+	Context("with a rollout", func() {
+		var webCandidate *appsv1.Deployment
+		var daphneCandidate *appsv1.Deployment
+		var requests []*http.Request
+		var checkStatus int
+
+		BeforeEach(func() {
+			daphneDeployment.Status.AvailableReplicas = 2
+			celerydDeployment.Status.AvailableReplicas = 2
+			channelworkersDeployment.Status.AvailableReplicas = 2
+			staticDeployment.Status.AvailableReplicas = 2
+			celerybeatStatefulSet.Status.ReadyReplicas = 2
+			webCandidate = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-web-green", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Replicas: intp(2)},
+				Status:     appsv1.DeploymentStatus{AvailableReplicas: 2},
+			}
+			daphneCandidate = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-daphne-green", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Replicas: intp(2)},
+				Status:     appsv1.DeploymentStatus{AvailableReplicas: 2},
+			}
+			instance.Spec.Rollout.Strategy = summonv1beta1.RolloutBlueGreen
+			instance.Status.Status = summonv1beta1.StatusDeploying
+			instance.Status.MigrateVersion = "1.2.3"
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "blue", ActiveVersion: "1.2.2", CandidateVersion: "1.2.3"}
+			ctx.Client = fake.NewFakeClient(instance, webCandidate, daphneCandidate, celerydDeployment,
+				channelworkersDeployment, staticDeployment, celerybeatStatefulSet)
+
+			requests = []*http.Request{}
+			checkStatus = 200
+		})
+
+		newStatus := func() components.Component {
+			comp := summoncomponents.NewStatus()
+			comp.InjectHTTPClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				requests = append(requests, req)
+				return &http.Response{StatusCode: checkStatus, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+			})})
+			return comp
+		}
+
+		It("promotes the candidate once it is available", func() {
+			Expect(newStatus()).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusReady))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.3"}))
+		})
+
+		It("doesn't promote the candidate until it is available", func() {
+			webCandidate.Status.AvailableReplicas = 1
+			ctx.Client = fake.NewFakeClient(instance, webCandidate, daphneCandidate, celerydDeployment,
+				channelworkersDeployment, staticDeployment, celerybeatStatefulSet)
+
+			Expect(newStatus()).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+			Expect(instance.Status.Rollout.ActiveSlot).To(Equal("blue"))
+		})
+
+		It("waits for the rollout to start after migrations", func() {
+			instance.Status.Rollout = summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.2"}
+
+			Expect(newStatus()).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+		})
+
+		It("runs the rollout check against the candidate", func() {
+			instance.Spec.Rollout.Check = summonv1beta1.RolloutCheckSpec{Path: "/healthz", ExpectedStatus: 200}
+
+			Expect(newStatus()).To(ReconcileContext(ctx))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.String()).To(Equal("http://foo-web-candidate.default.svc:8000/healthz"))
+			Expect(requests[0].Host).To(Equal("foo.ridecell.us"))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusReady))
+		})
+
+		It("doesn't promote the candidate if the rollout check fails", func() {
+			instance.Spec.Rollout.Check = summonv1beta1.RolloutCheckSpec{Path: "/healthz", ExpectedStatus: 200}
+			checkStatus = 503
+
+			Expect(newStatus()).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+			Expect(instance.Status.Rollout.ActiveSlot).To(Equal("blue"))
+			Expect(helpers.GetCondition(instance.Status.Conditions, summonv1beta1.ConditionDeployed).Reason).To(Equal("RolloutCheckFailed"))
+		})
+
+		It("starts a canary before promoting", func() {
+			instance.Spec.Rollout.Strategy = summonv1beta1.RolloutCanary
+			instance.Spec.Rollout.CanaryWeight = 10
+			instance.Spec.Rollout.CanaryDuration = &metav1.Duration{Duration: 5 * time.Minute}
+
+			Expect(newStatus()).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+			Expect(instance.Status.Rollout.ActiveSlot).To(Equal("blue"))
+			Expect(instance.Status.Rollout.CanaryWeight).To(BeEquivalentTo(10))
+			Expect(instance.Status.Rollout.CanaryStartTime).ToNot(BeNil())
+		})
+
+		It("promotes a canary once it has run for long enough", func() {
+			instance.Spec.Rollout.Strategy = summonv1beta1.RolloutCanary
+			instance.Spec.Rollout.CanaryWeight = 10
+			instance.Spec.Rollout.CanaryDuration = &metav1.Duration{Duration: 5 * time.Minute}
+			started := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+			instance.Status.Rollout.CanaryWeight = 10
+			instance.Status.Rollout.CanaryStartTime = &started
+
+			Expect(newStatus()).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusReady))
+			Expect(instance.Status.Rollout).To(Equal(summonv1beta1.RolloutStatus{ActiveSlot: "green", ActiveVersion: "1.2.3"}))
+		})
+	})
 })
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
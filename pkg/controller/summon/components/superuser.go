@@ -21,7 +21,6 @@ package components
 import (
 	_ "github.com/lib/pq"
 	"github.com/pkg/errors"
-	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -41,21 +40,19 @@ func (comp *superuserComponent) WatchTypes() []runtime.Object {
 	}
 }
 
-func (comp *superuserComponent) IsReconcilable(ctx *components.ComponentContext) bool {
-	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
-	// Wait for the database to be up and migrated.
-	if instance.Status.PostgresStatus != postgresv1.ClusterStatusRunning {
-		return false
-	}
-	if instance.Status.PostgresExtensionStatus != summonv1beta1.StatusReady {
-		return false
-	}
-	if instance.Status.MigrateVersion != instance.Spec.Version {
-		return false
-	}
+func (comp *superuserComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	// Waits on the database to be up and migrated via Dependencies.
 	return true
 }
 
+func (_ *superuserComponent) Dependencies() []string {
+	return []string{outputPostgres, outputPostgresExtensions, outputMigrations}
+}
+
+func (_ *superuserComponent) Outputs() []components.Output {
+	return nil
+}
+
 func (comp *superuserComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 	if instance.Spec.NoCreateSuperuser {
@@ -17,9 +17,15 @@ limitations under the License.
 package summon
 
 import (
-	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta1 "k8s.io/api/autoscaling/v2beta1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 )
@@ -46,21 +52,24 @@ func Add(mgr manager.Manager) error {
 		summoncomponents.NewAppSecret(),
 
 		summoncomponents.NewConfigMap("configmap.yml.tpl"),
+		summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPreMigrate),
 		summoncomponents.NewMigrations("migrations.yml.tpl"),
+		summoncomponents.NewHooks("hook.yml.tpl", summonv1beta1.HookPostMigrate),
 		summoncomponents.NewSuperuser(),
+		summoncomponents.NewRollout(),
 
 		// Redis components.
 		summoncomponents.NewRedisDeployment("redis/deployment.yml.tpl"),
 		summoncomponents.NewService("redis/service.yml.tpl"),
 
 		// Web components.
-		summoncomponents.NewDeployment("web/deployment.yml.tpl"),
-		summoncomponents.NewService("web/service.yml.tpl"),
+		summoncomponents.NewRolloutDeployment("web/deployment.yml.tpl"),
+		summoncomponents.NewRolloutService("web/service.yml.tpl"),
 		summoncomponents.NewIngress("web/ingress.yml.tpl"),
 
 		// Daphne components.
-		summoncomponents.NewDeployment("daphne/deployment.yml.tpl"),
-		summoncomponents.NewService("daphne/service.yml.tpl"),
+		summoncomponents.NewRolloutDeployment("daphne/deployment.yml.tpl"),
+		summoncomponents.NewRolloutService("daphne/service.yml.tpl"),
 		summoncomponents.NewIngress("daphne/ingress.yml.tpl"),
 
 		// Static file components.
@@ -78,8 +87,20 @@ func Add(mgr manager.Manager) error {
 		// Channelworker components.
 		summoncomponents.NewDeployment("channelworker/deployment.yml.tpl"),
 
+		// Report anything the templates above no longer render. Set the ridecell.io/prune annotation to
+		// "delete" on an instance to clean them up.
+		components.NewPruner("summon-operator", []runtime.Object{
+			&appsv1.DeploymentList{},
+			&appsv1.StatefulSetList{},
+			&autoscalingv2beta1.HorizontalPodAutoscalerList{},
+			&corev1.ServiceList{},
+			&extv1beta1.IngressList{},
+			&corev1.ConfigMapList{},
+		}, components.PruneReport),
+
 		// End of converge status checks.
 		summoncomponents.NewStatus(),
+		summoncomponents.NewPostDeployHooks("hook.yml.tpl"),
 
 		// Notification componenets.
 		// Keep Notification at the end of this block
@@ -25,6 +25,7 @@ spec:
         app.kubernetes.io/part-of: {{ .Instance.Name }}
         app.kubernetes.io/managed-by: summon-operator
     spec:
+      {{- template "podOverrides" .Instance.Spec.Overrides.Celerybeat }}
       imagePullSecrets:
       - name: pull-secret
       initContainers:
@@ -46,6 +47,10 @@ spec:
         image: us.gcr.io/ridecell-1/summon:{{ .Instance.Spec.Version }}
         imagePullPolicy: Always
         command: [python, "-m", celery, "-A", summon_platform, beat, "-l", info, "--schedule", /schedule/beat, --pidfile=]
+        {{- template "containerOverrides" .Instance.Spec.Overrides.Celerybeat }}
+        {{- with .Instance.Spec.Overrides.Celerybeat.Resources }}
+        resources: {{ toJson . }}
+        {{- else }}
         resources:
           requests:
             memory: 512M
@@ -53,6 +58,7 @@ spec:
           limits:
             memory: 1G
             cpu: 200m
+        {{- end }}
         volumeMounts:
         - name: config-volume
           mountPath: /etc/config
@@ -2,4 +2,4 @@
 {{ define "componentType" }}worker{{ end }}
 {{ define "command" }}[python, "-m", celery, "-A", summon_platform, worker, "-l", info]{{ end }}
 {{ define "replicas" }}{{ .Instance.Spec.WorkerReplicas }}{{ end }}
-{{ template "deployment" . }}
+{{ template "deployment" (dict "Instance" .Instance "Extra" .Extra "Autoscaler" .Instance.Spec.Autoscaling.Celeryd "Overrides" .Instance.Spec.Overrides.Celeryd) }}
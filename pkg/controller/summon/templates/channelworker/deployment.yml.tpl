@@ -2,4 +2,4 @@
 {{ define "componentType" }}worker{{ end }}
 {{ define "command" }}[python, manage.py, runworker, "-v2", "--threads", "2"]{{ end }}
 {{ define "replicas" }}{{ .Instance.Spec.ChannelWorkerReplicas }}{{ end }}
-{{ template "deployment" . }}
+{{ template "deployment" (dict "Instance" .Instance "Extra" .Extra "Autoscaler" .Instance.Spec.Autoscaling.ChannelWorker "Overrides" .Instance.Spec.Overrides.ChannelWorker) }}
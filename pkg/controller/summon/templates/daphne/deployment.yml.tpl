@@ -2,4 +2,4 @@
 {{ define "componentType" }}web{{ end }}
 {{ define "command" }}[daphne, "-b", "0.0.0.0", "summon_platform.asgi:channel_layer"]{{ end }}
 {{ define "replicas" }}{{ .Instance.Spec.DaphneReplicas }}{{ end }}
-{{ template "deployment" . }}
+{{ template "deployment" (dict "Instance" .Instance "Extra" .Extra "Autoscaler" .Instance.Spec.Autoscaling.Daphne "Overrides" .Instance.Spec.Overrides.Daphne) }}
@@ -1,4 +1,6 @@
 {{ define "componentName" }}daphne{{ end }}
 {{ define "componentType" }}web{{ end }}
 {{ define "ingressPath" }}/websockets{{ end }}
+{{ define "ingressAnnotations" }}{{ template "canaryAnnotations" . }}{{ end }}
+{{ define "ingressBackends" }}{{ template "canaryBackends" . }}{{ end }}
 {{ template "ingress" . }}
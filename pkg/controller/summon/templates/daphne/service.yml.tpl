@@ -1,3 +1,5 @@
 {{ define "componentName" }}daphne{{ end }}
 {{ define "componentType" }}web{{ end }}
+{{ define "serviceSlot" }}{{ template "activeSlot" . }}{{ end }}
 {{ template "service" . }}
+{{ template "candidateService" . }}
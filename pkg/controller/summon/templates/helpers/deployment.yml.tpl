@@ -1,26 +1,30 @@
+{{/* Call with (dict "Instance" .Instance "Extra" .Extra "Autoscaler" .Instance.Spec.Autoscaling.Foo "Overrides" .Instance.Spec.Overrides.Foo),
+     the replica count is left to a HorizontalPodAutoscaler when the autoscaler is set. */}}
 {{ define "deployment" }}
 apiVersion: apps/v1
 kind: Deployment
 metadata:
-  name: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+  name: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ with .Extra.slot }}-{{ . }}{{ end }}
   namespace: {{ .Instance.Namespace }}
   labels:
     app.kubernetes.io/name: {{ block "componentName" . }}{{ end }}
-    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ with .Extra.slot }}-{{ . }}{{ end }}
     app.kubernetes.io/version: {{ .Instance.Spec.Version }}
     app.kubernetes.io/component: {{ block "componentType" . }}{{ end }}
     app.kubernetes.io/part-of: {{ .Instance.Name }}
     app.kubernetes.io/managed-by: summon-operator
 spec:
+  {{- if not .Autoscaler }}
   replicas: {{ block "replicas" . }}1{{ end }}
+  {{- end }}
   selector:
     matchLabels:
-      app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+      app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ with .Extra.slot }}-{{ . }}{{ end }}
   template:
     metadata:
       labels:
         app.kubernetes.io/name: {{ block "componentName" . }}{{ end }}
-        app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+        app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ with .Extra.slot }}-{{ . }}{{ end }}
         app.kubernetes.io/version: {{ .Instance.Spec.Version }}
         app.kubernetes.io/component: {{ block "componentType" . }}{{ end }}
         app.kubernetes.io/part-of: {{ .Instance.Name }}
@@ -29,6 +33,7 @@ spec:
         summon.ridecell.io/appSecretsHash: {{ .Extra.appSecretsHash }}
         summon.ridecell.io/configHash: {{ .Extra.configHash }}
     spec:
+      {{- template "podOverrides" .Overrides }}
       imagePullSecrets:
       - name: pull-secret
       containers:
@@ -37,6 +42,10 @@ spec:
         imagePullPolicy: Always
         command: {{ block "command" . }}[]{{ end }}
         ports: {{ block "deploymentPorts" . }}[{containerPort: 8000}]{{ end }}
+        {{- template "containerOverrides" .Overrides }}
+        {{- with .Overrides.Resources }}
+        resources: {{ toJson . }}
+        {{- else }}
         resources:
           requests:
             memory: 512M
@@ -44,6 +53,7 @@ spec:
           limits:
             memory: 1G
             cpu: 1000m
+        {{- end }}
         volumeMounts:
         - name: config-volume
           mountPath: /etc/config
@@ -57,4 +67,8 @@ spec:
         - name: app-secrets
           secret:
             secretName: summon.{{ .Instance.Name }}.app-secrets
+{{- if .Autoscaler }}
+---
+{{ template "hpa" . }}
+{{- end }}
 {{ end }}
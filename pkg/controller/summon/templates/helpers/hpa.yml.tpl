@@ -0,0 +1,39 @@
+{{/* Rendered by the deployment helper, scales the Deployment for the same subsystem and slot. */}}
+{{ define "hpa" }}
+apiVersion: autoscaling/v2beta1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: {{ .Instance.Name }}-{{ template "componentName" . }}{{ with .Extra.slot }}-{{ . }}{{ end }}
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ template "componentName" . }}
+    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ template "componentName" . }}{{ with .Extra.slot }}-{{ . }}{{ end }}
+    app.kubernetes.io/version: {{ .Instance.Spec.Version }}
+    app.kubernetes.io/component: {{ template "componentType" . }}
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: summon-operator
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: {{ .Instance.Name }}-{{ template "componentName" . }}{{ with .Extra.slot }}-{{ . }}{{ end }}
+  {{- with .Autoscaler }}
+  {{- with .MinReplicas }}
+  minReplicas: {{ . }}
+  {{- end }}
+  maxReplicas: {{ .MaxReplicas }}
+  metrics:
+  {{- with .TargetCPUUtilization }}
+  - type: Resource
+    resource:
+      name: cpu
+      targetAverageUtilization: {{ . }}
+  {{- end }}
+  {{- with .TargetMemoryUtilization }}
+  - type: Resource
+    resource:
+      name: memory
+      targetAverageUtilization: {{ . }}
+  {{- end }}
+  {{- end }}
+{{ end }}
@@ -15,6 +15,7 @@ metadata:
     kubernetes.io/ingress.class: traefik
     kubernetes.io/tls-acme: "true"
     certmanager.k8s.io/cluster-issuer: letsencrypt-prod
+    {{- block "ingressAnnotations" . }}{{ end }}
 spec:
   rules:
   - host: {{ .Instance.Spec.Hostname }}
@@ -24,8 +25,26 @@ spec:
         backend:
           serviceName: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
           servicePort: 8000
+      {{- block "ingressBackends" . }}{{ end }}
   tls:
   - secretName: {{ .Instance.Name }}-tls
     hosts:
     - {{ .Instance.Spec.Hostname }}
 {{ end }}
+
+{{/* For subsystems following the rollout strategy. Use as the ingressAnnotations and ingressBackends blocks to send the canary share of requests to the candidate Service. */}}
+{{ define "canaryAnnotations" }}
+{{- with .Instance.Status.Rollout.CanaryWeight }}
+    traefik.ingress.kubernetes.io/service-weights: |
+      {{ $.Instance.Name }}-{{ template "componentName" $ }}-candidate: {{ . }}%
+{{- end }}
+{{- end }}
+
+{{ define "canaryBackends" }}
+{{- if .Instance.Status.Rollout.CanaryWeight }}
+      - path: {{ template "ingressPath" . }}
+        backend:
+          serviceName: {{ .Instance.Name }}-{{ template "componentName" . }}-candidate
+          servicePort: 8000
+{{- end }}
+{{- end }}
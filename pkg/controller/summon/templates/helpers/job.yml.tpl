@@ -0,0 +1,63 @@
+{{/* Call with (dict "Instance" .Instance "Extra" .Extra "Overrides" .Instance.Spec.Overrides.Foo). */}}
+{{ define "job" }}
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ block "componentName" . }}{{ end }}
+    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+    app.kubernetes.io/version: {{ .Instance.Spec.Version }}
+    app.kubernetes.io/component: {{ block "componentType" . }}{{ end }}
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: summon-operator
+spec:
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: {{ block "componentName" . }}{{ end }}
+        app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+        app.kubernetes.io/version: {{ .Instance.Spec.Version }}
+        app.kubernetes.io/component: {{ block "componentType" . }}{{ end }}
+        app.kubernetes.io/part-of: {{ .Instance.Name }}
+        app.kubernetes.io/managed-by: summon-operator
+    spec:
+      {{- template "podOverrides" .Overrides }}
+      restartPolicy: Never
+      imagePullSecrets:
+      - name: pull-secret
+      containers:
+      - name: default
+        image: us.gcr.io/ridecell-1/summon:{{ .Instance.Spec.Version }}
+        imagePullPolicy: Always
+        command:
+        - sh
+        - "-c"
+        - {{ block "jobCommand" . }}{{ end }}
+        {{- template "containerOverrides" .Overrides }}
+        {{- with .Overrides.Resources }}
+        resources: {{ toJson . }}
+        {{- else }}
+        resources:
+          requests:
+            memory: 1G
+            cpu: 500m
+          limits:
+            memory: 2G
+            cpu: 2
+        {{- end }}
+        volumeMounts:
+        - name: config-volume
+          mountPath: /etc/config
+        - name: app-secrets
+          mountPath: /etc/secrets
+
+      volumes:
+        - name: config-volume
+          configMap:
+            name: {{ .Instance.Name }}-config
+        - name: app-secrets
+          secret:
+            secretName: summon.{{ .Instance.Name }}.app-secrets
+{{ end }}
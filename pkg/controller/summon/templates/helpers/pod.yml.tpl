@@ -0,0 +1,23 @@
+{{/* Scheduling fields from a PodOverrides, use inside a pod spec as {{- template "podOverrides" .Instance.Spec.Overrides.Foo }}. */}}
+{{ define "podOverrides" }}
+{{- with .NodeSelector }}
+      nodeSelector: {{ toJson . }}
+{{- end }}
+{{- with .Tolerations }}
+      tolerations: {{ toJson . }}
+{{- end }}
+{{- with .Affinity }}
+      affinity: {{ toJson . }}
+{{- end }}
+{{- with .PriorityClassName }}
+      priorityClassName: {{ toJson . }}
+{{- end }}
+{{- end }}
+
+{{/* Container fields from a PodOverrides, use inside the main container as {{- template "containerOverrides" .Instance.Spec.Overrides.Foo }}.
+     Resources are left to the caller since each template has its own defaults. */}}
+{{ define "containerOverrides" }}
+{{- with .Env }}
+        env: {{ toJson . }}
+{{- end }}
+{{- end }}
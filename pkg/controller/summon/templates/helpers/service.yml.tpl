@@ -13,6 +13,32 @@ metadata:
     app.kubernetes.io/managed-by: summon-operator
 spec:
   selector:
-    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "serviceSlot" . }}{{ end }}
   ports: {{ block "servicePorts" . }}[{protocol: TCP, port: 8000}]{{ end }}
 {{ end }}
+
+{{/* For subsystems following the rollout strategy. Use as the serviceSlot block to send traffic to the active slot. */}}
+{{ define "activeSlot" }}{{ with .Instance.Status.Rollout.ActiveSlot }}-{{ . }}{{ end }}{{ end }}
+
+{{/* A second Service for the pods of a version being rolled out, used for canary traffic and the rollout check. */}}
+{{ define "candidateService" }}
+{{- with .Instance.Status.Rollout }}{{ if .CandidateVersion }}
+---
+kind: Service
+apiVersion: v1
+metadata:
+  name: {{ $.Instance.Name }}-{{ template "componentName" $ }}-candidate
+  namespace: {{ $.Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ template "componentName" $ }}
+    app.kubernetes.io/instance: {{ $.Instance.Name }}-{{ template "componentName" $ }}
+    app.kubernetes.io/version: {{ .CandidateVersion }}
+    app.kubernetes.io/component: {{ template "componentType" $ }}
+    app.kubernetes.io/part-of: {{ $.Instance.Name }}
+    app.kubernetes.io/managed-by: summon-operator
+spec:
+  selector:
+    app.kubernetes.io/instance: {{ $.Instance.Name }}-{{ template "componentName" $ }}-{{ .CandidateSlot }}
+  ports: {{ template "servicePorts" $ }}
+{{- end }}{{ end }}
+{{- end }}
@@ -0,0 +1,4 @@
+{{ define "componentName" }}hook-{{ .Extra.hook.Name }}{{ end }}
+{{ define "componentType" }}hook{{ end }}
+{{ define "jobCommand" }}{{ .Extra.hook.Command | toJson }}{{ end }}
+{{ template "job" (dict "Instance" .Instance "Extra" .Extra "Overrides" .Instance.Spec.Overrides.Hooks) }}
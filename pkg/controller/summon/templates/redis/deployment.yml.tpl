@@ -25,9 +25,14 @@ spec:
         app.kubernetes.io/part-of: {{ .Instance.Name }}
         app.kubernetes.io/managed-by: summon-operator
     spec:
+      {{- template "podOverrides" .Instance.Spec.Overrides.Redis }}
       containers:
       - name: default
         image: redis:latest
         imagePullPolicy: Always
+        {{- template "containerOverrides" .Instance.Spec.Overrides.Redis }}
+        {{- with .Instance.Spec.Overrides.Redis.Resources }}
+        resources: {{ toJson . }}
+        {{- end }}
         ports:
         - containerPort: 6379
@@ -2,4 +2,4 @@
 {{ define "componentType" }}web{{ end }}
 {{ define "command" }}[caddy, "-port", "8000", "-root", /var/www, "-log", stdout]{{ end }}
 {{ define "replicas" }}{{ .Instance.Spec.StaticReplicas }}{{ end }}
-{{ template "deployment" . }}
+{{ template "deployment" (dict "Instance" .Instance "Extra" .Extra "Autoscaler" .Instance.Spec.Autoscaling.Static "Overrides" .Instance.Spec.Overrides.Static) }}
@@ -2,4 +2,4 @@
 {{ define "componentType" }}web{{ end }}
 {{ define "command" }}[python, -m, twisted, --log-format, text, web, --listen, tcp:8000, --wsgi, summon_platform.wsgi.application]{{ end }}
 {{ define "replicas" }}{{ .Instance.Spec.WebReplicas }}{{ end }}
-{{ template "deployment" . }}
+{{ template "deployment" (dict "Instance" .Instance "Extra" .Extra "Autoscaler" .Instance.Spec.Autoscaling.Web "Overrides" .Instance.Spec.Overrides.Web) }}
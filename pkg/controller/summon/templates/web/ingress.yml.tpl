@@ -1,4 +1,6 @@
 {{ define "componentName" }}web{{ end }}
 {{ define "componentType" }}web{{ end }}
 {{ define "ingressPath" }}/{{ end }}
+{{ define "ingressAnnotations" }}{{ template "canaryAnnotations" . }}{{ end }}
+{{ define "ingressBackends" }}{{ template "canaryBackends" . }}{{ end }}
 {{ template "ingress" . }}
@@ -1,3 +1,5 @@
 {{ define "componentName" }}web{{ end }}
 {{ define "componentType" }}web{{ end }}
+{{ define "serviceSlot" }}{{ template "activeSlot" . }}{{ end }}
 {{ template "service" . }}
+{{ template "candidateService" . }}
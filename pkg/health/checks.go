@@ -0,0 +1,106 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/nlopes/slack"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// Liveness check on the leader election lease. The elector renews it every few seconds while this replica
+// leads, so a lease we hold which has run out means the manager is wedged. The manager's identity is the
+// hostname plus a random suffix, so lock should have the hostname followed by an underscore as its
+// identity. Standby replicas pass, and so do errors reading the lock: the elector can't renew through
+// those either, and the manager exits by itself once the lease is lost.
+func LeaseRenewed(lock resourcelock.Interface) Check {
+	return func(_ context.Context) error {
+		record, err := lock.Get()
+		if err != nil || !strings.HasPrefix(record.HolderIdentity, lock.Identity()) {
+			return nil
+		}
+		expiry := record.RenewTime.Add(time.Duration(record.LeaseDurationSeconds) * time.Second)
+		if time.Now().After(expiry) {
+			return errors.Errorf("leader election lease not renewed since %s", record.RenewTime)
+		}
+		return nil
+	}
+}
+
+// Readiness check for the informer caches. Waiting on an already closed channel checks them once without
+// blocking. The manager only starts its caches once elected, so standby replicas stay unready, which
+// matches them not serving webhooks either.
+func CacheSynced(c cache.Cache) Check {
+	closed := make(chan struct{})
+	close(closed)
+	return func(_ context.Context) error {
+		if !c.WaitForCacheSync(closed) {
+			return errors.New("informer caches not synced")
+		}
+		return nil
+	}
+}
+
+// Readiness check that AWS is reachable with our credentials, using the same environment as the AWS
+// components.
+func AWSReachable() Check {
+	return func(ctx context.Context) error {
+		sess, err := session.NewSession()
+		if err != nil {
+			return errors.Wrap(err, "unable to create AWS session")
+		}
+		_, err = iam.New(sess).GetUserWithContext(ctx, &iam.GetUserInput{})
+		return errors.Wrap(err, "unable to reach AWS")
+	}
+}
+
+// Readiness check that Slack accepts our API key.
+func SlackReachable(apiKey string) Check {
+	return func(ctx context.Context) error {
+		if apiKey == "" {
+			return errors.New("no Slack API key")
+		}
+		_, err := slack.New(apiKey).AuthTestContext(ctx)
+		return errors.Wrap(err, "unable to reach Slack")
+	}
+}
+
+// Wrap a check which talks to something outside the cluster so it runs at most once per interval, no
+// matter how often the kubelet probes.
+func Cached(check Check, interval time.Duration) Check {
+	var mutex sync.Mutex
+	var lastRun time.Time
+	var lastErr error
+	return func(ctx context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !lastRun.IsZero() && time.Since(lastRun) < interval {
+			return lastErr
+		}
+		lastErr = check(ctx)
+		lastRun = time.Now()
+		return lastErr
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How long a single probe request may take, probes have their own timeouts on the kubelet side too.
+var CheckTimeout = 5 * time.Second
+
+// A single health check, returning an error if something is wrong.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Serves /healthz for the liveness probe and /readyz for the readiness probe. Each runs its own list of
+// checks. This runs outside the manager, whose runnables only start once this replica is the leader, so
+// standby replicas still answer their probes.
+type Server struct {
+	Addr string
+
+	mutex     sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+func NewServer(addr string) *Server {
+	return &Server{Addr: addr}
+}
+
+// Add a check which restarts the operator when it fails.
+func (s *Server) AddLivenessCheck(name string, check Check) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.liveness = append(s.liveness, namedCheck{name: name, check: check})
+}
+
+// Add a check which takes the operator out of the webhook Service when it fails.
+func (s *Server) AddReadinessCheck(name string, check Check) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.readiness = append(s.readiness, namedCheck{name: name, check: check})
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", s.checkHandler(func() []namedCheck { return s.liveness }))
+	mux.Handle("/readyz", s.checkHandler(func() []namedCheck { return s.readiness }))
+	return mux
+}
+
+func (s *Server) ListenAndServe() error {
+	return http.ListenAndServe(s.Addr, s.Handler())
+}
+
+// Run every check, the response lists each one so a failing probe is easy to debug with curl.
+func (s *Server) checkHandler(checks func() []namedCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.RLock()
+		toRun := checks()
+		s.mutex.RUnlock()
+
+		ctx, cancel := context.WithTimeout(r.Context(), CheckTimeout)
+		defer cancel()
+
+		failed := false
+		body := ""
+		for _, c := range toRun {
+			err := c.check(ctx)
+			if err != nil {
+				failed = true
+				body += fmt.Sprintf("[-] %s failed: %s\n", c.name, err)
+			} else {
+				body += fmt.Sprintf("[+] %s ok\n", c.name)
+			}
+		}
+		if failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			body += "check failed\n"
+		} else {
+			body += "ok\n"
+		}
+		w.Write([]byte(body))
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	"github.com/Ridecell/ridecell-operator/pkg/health"
+)
+
+// Only WaitForCacheSync is used by the checks.
+type fakeCache struct {
+	cache.Cache
+	synced bool
+}
+
+func (c *fakeCache) WaitForCacheSync(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+	default:
+		panic("WaitForCacheSync called with an open channel, it would block")
+	}
+	return c.synced
+}
+
+// Only Get and Identity are used by the checks.
+type fakeLock struct {
+	resourcelock.Interface
+	identity string
+	record   resourcelock.LeaderElectionRecord
+}
+
+func (l *fakeLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	return &l.record, nil
+}
+
+func (l *fakeLock) Identity() string {
+	return l.identity
+}
+
+var _ = Describe("health server", func() {
+	var server *health.Server
+
+	probe := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	BeforeEach(func() {
+		server = health.NewServer(":0")
+	})
+
+	It("passes with no checks", func() {
+		Expect(probe("/healthz").Code).To(Equal(200))
+		Expect(probe("/readyz").Code).To(Equal(200))
+	})
+
+	It("fails readiness when a readiness check fails", func() {
+		server.AddReadinessCheck("broken", func(_ context.Context) error { return errors.New("nope") })
+		Expect(probe("/healthz").Code).To(Equal(200))
+		w := probe("/readyz")
+		Expect(w.Code).To(Equal(500))
+		Expect(w.Body.String()).To(ContainSubstring("broken failed: nope"))
+	})
+
+	It("fails readiness until the caches have synced", func() {
+		c := &fakeCache{}
+		server.AddReadinessCheck("cache-sync", health.CacheSynced(c))
+		w := probe("/readyz")
+		Expect(w.Code).To(Equal(500))
+		Expect(w.Body.String()).To(ContainSubstring("cache-sync failed: informer caches not synced"))
+		c.synced = true
+		Expect(probe("/readyz").Code).To(Equal(200))
+	})
+
+	It("fails liveness when our lease is not renewed", func() {
+		lock := &fakeLock{identity: "operator-0_", record: resourcelock.LeaderElectionRecord{
+			HolderIdentity:       "operator-0_1234",
+			LeaseDurationSeconds: 15,
+			RenewTime:            metav1.Now(),
+		}}
+		server.AddLivenessCheck("leader-lease", health.LeaseRenewed(lock))
+		Expect(probe("/healthz").Code).To(Equal(200))
+		lock.record.RenewTime = metav1.NewTime(time.Now().Add(-time.Minute))
+		w := probe("/healthz")
+		Expect(w.Code).To(Equal(500))
+		Expect(w.Body.String()).To(ContainSubstring("leader-lease failed: leader election lease not renewed"))
+	})
+
+	It("passes liveness while another replica holds the lease", func() {
+		lock := &fakeLock{identity: "operator-1_", record: resourcelock.LeaderElectionRecord{
+			HolderIdentity:       "operator-0_1234",
+			LeaseDurationSeconds: 15,
+			RenewTime:            metav1.NewTime(time.Now().Add(-time.Minute)),
+		}}
+		server.AddLivenessCheck("leader-lease", health.LeaseRenewed(lock))
+		Expect(probe("/healthz").Code).To(Equal(200))
+	})
+
+	It("caches slow checks", func() {
+		calls := 0
+		check := health.Cached(func(_ context.Context) error {
+			calls++
+			return nil
+		}, time.Hour)
+		Expect(check(context.TODO())).To(Succeed())
+		Expect(check(context.TODO())).To(Succeed())
+		Expect(calls).To(Equal(1))
+	})
+})
@@ -0,0 +1,164 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An http.FileSystem which serves some files from memory on top of another filesystem. This lets
+// individual templates be overridden at runtime without rebuilding the embedded templates.
+type overlayFileSystem struct {
+	base  http.FileSystem
+	files map[string][]byte
+	// Used as the modification time of all overlay files.
+	created time.Time
+}
+
+// Make a filesystem which serves files from the map in preference to base. Map keys are paths like
+// "helpers/deployment.yml.tpl". New files are added to directory listings if their directory exists in
+// base. The returned filesystem is safe to use as a template cache key.
+func NewOverlayFileSystem(base http.FileSystem, files map[string][]byte) http.FileSystem {
+	overlay := &overlayFileSystem{base: base, files: map[string][]byte{}, created: time.Now()}
+	for name, data := range files {
+		overlay.files[cleanPath(name)] = data
+	}
+	return overlay
+}
+
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (fs *overlayFileSystem) Open(name string) (http.File, error) {
+	name = cleanPath(name)
+	data, ok := fs.files[name]
+	if ok {
+		return &overlayFile{Reader: bytes.NewReader(data), info: fs.fileInfo(name, data)}, nil
+	}
+	f, err := fs.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		return &overlayDir{File: f, fs: fs, dir: name}, nil
+	}
+	return f, nil
+}
+
+func (fs *overlayFileSystem) fileInfo(name string, data []byte) *overlayFileInfo {
+	return &overlayFileInfo{name: path.Base(name), size: int64(len(data)), modTime: fs.created}
+}
+
+// A file served from the overlay.
+type overlayFile struct {
+	*bytes.Reader
+	info *overlayFileInfo
+}
+
+func (f *overlayFile) Close() error {
+	return nil
+}
+
+func (f *overlayFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f *overlayFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+type overlayFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i *overlayFileInfo) Name() string       { return i.name }
+func (i *overlayFileInfo) Size() int64        { return i.size }
+func (i *overlayFileInfo) Mode() os.FileMode  { return 0444 }
+func (i *overlayFileInfo) ModTime() time.Time { return i.modTime }
+func (i *overlayFileInfo) IsDir() bool        { return false }
+func (i *overlayFileInfo) Sys() interface{}   { return nil }
+
+// A directory from the base filesystem with any overlay files in it added to the listing.
+type overlayDir struct {
+	http.File
+	fs      *overlayFileSystem
+	dir     string
+	entries []os.FileInfo
+	read    bool
+}
+
+func (d *overlayDir) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.read {
+		baseEntries, err := d.File.Readdir(0)
+		if err != nil {
+			return nil, err
+		}
+		seen := map[string]bool{}
+		for _, entry := range baseEntries {
+			name := path.Join(d.dir, entry.Name())
+			data, ok := d.fs.files[name]
+			if ok {
+				// Report the overlay version's size.
+				d.entries = append(d.entries, d.fs.fileInfo(name, data))
+			} else {
+				d.entries = append(d.entries, entry)
+			}
+			seen[name] = true
+		}
+		added := []string{}
+		for name := range d.fs.files {
+			if path.Dir("/"+name) == path.Clean("/"+d.dir) && !seen[name] {
+				added = append(added, name)
+			}
+		}
+		sort.Strings(added)
+		for _, name := range added {
+			d.entries = append(d.entries, d.fs.fileInfo(name, d.fs.files[name]))
+		}
+		d.read = true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
@@ -20,6 +20,10 @@ import (
 	"bytes"
 	"net/http"
 	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
@@ -32,6 +36,75 @@ import (
 
 var logger = log.Log.WithName("template")
 
+// Parsed templates are cached because parsing every helper on every reconcile adds up fast. A parsed
+// template can be executed concurrently so they are shared between all callers.
+type cacheKey struct {
+	fs       interface{}
+	filename string
+}
+
+var cache = struct {
+	sync.RWMutex
+	templates map[cacheKey]*template.Template
+}{templates: map[cacheKey]*template.Template{}}
+
+// Get something usable as a map key for a filesystem. Things like vfsgen's map-based filesystems aren't
+// comparable, so for reference types we use the address instead. Returns false if there is no safe key,
+// in which case the filesystem isn't cached.
+func fsKey(fs http.FileSystem) (interface{}, bool) {
+	value := reflect.ValueOf(fs)
+	switch value.Kind() {
+	case reflect.Map, reflect.Ptr, reflect.Slice, reflect.Func, reflect.Chan:
+		return struct {
+			typ reflect.Type
+			ptr uintptr
+		}{value.Type(), value.Pointer()}, true
+	}
+	if !value.Type().Comparable() {
+		return nil, false
+	}
+	return fs, true
+}
+
+// Drop all cached templates for a filesystem. Call this when the files in a filesystem change at runtime.
+func Invalidate(fs http.FileSystem) {
+	key, ok := fsKey(fs)
+	if !ok {
+		return
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	for k := range cache.templates {
+		if k.fs == key {
+			delete(cache.templates, k)
+		}
+	}
+}
+
+func getTemplate(fs http.FileSystem, filename string) (*template.Template, error) {
+	fsID, cacheable := fsKey(fs)
+	if !cacheable {
+		return parseTemplate(fs, filename)
+	}
+	key := cacheKey{fs: fsID, filename: filename}
+	cache.RLock()
+	tmpl, ok := cache.templates[key]
+	cache.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	// Two callers might both parse the same template at once, but that is harmless.
+	tmpl, err := parseTemplate(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+	cache.Lock()
+	cache.templates[key] = tmpl
+	cache.Unlock()
+	return tmpl, nil
+}
+
 func parseTemplate(fs http.FileSystem, filename string) (*template.Template, error) {
 	// Create a template object.
 	tmpl := template.New(path.Base(filename))
@@ -90,9 +163,28 @@ func parseObject(rawObject string) (runtime.Object, error) {
 	return obj, nil
 }
 
+// Matches a YAML document separator line.
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// Split rendered output into YAML documents, dropping any that are empty or only comments. These show
+// up naturally when a template ranges over an empty list.
+func splitDocuments(rawObjects string) []string {
+	documents := []string{}
+	for _, document := range documentSeparator.Split(rawObjects, -1) {
+		for _, line := range strings.Split(document, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				documents = append(documents, document)
+				break
+			}
+		}
+	}
+	return documents
+}
+
 func Get(fs http.FileSystem, filename string, data interface{}) (runtime.Object, error) {
 	logger := logger.WithValues("template", filename)
-	tmpl, err := parseTemplate(fs, filename)
+	tmpl, err := getTemplate(fs, filename)
 	if err != nil {
 		logger.Error(err, "failed parsing template")
 		return nil, err
@@ -110,3 +202,28 @@ func Get(fs http.FileSystem, filename string, data interface{}) (runtime.Object,
 
 	return obj, nil
 }
+
+// Like Get, but for templates which render any number of objects separated by "---".
+func GetList(fs http.FileSystem, filename string, data interface{}) ([]runtime.Object, error) {
+	logger := logger.WithValues("template", filename)
+	tmpl, err := getTemplate(fs, filename)
+	if err != nil {
+		logger.Error(err, "failed parsing template")
+		return nil, err
+	}
+	out, err := renderTemplate(tmpl, data)
+	if err != nil {
+		logger.WithValues("data", data).Error(err, "failed rendering data")
+		return nil, err
+	}
+	objs := []runtime.Object{}
+	for _, document := range splitDocuments(out) {
+		obj, err := parseObject(document)
+		if err != nil {
+			logger.WithValues("object", document).Error(err, "failed parsing raw")
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates_test
+
+import (
+	"testing"
+
+	"github.com/Ridecell/ridecell-operator/pkg/templates"
+)
+
+type benchmarkData struct {
+	Name string
+}
+
+func BenchmarkGetCached(b *testing.B) {
+	data := benchmarkData{Name: "bench"}
+	for i := 0; i < b.N; i++ {
+		_, err := templates.Get(testTemplates, "test3.yml.tpl", data)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetUncached(b *testing.B) {
+	data := benchmarkData{Name: "bench"}
+	for i := 0; i < b.N; i++ {
+		// Same as the behavior before the cache existed.
+		templates.Invalidate(testTemplates)
+		_, err := templates.Get(testTemplates, "test3.yml.tpl", data)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetCachedParallel(b *testing.B) {
+	data := benchmarkData{Name: "bench"}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, err := templates.Get(testTemplates, "test3.yml.tpl", data)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
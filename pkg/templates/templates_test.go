@@ -17,7 +17,10 @@ limitations under the License.
 package templates_test
 
 import (
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -62,4 +65,91 @@ var _ = Describe("Templates", func() {
 			Expect(deployment.Spec.Replicas).To(PointTo(BeEquivalentTo(1)))
 		})
 	})
+
+	Context("a multi-document template", func() {
+		It("should render all the Deployments", func() {
+			rawObjects, err := templates.GetList(testTemplates, "test4.yml.tpl", struct{ Names []string }{Names: []string{"one", "two"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawObjects).To(HaveLen(2))
+			Expect(rawObjects[0].(*appsv1.Deployment).Name).To(Equal("test-one"))
+			Expect(rawObjects[1].(*appsv1.Deployment).Name).To(Equal("test-two"))
+		})
+
+		It("should render nothing for an empty list", func() {
+			rawObjects, err := templates.GetList(testTemplates, "test4.yml.tpl", struct{ Names []string }{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawObjects).To(BeEmpty())
+		})
+	})
+
+	Context("the template cache", func() {
+		var dir string
+		var fs http.FileSystem
+
+		writeTemplate := func(name string) {
+			body := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: " + name + "\n"
+			err := ioutil.WriteFile(filepath.Join(dir, "cached.yml.tpl"), []byte(body), 0644)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		getName := func() string {
+			rawObject, err := templates.Get(fs, "cached.yml.tpl", struct{}{})
+			Expect(err).ToNot(HaveOccurred())
+			return rawObject.(*appsv1.Deployment).Name
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "templates")
+			Expect(err).ToNot(HaveOccurred())
+			fs = http.Dir(dir)
+		})
+
+		AfterEach(func() {
+			templates.Invalidate(fs)
+			os.RemoveAll(dir)
+		})
+
+		It("reuses the parsed template", func() {
+			writeTemplate("first")
+			Expect(getName()).To(Equal("first"))
+			writeTemplate("second")
+			Expect(getName()).To(Equal("first"))
+		})
+
+		It("reparses after the filesystem is invalidated", func() {
+			writeTemplate("first")
+			Expect(getName()).To(Equal("first"))
+			writeTemplate("second")
+			templates.Invalidate(fs)
+			Expect(getName()).To(Equal("second"))
+		})
+	})
+
+	Context("an overlay filesystem", func() {
+		It("serves overridden templates", func() {
+			overlay := templates.NewOverlayFileSystem(testTemplates, map[string][]byte{
+				"test1.yml.tpl": []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: overridden\n"),
+			})
+			rawObject, err := templates.Get(overlay, "test1.yml.tpl", struct{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawObject.(*appsv1.Deployment).Name).To(Equal("overridden"))
+		})
+
+		It("falls back to the base filesystem", func() {
+			overlay := templates.NewOverlayFileSystem(testTemplates, map[string][]byte{})
+			rawObject, err := templates.Get(overlay, "test3.yml.tpl", struct{ Name string }{Name: "tres"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawObject.(*appsv1.Deployment).Name).To(Equal("test-tres"))
+		})
+
+		It("adds new helpers", func() {
+			overlay := templates.NewOverlayFileSystem(testTemplates, map[string][]byte{
+				"helpers/replicas.yml.tpl": []byte(`{{ define "replicas" }}3{{ end }}`),
+			})
+			rawObject, err := templates.Get(overlay, "test2.yml.tpl", struct{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawObject.(*appsv1.Deployment).Spec.Replicas).To(PointTo(BeEquivalentTo(3)))
+		})
+	})
 })
@@ -0,0 +1,9 @@
+# Leading comment, which shouldn't count as an object.
+---
+{{- range .Names }}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-{{ . }}
+{{- end }}
@@ -41,10 +41,19 @@ func ReconcileErrorContext(ctx *components.ComponentContext, inErr error) types.
 	return &reconcileContextMatcher{ctx: ctx, inErr: inErr}
 }
 
+// Gomega matcher for checking if a component finalizes correctly.
+//
+//   comp := MyComponent()
+//   Expect(comp).To(FinalizeContext(ctx))
+func FinalizeContext(ctx *components.ComponentContext) types.GomegaMatcher {
+	return &reconcileContextMatcher{ctx: ctx, finalize: true}
+}
+
 type reconcileContextMatcher struct {
-	ctx   *components.ComponentContext
-	inErr error
-	err   error
+	ctx      *components.ComponentContext
+	inErr    error
+	finalize bool
+	err      error
 }
 
 // Match implements GomegaMatcher
@@ -60,6 +69,8 @@ func (matcher *reconcileContextMatcher) Match(actual interface{}) (bool, error)
 	var err error
 	if matcher.inErr != nil {
 		result, err = comp.(components.ErrorHandler).ReconcileError(matcher.ctx, matcher.inErr)
+	} else if matcher.finalize {
+		result, err = comp.(components.Finalizer).Finalize(matcher.ctx)
 	} else {
 		result, err = comp.Reconcile(matcher.ctx)
 	}
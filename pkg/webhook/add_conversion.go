@@ -0,0 +1,37 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	summonv1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+func init() {
+	conversions = append(conversions, &conversion{
+		group:    "summon.ridecell.io",
+		kind:     "SummonPlatform",
+		resource: "summonplatforms",
+		hub:      "v1beta1",
+		versions: map[string]func() runtime.Object{
+			"v1beta1": func() runtime.Object { return &summonv1beta1.SummonPlatform{} },
+			"v1":      func() runtime.Object { return &summonv1.SummonPlatform{} },
+		},
+	})
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
+	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	summonv1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+func init() {
+	WebhookFuncs = append(WebhookFuncs,
+		validating("summon.ridecell.io", "v1beta1", "summonplatforms", func() Validator { return &summonv1beta1.SummonPlatform{} }),
+		validating("summon.ridecell.io", "v1", "summonplatforms", func() Validator { return &summonv1.SummonPlatform{} }),
+		validating("aws.ridecell.io", "v1beta1", "iamusers", func() Validator { return &awsv1beta1.IAMUser{} }),
+		validating("aws.ridecell.io", "v1beta1", "s3buckets", func() Validator { return &awsv1beta1.S3Bucket{} }),
+		validating("secrets.ridecell.io", "v1beta1", "encryptedsecrets", func() Validator { return &secretsv1beta1.EncryptedSecret{} }),
+	)
+}
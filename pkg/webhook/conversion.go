@@ -0,0 +1,228 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+// Path the API server sends ConversionReviews to.
+const ConversionPath = "/convert"
+
+// The webhook server only builds configurations for mutating and validating webhooks, anything else is
+// just served.
+const webhookTypeConversion types.WebhookType = 100
+
+// The version of an API type everything else converts through, normally the storage version.
+type Hub interface {
+	runtime.Object
+	Hub()
+}
+
+// Implemented by every other version of an API type.
+type Convertible interface {
+	runtime.Object
+	ConvertTo(hub runtime.Object) error
+	ConvertFrom(hub runtime.Object) error
+}
+
+// All the versions of one resource which can be converted between.
+type conversion struct {
+	group    string
+	kind     string
+	resource string
+	hub      string
+	versions map[string]func() runtime.Object
+}
+
+// Set up by add_conversion.go.
+var conversions []*conversion
+
+// ConversionReview and friends from apiextensions.k8s.io/v1beta1. The apiextensions package we build
+// against predates CRD conversion, so these mirror the wire format.
+type ConversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *ConversionRequest  `json:"request,omitempty"`
+	Response        *ConversionResponse `json:"response,omitempty"`
+}
+
+type ConversionRequest struct {
+	UID               apitypes.UID           `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type ConversionResponse struct {
+	UID              apitypes.UID           `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// Serves ConversionReviews for every registered conversion.
+type conversionWebhook struct {
+	conversions []*conversion
+}
+
+func (wh *conversionWebhook) GetName() string {
+	return "convert.ridecell.io"
+}
+
+func (wh *conversionWebhook) GetPath() string {
+	return ConversionPath
+}
+
+func (wh *conversionWebhook) GetType() types.WebhookType {
+	return webhookTypeConversion
+}
+
+func (wh *conversionWebhook) Handler() http.Handler {
+	return wh
+}
+
+func (wh *conversionWebhook) Validate() error {
+	for _, c := range wh.conversions {
+		err := c.validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wh *conversionWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &ConversionReview{}
+	err := json.NewDecoder(r.Body).Decode(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "no conversion request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = wh.convertAll(review.Request)
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (wh *conversionWebhook) convertAll(req *ConversionRequest) *ConversionResponse {
+	resp := &ConversionResponse{UID: req.UID}
+	for _, obj := range req.Objects {
+		converted, err := wh.convert(obj.Raw, req.DesiredAPIVersion)
+		if err != nil {
+			resp.ConvertedObjects = nil
+			resp.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			return resp
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	resp.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return resp
+}
+
+// Convert one object by going through the hub version.
+func (wh *conversionWebhook) convert(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	typeMeta := &metav1.TypeMeta{}
+	err := json.Unmarshal(raw, typeMeta)
+	if err != nil {
+		return nil, errors.Wrap(err, "conversion: unable to decode type")
+	}
+	srcGVK := typeMeta.GroupVersionKind()
+	dstGV, err := schema.ParseGroupVersion(desiredAPIVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "conversion: unable to parse desired API version")
+	}
+	if srcGVK.GroupVersion() == dstGV {
+		return raw, nil
+	}
+
+	var conv *conversion
+	for _, c := range wh.conversions {
+		if c.group == srcGVK.Group && c.kind == srcGVK.Kind {
+			conv = c
+			break
+		}
+	}
+	if conv == nil || dstGV.Group != conv.group {
+		return nil, errors.Errorf("conversion: no conversion from %s to %s", srcGVK, desiredAPIVersion)
+	}
+	newSrc, ok := conv.versions[srcGVK.Version]
+	if !ok {
+		return nil, errors.Errorf("conversion: unknown version %s", srcGVK)
+	}
+	newDst, ok := conv.versions[dstGV.Version]
+	if !ok {
+		return nil, errors.Errorf("conversion: unknown version %s", desiredAPIVersion)
+	}
+
+	src := newSrc()
+	err = json.Unmarshal(raw, src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "conversion: unable to decode %s", srcGVK)
+	}
+
+	hub := src
+	if srcGVK.Version != conv.hub {
+		hub = conv.versions[conv.hub]()
+		err = src.(Convertible).ConvertTo(hub)
+		if err != nil {
+			return nil, errors.Wrapf(err, "conversion: unable to convert %s to %s", srcGVK, conv.hub)
+		}
+	}
+	dst := hub
+	if dstGV.Version != conv.hub {
+		dst = newDst()
+		err = dst.(Convertible).ConvertFrom(hub)
+		if err != nil {
+			return nil, errors.Wrapf(err, "conversion: unable to convert %s to %s", conv.hub, desiredAPIVersion)
+		}
+	}
+	dst.GetObjectKind().SetGroupVersionKind(dstGV.WithKind(conv.kind))
+	return json.Marshal(dst)
+}
+
+// Check every non-hub version of a conversion really can be converted.
+func (c *conversion) validate() error {
+	_, ok := c.versions[c.hub]().(Hub)
+	if !ok {
+		return fmt.Errorf("%s/%s %s does not implement Hub", c.group, c.hub, c.kind)
+	}
+	for version, newObject := range c.versions {
+		if version == c.hub {
+			continue
+		}
+		_, ok := newObject().(Convertible)
+		if !ok {
+			return fmt.Errorf("%s/%s %s does not implement Convertible", c.group, version, c.kind)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	summonv1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+func convertReview(wh *conversionWebhook, desiredAPIVersion string, objs ...runtime.Object) *ConversionResponse {
+	review := &ConversionReview{Request: &ConversionRequest{UID: "1234", DesiredAPIVersion: desiredAPIVersion}}
+	for _, obj := range objs {
+		raw, err := json.Marshal(obj)
+		Expect(err).ToNot(HaveOccurred())
+		review.Request.Objects = append(review.Request.Objects, runtime.RawExtension{Raw: raw})
+	}
+	body, err := json.Marshal(review)
+	Expect(err).ToNot(HaveOccurred())
+
+	w := httptest.NewRecorder()
+	wh.ServeHTTP(w, httptest.NewRequest("POST", ConversionPath, bytes.NewReader(body)))
+	Expect(w.Code).To(Equal(200))
+	respReview := &ConversionReview{}
+	err = json.Unmarshal(w.Body.Bytes(), respReview)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(respReview.Response).ToNot(BeNil())
+	Expect(respReview.Response.UID).To(BeEquivalentTo("1234"))
+	return respReview.Response
+}
+
+var _ = Describe("conversion webhook", func() {
+	var wh *conversionWebhook
+	var instance *summonv1beta1.SummonPlatform
+
+	BeforeEach(func() {
+		wh = &conversionWebhook{conversions: conversions}
+		replicas := int32(2)
+		instance = &summonv1beta1.SummonPlatform{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "summon.ridecell.io/v1beta1", Kind: "SummonPlatform"},
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: summonv1beta1.SummonPlatformSpec{
+				Version:        "1234-eb6b515-master",
+				WorkerReplicas: &replicas,
+			},
+		}
+	})
+
+	It("is valid", func() {
+		Expect(wh.Validate()).To(Succeed())
+	})
+
+	It("converts v1beta1 to v1", func() {
+		resp := convertReview(wh, "summon.ridecell.io/v1", instance)
+		Expect(resp.Result.Status).To(Equal(metav1.StatusSuccess))
+		Expect(resp.ConvertedObjects).To(HaveLen(1))
+		converted := &summonv1.SummonPlatform{}
+		err := json.Unmarshal(resp.ConvertedObjects[0].Raw, converted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(converted.APIVersion).To(Equal("summon.ridecell.io/v1"))
+		Expect(converted.Kind).To(Equal("SummonPlatform"))
+		Expect(converted.Name).To(Equal("foo"))
+		Expect(*converted.Spec.Replicas.Celeryd).To(BeEquivalentTo(2))
+	})
+
+	It("converts v1 back to v1beta1", func() {
+		resp := convertReview(wh, "summon.ridecell.io/v1", instance)
+		obj := &unstructured.Unstructured{}
+		err := json.Unmarshal(resp.ConvertedObjects[0].Raw, obj)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp = convertReview(wh, "summon.ridecell.io/v1beta1", obj)
+		Expect(resp.Result.Status).To(Equal(metav1.StatusSuccess))
+		converted := &summonv1beta1.SummonPlatform{}
+		err = json.Unmarshal(resp.ConvertedObjects[0].Raw, converted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(converted.Spec).To(Equal(instance.Spec))
+	})
+
+	It("passes through objects already in the desired version", func() {
+		resp := convertReview(wh, "summon.ridecell.io/v1beta1", instance)
+		Expect(resp.Result.Status).To(Equal(metav1.StatusSuccess))
+		converted := &summonv1beta1.SummonPlatform{}
+		err := json.Unmarshal(resp.ConvertedObjects[0].Raw, converted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(converted).To(Equal(instance))
+	})
+
+	It("fails on an unknown version", func() {
+		resp := convertReview(wh, "summon.ridecell.io/v2", instance)
+		Expect(resp.Result.Status).To(Equal(metav1.StatusFailure))
+		Expect(resp.ConvertedObjects).To(BeEmpty())
+	})
+})
+
+var _ = Describe("CRD installer", func() {
+	It("adds the extra versions and conversion settings", func() {
+		crd := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"version": "v1beta1",
+			},
+		}}
+		conversionConfig := map[string]interface{}{"strategy": "Webhook"}
+		err := setConversion(crd, conversions[0], conversionConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(HaveLen(2))
+		Expect(versions[0]).To(HaveKeyWithValue("name", "v1beta1"))
+		Expect(versions[0]).To(HaveKeyWithValue("storage", true))
+		Expect(versions[1]).To(HaveKeyWithValue("name", "v1"))
+		Expect(versions[1]).To(HaveKeyWithValue("storage", false))
+		strategy, _, err := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strategy).To(Equal("Webhook"))
+	})
+})
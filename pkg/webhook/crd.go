@@ -0,0 +1,151 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var logger = log.Log.WithName("webhook")
+
+// Key the webhook server stores its CA certificate under in the cert Secret.
+const caCertKey = "ca-cert.pem"
+
+// Adds the extra versions and the conversion webhook to our CRDs. controller-gen only knows about one
+// version per CRD, and the CA bundle isn't known until the webhook server has made its certificate, so
+// this runs in the manager rather than living in the manifests.
+type crdInstaller struct {
+	client      client.Client
+	namespace   string
+	secretName  string
+	serviceName string
+	conversions []*conversion
+}
+
+func (i *crdInstaller) Start(stop <-chan struct{}) error {
+	return wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+		err := i.install()
+		if err != nil {
+			logger.Error(err, "unable to install CRD conversion, retrying")
+			return false, nil
+		}
+		return true, nil
+	}, stop)
+}
+
+func (i *crdInstaller) install() error {
+	secret := &corev1.Secret{}
+	err := i.client.Get(context.TODO(), client.ObjectKey{Namespace: i.namespace, Name: i.secretName}, secret)
+	if err != nil {
+		return errors.Wrapf(err, "webhook: unable to get cert secret %s/%s", i.namespace, i.secretName)
+	}
+	caCert, ok := secret.Data[caCertKey]
+	if !ok {
+		return errors.Errorf("webhook: cert secret %s/%s has no %s", i.namespace, i.secretName, caCertKey)
+	}
+
+	for _, c := range i.conversions {
+		crd := &unstructured.Unstructured{}
+		crd.SetAPIVersion("apiextensions.k8s.io/v1beta1")
+		crd.SetKind("CustomResourceDefinition")
+		name := fmt.Sprintf("%s.%s", c.resource, c.group)
+		err = i.client.Get(context.TODO(), client.ObjectKey{Name: name}, crd)
+		if err != nil {
+			return errors.Wrapf(err, "webhook: unable to get CRD %s", name)
+		}
+		err = setConversion(crd, c, i.conversionConfig(caCert))
+		if err != nil {
+			return errors.Wrapf(err, "webhook: unable to set conversion on CRD %s", name)
+		}
+		err = i.client.Update(context.TODO(), crd)
+		if err != nil {
+			return errors.Wrapf(err, "webhook: unable to update CRD %s", name)
+		}
+		logger.Info("installed CRD conversion", "crd", name)
+	}
+	return nil
+}
+
+func (i *crdInstaller) conversionConfig(caCert []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"strategy": "Webhook",
+		"webhookClientConfig": map[string]interface{}{
+			"caBundle": base64.StdEncoding.EncodeToString(caCert),
+			"service": map[string]interface{}{
+				"namespace": i.namespace,
+				"name":      i.serviceName,
+				"path":      ConversionPath,
+			},
+		},
+	}
+}
+
+// Make sure every version of the conversion is served, keeping the hub as the storage version.
+func setConversion(crd *unstructured.Unstructured, c *conversion, conversionConfig map[string]interface{}) error {
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := version["name"].(string)
+		seen[name] = true
+	}
+	for name := range c.versions {
+		if !seen[name] {
+			versions = append(versions, map[string]interface{}{
+				"name":    name,
+				"served":  true,
+				"storage": name == c.hub,
+			})
+		}
+	}
+
+	// The old single version field has to match the first entry in versions, which must be the hub.
+	hubFirst := []interface{}{}
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		if version["name"] == c.hub {
+			hubFirst = append([]interface{}{v}, hubFirst...)
+		} else {
+			hubFirst = append(hubFirst, v)
+		}
+	}
+	err = unstructured.SetNestedSlice(crd.Object, hubFirst, "spec", "versions")
+	if err != nil {
+		return err
+	}
+	err = unstructured.SetNestedField(crd.Object, c.hub, "spec", "version")
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(crd.Object, conversionConfig, "spec", "conversion")
+}
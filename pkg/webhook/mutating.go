@@ -0,0 +1,88 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	atypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+// Implemented by API types which can fill in their own defaults. The defaults component calls the same
+// method, so objects saved before the webhook existed still get them.
+type Defaulter interface {
+	runtime.Object
+	Default()
+}
+
+// Patches defaults into objects so they are saved along with the rest of the spec.
+type mutatingHandler struct {
+	newObject func() Defaulter
+}
+
+func (h *mutatingHandler) Handle(_ context.Context, req atypes.Request) atypes.Response {
+	obj := h.newObject()
+	err := json.Unmarshal(req.AdmissionRequest.Object.Raw, obj)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	// Clients can leave the namespace out of the body and only give it in the URL. Fill it in before
+	// defaulting, several defaults are built from it.
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	if accessor.GetNamespace() == "" {
+		accessor.SetNamespace(req.AdmissionRequest.Namespace)
+	}
+
+	original := obj.DeepCopyObject()
+	obj.Default()
+	return admission.PatchResponse(original, obj)
+}
+
+// Make a function for WebhookFuncs which defaults creates and updates of one resource.
+func mutating(group, version, resource string, newObject func() Defaulter) func(manager.Manager) (*admission.Webhook, error) {
+	return func(_ manager.Manager) (*admission.Webhook, error) {
+		failurePolicy := admissionregistrationv1beta1.Fail
+		return &admission.Webhook{
+			Name: fmt.Sprintf("default-%s.%s", resource, group),
+			Path: fmt.Sprintf("/mutate-%s-%s-%s", group, version, resource),
+			Type: types.WebhookTypeMutating,
+			Rules: []admissionregistrationv1beta1.RuleWithOperations{{
+				Operations: []admissionregistrationv1beta1.OperationType{admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update},
+				Rule: admissionregistrationv1beta1.Rule{
+					APIGroups:   []string{group},
+					APIVersions: []string{version},
+					Resources:   []string{resource},
+				},
+			}},
+			FailurePolicy: &failurePolicy,
+			Handlers:      []admission.Handler{&mutatingHandler{newObject: newObject}},
+		}, nil
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+var _ = Describe("mutating webhook", func() {
+	var handler *mutatingHandler
+	var instance *summonv1beta1.SummonPlatform
+
+	BeforeEach(func() {
+		handler = &mutatingHandler{newObject: func() Defaulter { return &summonv1beta1.SummonPlatform{} }}
+		instance = &summonv1beta1.SummonPlatform{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       summonv1beta1.SummonPlatformSpec{Version: "1234-eb6b515-master"},
+		}
+	})
+
+	It("patches in the SummonPlatform defaults", func() {
+		resp := handler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeTrue())
+		paths := []string{}
+		for _, patch := range resp.Patches {
+			paths = append(paths, patch.Path)
+		}
+		Expect(paths).To(ContainElement("/spec/hostname"))
+		Expect(paths).To(ContainElement("/spec/pullSecret"))
+	})
+
+	It("uses the request namespace when the object leaves it out", func() {
+		instance.Namespace = ""
+		req := admissionRequest(admissionv1beta1.Create, instance, nil)
+		req.AdmissionRequest.Namespace = "qa"
+		resp := handler.Handle(context.TODO(), req)
+		Expect(resp.Response.Allowed).To(BeTrue())
+		values := map[string]interface{}{}
+		for _, patch := range resp.Patches {
+			values[patch.Path] = patch.Value
+		}
+		Expect(values).To(HaveKeyWithValue("/spec/secrets", []interface{}{"qa", "foo"}))
+		Expect(values).To(HaveKeyWithValue("/spec/database/sharedDatabaseName", "qa"))
+		Expect(values).ToNot(HaveKey("/metadata/namespace"))
+	})
+
+	It("leaves name based defaults alone for generateName objects", func() {
+		instance.Name = ""
+		instance.GenerateName = "foo-"
+		resp := handler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeTrue())
+		paths := []string{}
+		for _, patch := range resp.Patches {
+			paths = append(paths, patch.Path)
+		}
+		Expect(paths).ToNot(ContainElement("/spec/hostname"))
+		Expect(paths).ToNot(ContainElement("/spec/secrets"))
+		Expect(paths).To(ContainElement("/spec/pullSecret"))
+	})
+
+	It("does not override values already set", func() {
+		instance.Default()
+		instance.Spec.Hostname = "foo.example.com"
+		resp := handler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Update, instance, instance))
+		Expect(resp.Response.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+})
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	atypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+// Implemented by API types which can check their own spec. These are the same checks the components
+// rely on, so bad objects are rejected at apply time rather than failing a reconcile.
+type Validator interface {
+	runtime.Object
+	Validate() error
+}
+
+// An optional interface for Validators which also need to check what changed in an update.
+type UpdateValidator interface {
+	ValidateUpdate(old runtime.Object) error
+}
+
+// Rejects objects which fail their own validation.
+type validatingHandler struct {
+	newObject func() Validator
+}
+
+func (h *validatingHandler) Handle(_ context.Context, req atypes.Request) atypes.Response {
+	obj := h.newObject()
+	err := json.Unmarshal(req.AdmissionRequest.Object.Raw, obj)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	updateValidator, ok := obj.(UpdateValidator)
+	if ok && req.AdmissionRequest.Operation == admissionv1beta1.Update {
+		old := h.newObject()
+		err = json.Unmarshal(req.AdmissionRequest.OldObject.Raw, old)
+		if err != nil {
+			return admission.ErrorResponse(http.StatusBadRequest, err)
+		}
+		err = updateValidator.ValidateUpdate(old)
+	} else {
+		err = obj.Validate()
+	}
+	if err != nil {
+		return admission.ValidationResponse(false, err.Error())
+	}
+	return admission.ValidationResponse(true, "")
+}
+
+// Make a function for WebhookFuncs which validates creates and updates of one resource.
+func validating(group, version, resource string, newObject func() Validator) func(manager.Manager) (*admission.Webhook, error) {
+	return func(_ manager.Manager) (*admission.Webhook, error) {
+		failurePolicy := admissionregistrationv1beta1.Fail
+		return &admission.Webhook{
+			Name: fmt.Sprintf("validate-%s.%s", resource, group),
+			Path: fmt.Sprintf("/validate-%s-%s-%s", group, version, resource),
+			Type: types.WebhookTypeValidating,
+			Rules: []admissionregistrationv1beta1.RuleWithOperations{{
+				Operations: []admissionregistrationv1beta1.OperationType{admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update},
+				Rule: admissionregistrationv1beta1.Rule{
+					APIGroups:   []string{group},
+					APIVersions: []string{version},
+					Resources:   []string{resource},
+				},
+			}},
+			FailurePolicy: &failurePolicy,
+			Handlers:      []admission.Handler{&validatingHandler{newObject: newObject}},
+		}, nil
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	atypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+
+	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
+	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+func admissionRequest(operation admissionv1beta1.Operation, obj, old runtime.Object) atypes.Request {
+	req := &admissionv1beta1.AdmissionRequest{Operation: operation}
+	raw, err := json.Marshal(obj)
+	Expect(err).ToNot(HaveOccurred())
+	req.Object.Raw = raw
+	if old != nil {
+		raw, err = json.Marshal(old)
+		Expect(err).ToNot(HaveOccurred())
+		req.OldObject.Raw = raw
+	}
+	return atypes.Request{AdmissionRequest: req}
+}
+
+var _ = Describe("validating webhook", func() {
+	var summonHandler *validatingHandler
+	var instance *summonv1beta1.SummonPlatform
+
+	BeforeEach(func() {
+		summonHandler = &validatingHandler{newObject: func() Validator { return &summonv1beta1.SummonPlatform{} }}
+		instance = &summonv1beta1.SummonPlatform{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       summonv1beta1.SummonPlatformSpec{Version: "1234-eb6b515-master"},
+		}
+	})
+
+	It("allows a valid SummonPlatform", func() {
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeTrue())
+	})
+
+	It("rejects a malformed version", func() {
+		instance.Spec.Version = "latest"
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.version"))
+	})
+
+	It("rejects negative replicas", func() {
+		replicas := int32(-1)
+		instance.Spec.WebReplicas = &replicas
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.webReplicas"))
+	})
+
+	It("rejects an unknown rollout strategy", func() {
+		instance.Spec.Rollout.Strategy = "Yolo"
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.rollout.strategy"))
+	})
+
+	It("rejects a canary weight over 100", func() {
+		instance.Spec.Rollout.Strategy = summonv1beta1.RolloutCanary
+		instance.Spec.Rollout.CanaryWeight = 150
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.rollout.canaryWeight"))
+	})
+
+	It("rejects an autoscaler with minReplicas over maxReplicas", func() {
+		minReplicas := int32(3)
+		instance.Spec.Autoscaling.Web = &summonv1beta1.AutoscalerSpec{MinReplicas: &minReplicas, MaxReplicas: 2}
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.autoscaling.web.minReplicas"))
+	})
+
+	It("rejects an invalid override env var name", func() {
+		instance.Spec.Overrides.Web.Env = []corev1.EnvVar{{Name: "1BAD", Value: "x"}}
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.overrides.web.env[0].name"))
+	})
+
+	It("rejects duplicate hook names", func() {
+		hook := summonv1beta1.HookSpec{Name: "warmup", Phase: summonv1beta1.HookPostDeploy, Command: "python manage.py warmup"}
+		instance.Spec.Hooks = []summonv1beta1.HookSpec{hook, hook}
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, instance, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.hooks[1].name"))
+	})
+
+	It("rejects changing exclusiveDatabase", func() {
+		old := instance.DeepCopy()
+		instance.Spec.Database.ExclusiveDatabase = true
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Update, instance, old))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.database.exclusiveDatabase"))
+	})
+
+	It("allows other updates", func() {
+		old := instance.DeepCopy()
+		instance.Spec.Version = "1235-eb6b515-master"
+		resp := summonHandler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Update, instance, old))
+		Expect(resp.Response.Allowed).To(BeTrue())
+	})
+
+	It("rejects an IAMUser with an invalid inline policy", func() {
+		handler := &validatingHandler{newObject: func() Validator { return &awsv1beta1.IAMUser{} }}
+		user := &awsv1beta1.IAMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       awsv1beta1.IAMUserSpec{InlinePolicies: map[string]string{"test": `{"Version": `}},
+		}
+		resp := handler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, user, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("spec.inlinePolicies[test]"))
+	})
+
+	It("rejects an S3Bucket with an invalid policy", func() {
+		handler := &validatingHandler{newObject: func() Validator { return &awsv1beta1.S3Bucket{} }}
+		bucket := &awsv1beta1.S3Bucket{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       awsv1beta1.S3BucketSpec{BucketPolicy: "{"},
+		}
+		resp := handler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, bucket, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+	})
+
+	It("rejects an EncryptedSecret with an empty value", func() {
+		handler := &validatingHandler{newObject: func() Validator { return &secretsv1beta1.EncryptedSecret{} }}
+		secret := &secretsv1beta1.EncryptedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Data:       map[string]string{"KEY": ""},
+		}
+		resp := handler.Handle(context.TODO(), admissionRequest(admissionv1beta1.Create, secret, nil))
+		Expect(resp.Response.Allowed).To(BeFalse())
+		Expect(string(resp.Response.Result.Reason)).To(ContainSubstring("does not have a value"))
+	})
+})
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Set from the manager's --webhook-port flag.
+var Port int32 = 9876
+
+// Where the serving certificate is written. It is generated on startup and stored in a Secret so every
+// replica uses the same one.
+var CertDir = "/tmp/cert"
+
+// WebhookFuncs is a list of functions to create all the admission webhooks served by the manager.
+var WebhookFuncs []func(manager.Manager) (*admission.Webhook, error)
+
+const (
+	certSecretName = "ridecell-operator-webhook-cert"
+	serviceName    = "ridecell-operator-webhook"
+)
+
+// AddToManager starts a webhook server in the Manager and registers every webhook with it. The server also
+// installs the webhook configurations and the Service in front of it, and the CRD conversions are
+// installed once the certificate exists.
+func AddToManager(mgr manager.Manager) error {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = "ridecell-operator"
+	}
+	svr, err := webhook.NewServer("ridecell-operator-admission-server", mgr, webhook.ServerOptions{
+		Port:    Port,
+		CertDir: CertDir,
+		BootstrapOptions: &webhook.BootstrapOptions{
+			MutatingWebhookConfigName:   "ridecell-operator-mutating",
+			ValidatingWebhookConfigName: "ridecell-operator-validating",
+			Secret:                      &apitypes.NamespacedName{Namespace: namespace, Name: certSecretName},
+			Service: &webhook.Service{
+				Namespace: namespace,
+				Name:      serviceName,
+				Selectors: map[string]string{"app": "ridecell-operator"},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to create webhook server")
+	}
+
+	webhooks := []webhook.Webhook{}
+	for _, f := range WebhookFuncs {
+		wh, err := f(mgr)
+		if err != nil {
+			return err
+		}
+		webhooks = append(webhooks, wh)
+	}
+	if len(conversions) != 0 {
+		webhooks = append(webhooks, &conversionWebhook{conversions: conversions})
+	}
+	err = svr.Register(webhooks...)
+	if err != nil {
+		return err
+	}
+
+	if len(conversions) != 0 {
+		// Use a direct client, a cached one would start informers on every Secret and CRD.
+		c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+		if err != nil {
+			return errors.Wrap(err, "unable to create CRD client")
+		}
+		err = mgr.Add(&crdInstaller{
+			client:      c,
+			namespace:   namespace,
+			secretName:  certSecretName,
+			serviceName: serviceName,
+			conversions: conversions,
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to add CRD installer")
+		}
+	}
+	return nil
+}